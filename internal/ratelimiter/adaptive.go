@@ -0,0 +1,242 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// AdaptiveMinSamples is how many outcomes a key needs across the rolling
+	// window before its limit adapts, so one early failure can't throttle it.
+	AdaptiveMinSamples = 10
+
+	// adaptiveSweepSize is the number of learned outcomes above which stale
+	// entries are swept whenever a new one is stored.
+	adaptiveSweepSize = 1024
+)
+
+// adaptiveOutcomeScript records a request outcome in the current window and
+// returns the outcomes of the current and previous windows.
+//
+// KEYS[1]: The Redis hash holding the current window's outcomes
+// KEYS[2]: The Redis hash holding the previous window's outcomes
+// ARGV[1]: The outcome to record ("success" or "failure", "" to only read)
+// ARGV[2]: The TTL in milliseconds for the current window's hash
+//
+// Returns: {successes, failures}
+const adaptiveOutcomeScript = `
+if ARGV[1] ~= '' then
+    redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+local successes = 0
+local failures = 0
+for i = 1, 2 do
+    local counts = redis.call('HMGET', KEYS[i], 'success', 'failure')
+    successes = successes + tonumber(counts[1] or 0)
+    failures = failures + tonumber(counts[2] or 0)
+end
+return {successes, failures}
+`
+
+// AdaptiveLimiter is a RateLimiter whose limit for each key follows the
+// success rate of that key's requests, circuit-breaker style: when a
+// downstream starts failing, the key is throttled down to protect it, and
+// recovers as requests succeed again.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type AdaptiveLimiter interface {
+	RateLimiter
+
+	// Record atomically records the outcome of a request for key in a
+	// rolling counter of the current and previous windows, and updates the
+	// key's effective limit from it
+	Record(ctx context.Context, key string, success bool) error
+
+	// EffectiveLimit returns the limit key's outcomes currently call for,
+	// read from Redis, and updates the key's effective limit from it
+	EffectiveLimit(ctx context.Context, key string) (int64, error)
+}
+
+// adaptiveOutcomes is what a key's outcomes were when last read from Redis.
+type adaptiveOutcomes struct {
+	successes int64
+	failures  int64
+	until     time.Time
+}
+
+// adaptiveLimiter decorates a RateLimiter with limits scaled by each key's
+// success rate, applied through the wrapped limiter's Config.LimitResolver.
+type adaptiveLimiter struct {
+	RateLimiter
+
+	client *redis.Client
+	config *Config
+
+	// resolver is the LimitResolver config was created with, if any
+	resolver func(key string) int64
+
+	mu       sync.Mutex
+	outcomes map[string]adaptiveOutcomes
+	now      func() time.Time
+}
+
+// NewAdaptive creates the limiter for config.Algorithm, like NewFixedWindow,
+// NewSlidingWindow or NewTokenBucket, with a limit for each key that adapts
+// to the outcomes reported with Record.
+//
+// Once a key has AdaptiveMinSamples outcomes across the current and previous
+// window, its limit is Limit (or Config.LimitResolver's) scaled by the share
+// of successes, and at least 1. Each instance applies the outcomes it last
+// read from Redis, through Record or EffectiveLimit, so instances that share
+// keys but never record see the base limit. Limits changed with SetLimit are
+// not scaled.
+func NewAdaptive(client *redis.Client, config *Config) (AdaptiveLimiter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	cfg := config.WithDefaults()
+	a := &adaptiveLimiter{
+		client:   client,
+		config:   cfg,
+		resolver: cfg.LimitResolver,
+		outcomes: make(map[string]adaptiveOutcomes),
+		now:      time.Now,
+	}
+	inner := cfg.WithDefaults()
+	inner.LimitResolver = a.resolveLimit
+
+	var err error
+	switch cfg.Algorithm {
+	case FixedWindow:
+		a.RateLimiter, err = NewFixedWindow(client, inner)
+	case SlidingWindow:
+		a.RateLimiter, err = NewSlidingWindow(client, inner)
+	case TokenBucket:
+		a.RateLimiter, err = NewTokenBucket(client, inner)
+	default:
+		return nil, fmt.Errorf("%w: NewAdaptive does not support algorithm %q", ErrInvalidConfig, cfg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Record records the outcome of a request for key.
+func (a *adaptiveLimiter) Record(ctx context.Context, key string, success bool) error {
+	outcome := "failure"
+	if success {
+		outcome = "success"
+	}
+	if _, err := a.readOutcomes(ctx, key, outcome); err != nil {
+		return fmt.Errorf("failed to record outcome: %w", err)
+	}
+	return nil
+}
+
+// EffectiveLimit returns the limit key's outcomes call for.
+func (a *adaptiveLimiter) EffectiveLimit(ctx context.Context, key string) (int64, error) {
+	outcomes, err := a.readOutcomes(ctx, key, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outcomes: %w", err)
+	}
+	return adaptedLimit(a.baseLimit(key), outcomes.successes, outcomes.failures), nil
+}
+
+// readOutcomes records outcome (unless empty) and learns key's outcomes
+// across the current and previous window.
+func (a *adaptiveLimiter) readOutcomes(ctx context.Context, key, outcome string) (adaptiveOutcomes, error) {
+	if key == "" {
+		return adaptiveOutcomes{}, ErrInvalidKey
+	}
+	if err := a.config.checkCallBudget(ctx); err != nil {
+		return adaptiveOutcomes{}, err
+	}
+
+	now := a.now()
+	windowStart := now.Truncate(a.config.Window)
+	keys := []string{
+		a.formatKey(key, windowStart),
+		a.formatKey(key, windowStart.Add(-a.config.Window)),
+	}
+	ttl := a.config.effectiveTTL(2 * a.config.Window)
+
+	counts, err := a.client.Eval(ctx, adaptiveOutcomeScript, keys, outcome, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return adaptiveOutcomes{}, crossSlotError(err)
+	}
+	if len(counts) != 2 {
+		return adaptiveOutcomes{}, fmt.Errorf("unexpected outcome counts: %v", counts)
+	}
+
+	// Once the current window ends, the next read covers different windows
+	outcomes := adaptiveOutcomes{successes: counts[0], failures: counts[1], until: windowStart.Add(a.config.Window)}
+	a.store(key, outcomes, now)
+	return outcomes, nil
+}
+
+// store remembers key's outcomes for resolveLimit.
+func (a *adaptiveLimiter) store(key string, outcomes adaptiveOutcomes, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.outcomes) >= adaptiveSweepSize {
+		for k, o := range a.outcomes {
+			if !now.Before(o.until) {
+				delete(a.outcomes, k)
+			}
+		}
+	}
+	a.outcomes[key] = outcomes
+}
+
+// resolveLimit is the wrapped limiter's LimitResolver: key's base limit
+// scaled by the outcomes last learned for it.
+func (a *adaptiveLimiter) resolveLimit(key string) int64 {
+	base := a.baseLimit(key)
+
+	a.mu.Lock()
+	outcomes, ok := a.outcomes[key]
+	a.mu.Unlock()
+	if !ok || !a.now().Before(outcomes.until) {
+		return base
+	}
+	return adaptedLimit(base, outcomes.successes, outcomes.failures)
+}
+
+// baseLimit returns key's limit before adapting: Config.LimitResolver's
+// override when it returns one, otherwise Limit.
+func (a *adaptiveLimiter) baseLimit(key string) int64 {
+	if a.resolver != nil {
+		if override := a.resolver(key); override > 0 {
+			return override
+		}
+	}
+	return a.config.Limit
+}
+
+// formatKey formats the Redis key holding key's outcomes for the window
+// starting at windowStart.
+func (a *adaptiveLimiter) formatKey(key string, windowStart time.Time) string {
+	return a.config.FormatKey(key) + ":outcomes:" + strconv.FormatInt(windowStart.Unix(), 10)
+}
+
+// adaptedLimit scales limit by the share of successes once there are at
+// least AdaptiveMinSamples outcomes, keeping it at 1 or more.
+func adaptedLimit(limit, successes, failures int64) int64 {
+	total := successes + failures
+	if total < AdaptiveMinSamples {
+		return limit
+	}
+	return max(1, limit*successes/total)
+}
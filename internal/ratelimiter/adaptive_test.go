@@ -0,0 +1,138 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptive_FailuresLowerEffectiveLimit(t *testing.T) {
+	algorithms := []Algorithm{FixedWindow, SlidingWindow, TokenBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(string(algorithm), func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+
+			limiter, err := NewAdaptive(client, &Config{
+				Algorithm: algorithm,
+				Limit:     10,
+				Window:    time.Hour,
+			})
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			ctx := context.Background()
+
+			// Too few outcomes to adapt yet
+			for range AdaptiveMinSamples - 1 {
+				require.NoError(t, limiter.Record(ctx, "user:1", false))
+			}
+			limit, err := limiter.EffectiveLimit(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), limit)
+
+			// Every outcome a failure: throttled down to a single request
+			require.NoError(t, limiter.Record(ctx, "user:1", false))
+			limit, err = limiter.EffectiveLimit(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), limit)
+
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(1), result.Limit)
+			result, err = limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+
+			// Other keys keep the full limit
+			result, err = limiter.Allow(ctx, "user:2")
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), result.Limit)
+		})
+	}
+}
+
+func TestAdaptive_RecoversWithSuccesses(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewAdaptive(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     100,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for range 10 {
+		require.NoError(t, limiter.Record(ctx, "user:1", false))
+	}
+	for range 30 {
+		require.NoError(t, limiter.Record(ctx, "user:1", true))
+	}
+
+	// 30 of 40 outcomes succeeded
+	limit, err := limiter.EffectiveLimit(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(75), limit)
+
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(75), result.Limit)
+}
+
+func TestAdaptive_ScalesLimitResolver(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewAdaptive(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Hour,
+		LimitResolver: func(key string) int64 {
+			if key == "premium:1" {
+				return 1000
+			}
+			return 0
+		},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := range 20 {
+		require.NoError(t, limiter.Record(ctx, "premium:1", i%2 == 0))
+	}
+
+	limit, err := limiter.EffectiveLimit(ctx, "premium:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), limit)
+}
+
+func TestNewAdaptive_Validation(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	_, err := NewAdaptive(nil, &Config{Algorithm: FixedWindow, Limit: 10, Window: time.Minute})
+	assert.Error(t, err)
+
+	_, err = NewAdaptive(client, nil)
+	assert.Error(t, err)
+
+	_, err = NewAdaptive(client, &Config{Algorithm: Concurrency, Limit: 10, Window: time.Minute})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = NewAdaptive(client, &Config{Algorithm: FixedWindow, Limit: 0, Window: time.Minute})
+	assert.ErrorContains(t, err, "invalid config")
+
+	limiter, err := NewAdaptive(client, &Config{Algorithm: FixedWindow, Limit: 10, Window: time.Minute})
+	require.NoError(t, err)
+	defer limiter.Close()
+	assert.ErrorIs(t, limiter.Record(context.Background(), "", true), ErrInvalidKey)
+}
@@ -0,0 +1,30 @@
+package ratelimiter
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// algorithmCase is a Redis-backed algorithm and the constructor of its
+// RateLimiter.
+type algorithmCase struct {
+	algorithm Algorithm
+	create    func(*redis.Client, *Config) (RateLimiter, error)
+}
+
+// forEachAlgorithm runs test as a subtest, named after the algorithm, for
+// each algorithm with a RateLimiter: FixedWindow, SlidingWindow and
+// TokenBucket.
+func forEachAlgorithm(t *testing.T, test func(t *testing.T, tt algorithmCase)) {
+	t.Helper()
+	for _, tt := range []algorithmCase{
+		{FixedWindow, NewFixedWindow},
+		{SlidingWindow, NewSlidingWindow},
+		{TokenBucket, NewTokenBucket},
+	} {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			test(t, tt)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BackendErrorKind classifies a Redis failure, so alerting can route a
+// timeout differently from an unreachable server or a full one.
+type BackendErrorKind int
+
+const (
+	// BackendOther is any failure not covered by another kind.
+	BackendOther BackendErrorKind = iota
+
+	// BackendTimeout means Redis didn't answer in time: the context deadline
+	// passed, a read or write timed out, or no pooled connection freed up.
+	BackendTimeout
+
+	// BackendUnreachable means no connection to Redis could be used: the
+	// connection was refused or dropped, or the client is closed.
+	BackendUnreachable
+
+	// BackendOutOfMemory means Redis refused a write at its maxmemory limit
+	// (an OOM error).
+	BackendOutOfMemory
+
+	// BackendReadOnly means the command reached a read-only replica
+	// (a READONLY error), e.g. during a failover.
+	BackendReadOnly
+)
+
+// String returns the kind's name, e.g. "timeout".
+func (k BackendErrorKind) String() string {
+	switch k {
+	case BackendTimeout:
+		return "timeout"
+	case BackendUnreachable:
+		return "unreachable"
+	case BackendOutOfMemory:
+		return "out_of_memory"
+	case BackendReadOnly:
+		return "read_only"
+	default:
+		return "other"
+	}
+}
+
+// BackendError is a Redis failure returned by a limiter that fails closed,
+// with its Kind. Retrieve it with errors.As:
+//
+//	var backendErr *BackendError
+//	if errors.As(err, &backendErr) && backendErr.Kind == BackendOutOfMemory {
+//		// page the Redis on-call
+//	}
+type BackendError struct {
+	// Kind is the class of the failure.
+	Kind BackendErrorKind
+
+	// Err is the error from the Redis client.
+	Err error
+}
+
+// Error returns the Redis client's error message.
+func (e *BackendError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the Redis client's error.
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyBackendError returns the kind of Redis failure err is.
+func ClassifyBackendError(err error) BackendErrorKind {
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.Is(err, redis.ErrPoolTimeout), errors.As(err, &netErr) && netErr.Timeout():
+		return BackendTimeout
+	case errors.Is(err, redis.ErrClosed), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &opErr):
+		return BackendUnreachable
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		switch message := redisErr.Error(); {
+		case strings.HasPrefix(message, "OOM "):
+			return BackendOutOfMemory
+		case strings.HasPrefix(message, "READONLY "):
+			return BackendReadOnly
+		}
+	}
+	return BackendOther
+}
+
+// backendError wraps a failed Redis call's error in a BackendError.
+// Misconfigurations (ErrCrossSlot, ErrKeyTypeMismatch) and
+// ErrInsufficientBudget, which come from the limiter rather than Redis, are
+// returned unchanged, as is nil.
+func backendError(err error) error {
+	if err == nil || misconfigured(err) || errors.Is(err, ErrInsufficientBudget) {
+		return err
+	}
+	return &BackendError{Kind: ClassifyBackendError(err), Err: err}
+}
@@ -0,0 +1,131 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisReply is an error reply from Redis, as the client reports it
+type redisReply string
+
+func (e redisReply) Error() string { return string(e) }
+
+func (redisReply) RedisError() {}
+
+func TestClassifyBackendError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want BackendErrorKind
+	}{
+		{"deadline", context.DeadlineExceeded, BackendTimeout},
+		{"wrapped deadline", fmt.Errorf("eval: %w", context.DeadlineExceeded), BackendTimeout},
+		{"pool timeout", redis.ErrPoolTimeout, BackendTimeout},
+		{"read timeout", &net.OpError{Op: "read", Err: timeoutError{}}, BackendTimeout},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, BackendUnreachable},
+		{"closed client", redis.ErrClosed, BackendUnreachable},
+		{"dropped connection", io.EOF, BackendUnreachable},
+		{"oom", redisReply("OOM command not allowed when used memory > 'maxmemory'."), BackendOutOfMemory},
+		{"readonly", redisReply("READONLY You can't write against a read only replica."), BackendReadOnly},
+		{"other reply", redisReply("NOSCRIPT No matching script."), BackendOther},
+		{"oom text outside a reply", errors.New("OOM command not allowed"), BackendOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyBackendError(tt.err))
+		})
+	}
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestBackendError_FailClosed(t *testing.T) {
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	}
+
+	tests := []struct {
+		name  string
+		reply string
+		want  BackendErrorKind
+	}{
+		{"out of memory", "OOM command not allowed when used memory > 'maxmemory'.", BackendOutOfMemory},
+		{"read only", "READONLY You can't write against a read only replica.", BackendReadOnly},
+		{"other", "ERR something else went wrong", BackendOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+			mr.SetError(tt.reply)
+
+			limiter, err := NewFixedWindow(client, config)
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			_, err = limiter.Allow(context.Background(), "user:1")
+			var backendErr *BackendError
+			require.ErrorAs(t, err, &backendErr)
+			assert.Equal(t, tt.want, backendErr.Kind)
+			assert.ErrorContains(t, err, tt.reply)
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		client, _ := unreachableClient(t)
+		limiter, err := NewTokenBucket(client, &Config{Algorithm: TokenBucket, Limit: 10, Window: time.Minute})
+		require.NoError(t, err)
+
+		_, err = limiter.Allow(context.Background(), "user:1")
+		var backendErr *BackendError
+		require.ErrorAs(t, err, &backendErr)
+		assert.Equal(t, BackendUnreachable, backendErr.Kind)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewSlidingWindow(client, &Config{Algorithm: SlidingWindow, Limit: 10, Window: time.Minute})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		_, err = limiter.Allow(ctx, "user:1")
+		var backendErr *BackendError
+		require.ErrorAs(t, err, &backendErr)
+		assert.Equal(t, BackendTimeout, backendErr.Kind)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("limiter errors stay unwrapped", func(t *testing.T) {
+		assert.Same(t, ErrInsufficientBudget, backendError(ErrInsufficientBudget))
+		assert.NoError(t, backendError(nil))
+	})
+}
+
+func TestBackendErrorKind_String(t *testing.T) {
+	assert.Equal(t, "timeout", BackendTimeout.String())
+	assert.Equal(t, "unreachable", BackendUnreachable.String())
+	assert.Equal(t, "out_of_memory", BackendOutOfMemory.String())
+	assert.Equal(t, "read_only", BackendReadOnly.String())
+	assert.Equal(t, "other", BackendOther.String())
+}
@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// KeyBoundLimiter is a RateLimiter bound to a single key, with the method
+// set of golang.org/x/time/rate.Limiter (Allow, AllowN, Wait, WaitN), to
+// ease migrating code written against it to a distributed limiter.
+//
+// Unlike rate.Limiter, every call is a round-trip to Redis. Allow and AllowN
+// take no context and report a failed decision (e.g. Redis down without
+// Config.FailOpen) as not allowed; use the RateLimiter directly to tell the
+// two apart.
+//
+// Example:
+//
+//	// was: limiter := rate.NewLimiter(10, 10)
+//	limiter := ratelimiter.BindKey(distributed, "service:billing")
+//	if !limiter.Allow() {
+//	    return errTooManyRequests
+//	}
+type KeyBoundLimiter struct {
+	limiter RateLimiter
+	key     string
+}
+
+// BindKey returns a KeyBoundLimiter making every decision for key on limiter.
+func BindKey(limiter RateLimiter, key string) *KeyBoundLimiter {
+	return &KeyBoundLimiter{limiter: limiter, key: key}
+}
+
+// Allow reports whether a single request is allowed now.
+func (b *KeyBoundLimiter) Allow() bool {
+	return b.AllowN(time.Now(), 1)
+}
+
+// AllowN reports whether n requests are allowed, consuming them if so.
+// The decision is always made at the current time, by Redis, so t is only
+// accepted for compatibility. As with rate.Limiter, n <= 0 is always allowed.
+func (b *KeyBoundLimiter) AllowN(t time.Time, n int) bool {
+	if n <= 0 {
+		return true
+	}
+	result, err := b.limiter.AllowN(context.Background(), b.key, int64(n))
+	return err == nil && result.Allowed
+}
+
+// Wait blocks until a single request is allowed or ctx ends. See WaitN.
+func (b *KeyBoundLimiter) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n requests are allowed or ctx ends, returning the
+// errors of the package-level WaitN: a *WaitError if ctx ends first, and
+// ErrPermanentDenial if n can never be allowed (like rate.Limiter's error
+// for n above the burst).
+func (b *KeyBoundLimiter) WaitN(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := WaitN(ctx, b.limiter, b.key, int64(n))
+	return err
+}
@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBoundLimiter_AllowMirrorsDecisions(t *testing.T) {
+	decisions := []bool{true, false, true, true, false}
+	scripted := &scriptedLimiter{decisions: decisions}
+	bound := BindKey(scripted, "service:billing")
+
+	for i, want := range decisions {
+		assert.Equal(t, want, bound.Allow(), "call %d", i+1)
+	}
+
+	// Failed decisions are reported as not allowed
+	scripted.err = errors.New("redis down")
+	assert.False(t, bound.Allow())
+	assert.False(t, bound.AllowN(time.Now(), 2))
+
+	// Like x/time/rate, asking for nothing is always allowed, without a call
+	calls := scripted.calls
+	assert.True(t, bound.AllowN(time.Now(), 0))
+	assert.Equal(t, calls, scripted.calls)
+}
+
+func TestKeyBoundLimiter_Integration(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	bound := BindKey(limiter, "user:1")
+	assert.True(t, bound.AllowN(time.Now(), 2))
+	assert.True(t, bound.Allow())
+	assert.False(t, bound.Allow())
+
+	// The limiter's own state agrees with what the adapter reported
+	result, err := limiter.Allow(context.Background(), "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Waiting on an exhausted key gives up at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var waitErr *WaitError
+	assert.ErrorAs(t, bound.Wait(ctx), &waitErr)
+
+	// More than the limit can never be allowed
+	assert.ErrorIs(t, bound.WaitN(context.Background(), 4), ErrPermanentDenial)
+
+	require.NoError(t, BindKey(limiter, "user:2").Wait(context.Background()))
+}
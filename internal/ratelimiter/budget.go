@@ -0,0 +1,25 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// checkCallBudget returns ErrInsufficientBudget when ctx's deadline is closer
+// than Config.MinCallBudget, so that a Redis call that would most likely time
+// out is not attempted. The error is handled like any other storage error:
+// limiters configured to fail open allow the request.
+func (c *Config) checkCallBudget(ctx context.Context) error {
+	if c.MinCallBudget <= 0 {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	if left := time.Until(deadline); left < c.MinCallBudget {
+		return fmt.Errorf("%w: %v left, need %v", ErrInsufficientBudget, max(left, 0), c.MinCallBudget)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinCallBudget_NearExpiredDeadlineSkipsRedis(t *testing.T) {
+	for _, failOpen := range []bool{true, false} {
+		client, mr := setupMiniredis(t)
+		var calls int
+		client.AddHook(countingHook{calls: &calls})
+
+		limiter, err := NewFixedWindow(client, &Config{
+			Algorithm:     FixedWindow,
+			Limit:         10,
+			Window:        time.Minute,
+			FailOpen:      failOpen,
+			MinCallBudget: 50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		result, err := limiter.Allow(ctx, "user:1")
+		cancel()
+
+		assert.Zero(t, calls, "should fail over without attempting a Redis call")
+		if failOpen {
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+		} else {
+			assert.ErrorIs(t, err, ErrInsufficientBudget)
+		}
+
+		limiter.Close()
+		mr.Close()
+	}
+}
+
+func TestMinCallBudget_EnoughTimeCallsRedis(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:     TokenBucket,
+		Limit:         10,
+		Window:        time.Minute,
+		MinCallBudget: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// Without a deadline the budget does not apply
+	result, err := limiter.Allow(context.Background(), "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), result.Remaining)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), result.Remaining)
+}
+
+func TestMinCallBudget_Validate(t *testing.T) {
+	err := (&Config{
+		Algorithm:     FixedWindow,
+		Limit:         10,
+		Window:        time.Minute,
+		MinCallBudget: -time.Millisecond,
+	}).Validate()
+	require.Error(t, err)
+	assert.Equal(t, "MinCallBudget", ValidationErrors(err)[0].Field)
+}
@@ -0,0 +1,149 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// cachedSweepSize is the number of cached denials above which expired
+// entries are swept whenever a new denial is stored.
+const cachedSweepSize = 1024
+
+// cachedDenial is a denial served locally until it expires.
+type cachedDenial struct {
+	result   *Result
+	cachedAt time.Time
+	until    time.Time
+}
+
+// cachedLimiter decorates a RateLimiter with an in-process cache of denials,
+// forming a two-tier limiter: keys that are out of quota are denied locally
+// and only keys that may still have quota reach Redis.
+type cachedLimiter struct {
+	RateLimiter
+
+	ttl time.Duration
+
+	mu      sync.Mutex
+	denials map[string]cachedDenial
+	now     func() time.Time
+}
+
+// NewCached wraps limiter with a local cache of denials.
+//
+// When limiter denies a key and reports no quota left, further checks for
+// that key are denied locally for Config.LocalCacheTTL, or until the
+// denial's RetryAfter or ResetAt if that comes first, with RetryAfter
+// counting down from the original denial. Allows are never cached, so the
+// cache can only deny requests Redis would also have denied at the time of
+// the cached decision; the cost is that quota freed within the TTL (token
+// refills, Reset from another process) is not seen until it expires.
+//
+// config is typically the Config limiter was built with; only LocalCacheTTL
+// is used.
+func NewCached(limiter RateLimiter, config *Config) (RateLimiter, error) {
+	if limiter == nil {
+		return nil, fmt.Errorf("limiter cannot be nil")
+	}
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if config.LocalCacheTTL < 0 {
+		return nil, fmt.Errorf("invalid config: local cache ttl must not be negative, got: %v", config.LocalCacheTTL)
+	}
+
+	ttl := config.LocalCacheTTL
+	if ttl == 0 {
+		ttl = DefaultLocalCacheTTL
+	}
+
+	return &cachedLimiter{
+		RateLimiter: limiter,
+		ttl:         ttl,
+		denials:     make(map[string]cachedDenial),
+		now:         time.Now,
+	}, nil
+}
+
+// Allow checks a single request, serving cached denials locally.
+func (c *cachedLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return c.AllowN(ctx, key, 1)
+}
+
+// AllowN checks N requests, serving cached denials locally.
+func (c *cachedLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+
+	if result, ok := c.lookup(key); ok {
+		return result, nil
+	}
+
+	result, err := c.RateLimiter.AllowN(ctx, key, n)
+	if err == nil && result != nil && !result.Allowed && result.Remaining == 0 {
+		c.store(key, result)
+	}
+	return result, err
+}
+
+// Reset drops the key's cached denial and resets it in the wrapped limiter.
+func (c *cachedLimiter) Reset(ctx context.Context, key string) error {
+	c.mu.Lock()
+	delete(c.denials, key)
+	c.mu.Unlock()
+
+	return c.RateLimiter.Reset(ctx, key)
+}
+
+// lookup returns a clone of the key's cached denial if it has not expired.
+func (c *cachedLimiter) lookup(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	denial, ok := c.denials[key]
+	if !ok {
+		return nil, false
+	}
+
+	now := c.now()
+	if !now.Before(denial.until) {
+		delete(c.denials, key)
+		return nil, false
+	}
+
+	// ResetAt may be far later than the retry (a token bucket's is when it
+	// is full again), so count down the original RetryAfter instead
+	result := denial.result.Clone()
+	result.RetryAfter = max(result.RetryAfter-now.Sub(denial.cachedAt), 0)
+	return result, true
+}
+
+// store caches a denial until the TTL expires, a retry could succeed or the
+// window resets.
+func (c *cachedLimiter) store(key string, result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	until := now.Add(c.ttl)
+	if result.RetryAfter > 0 && now.Add(result.RetryAfter).Before(until) {
+		until = now.Add(result.RetryAfter)
+	}
+	if !result.ResetAt.IsZero() && result.ResetAt.Before(until) {
+		until = result.ResetAt
+	}
+
+	if len(c.denials) >= cachedSweepSize {
+		for k, denial := range c.denials {
+			if !now.Before(denial.until) {
+				delete(c.denials, k)
+			}
+		}
+	}
+
+	// The caller keeps result, so cache a clone it can't mutate
+	c.denials[key] = cachedDenial{result: result.Clone(), cachedAt: now, until: until}
+}
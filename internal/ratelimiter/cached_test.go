@@ -0,0 +1,213 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCached(t *testing.T, inner RateLimiter, ttl time.Duration) (*cachedLimiter, *fakeClock) {
+	t.Helper()
+
+	limiter, err := NewCached(inner, &Config{LocalCacheTTL: ttl})
+	require.NoError(t, err)
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cached := limiter.(*cachedLimiter)
+	cached.now = clock.Now
+	return cached, clock
+}
+
+func TestNewCached(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{true}}
+
+	_, err := NewCached(nil, &Config{})
+	assert.ErrorContains(t, err, "limiter cannot be nil")
+
+	_, err = NewCached(inner, nil)
+	assert.ErrorContains(t, err, "config cannot be nil")
+
+	_, err = NewCached(inner, &Config{LocalCacheTTL: -time.Second})
+	assert.ErrorContains(t, err, "local cache ttl must not be negative")
+
+	limiter, err := NewCached(inner, &Config{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLocalCacheTTL, limiter.(*cachedLimiter).ttl)
+}
+
+func TestCached_DenialsServedLocallyWithinTTL(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{false}}
+	limiter, clock := newTestCached(t, inner, 500*time.Millisecond)
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 1, inner.calls)
+
+	// Within the TTL repeated over-limit checks never reach the backend
+	for i := 0; i < 10; i++ {
+		clock.Advance(40 * time.Millisecond)
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	}
+	assert.Equal(t, 1, inner.calls)
+
+	// Once the TTL expires the backend is queried again
+	clock.Advance(100 * time.Millisecond)
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCached_ReturnsClones(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{false}}
+	limiter, _ := newTestCached(t, inner, time.Minute)
+
+	ctx := context.Background()
+
+	// Mutating the result that populated the cache doesn't change it
+	first, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	first.Allowed = true
+
+	// Nor does mutating a result served from the cache
+	second, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, second.Allowed)
+	second.Allowed = true
+	second.Limit = 1000
+
+	third, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, int64(10), third.Limit)
+	assert.Equal(t, 1, inner.calls)
+}
+
+func TestCached_AllowsNeverCached(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{true}}
+	limiter, _ := newTestCached(t, inner, time.Minute)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	assert.Equal(t, 5, inner.calls)
+}
+
+func TestCached_ExpiresAtResetAt(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	inner := &resetAtLimiter{resetAt: clock.now.Add(100 * time.Millisecond), retryAfter: 100 * time.Millisecond}
+
+	limiter, err := NewCached(inner, &Config{LocalCacheTTL: time.Minute})
+	require.NoError(t, err)
+	limiter.(*cachedLimiter).now = clock.Now
+
+	ctx := context.Background()
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+
+	clock.Advance(50 * time.Millisecond)
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 50*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 1, inner.calls)
+
+	// The window reset before the TTL expired, so the denial is dropped
+	clock.Advance(50 * time.Millisecond)
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCached_TokenBucketRetryAfter(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// A bucket refills a token every 36s, but is full again only in an hour
+	bucket, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     100,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	limiter, clock := newTestCached(t, bucket, time.Minute)
+
+	ctx := context.Background()
+	_, err = limiter.AllowN(ctx, "user:1", 100)
+	require.NoError(t, err)
+	denied, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	require.False(t, denied.Allowed)
+	assert.InDelta(t, 36*time.Second, denied.RetryAfter, float64(time.Second))
+
+	// The cached denial counts down the original RetryAfter
+	clock.Advance(10 * time.Second)
+	cached, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, cached.Allowed)
+	assert.Equal(t, denied.RetryAfter-10*time.Second, cached.RetryAfter)
+
+	// And is dropped once a retry could succeed, ahead of the TTL
+	clock.Advance(denied.RetryAfter - 10*time.Second)
+	_, ok := limiter.lookup("user:1")
+	assert.False(t, ok)
+}
+
+func TestCached_PartialDenialNotCached(t *testing.T) {
+	// A denial that still reports remaining quota may allow smaller requests
+	inner := &resetAtLimiter{remaining: 3}
+	limiter, _ := newTestCached(t, inner, time.Minute)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := limiter.AllowN(ctx, "user:1", 5)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestCached_Reset(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{false}}
+	limiter, _ := newTestCached(t, inner, time.Minute)
+
+	ctx := context.Background()
+	_, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+
+	require.NoError(t, limiter.Reset(ctx, "user:1"))
+
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+// resetAtLimiter denies every request with a fixed ResetAt and Remaining
+type resetAtLimiter struct {
+	resetAt    time.Time
+	retryAfter time.Duration
+	remaining  int64
+	calls      int
+}
+
+func (r *resetAtLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return r.AllowN(ctx, key, 1)
+}
+
+func (r *resetAtLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	r.calls++
+	return &Result{Allowed: false, Limit: 10, Remaining: r.remaining, RetryAfter: r.retryAfter, ResetAt: r.resetAt}, nil
+}
+
+func (r *resetAtLimiter) Reset(ctx context.Context, key string) error { return nil }
+
+func (r *resetAtLimiter) Close() error { return nil }
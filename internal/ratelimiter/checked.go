@@ -0,0 +1,42 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewLimiterChecked creates the limiter for config.Algorithm, like
+// NewFixedWindow, NewSlidingWindow or NewTokenBucket, and then pings Redis so
+// an unreachable server is caught at startup instead of on the first Allow.
+// A failed ping returns an error wrapping ErrStorageUnavailable, unless
+// config.FailOpen is set, since that limiter tolerates outages anyway.
+//
+// Concurrency limiters are not RateLimiters; use NewConcurrency for them.
+func NewLimiterChecked(ctx context.Context, client *redis.Client, config *Config) (RateLimiter, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	var limiter RateLimiter
+	var err error
+	switch config.Algorithm {
+	case FixedWindow:
+		limiter, err = NewFixedWindow(client, config)
+	case SlidingWindow:
+		limiter, err = NewSlidingWindow(client, config)
+	case TokenBucket:
+		limiter, err = NewTokenBucket(client, config)
+	default:
+		return nil, fmt.Errorf("%w: NewLimiterChecked does not support algorithm %q", ErrInvalidConfig, config.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx).Err(); err != nil && !config.FailOpen {
+		return nil, fmt.Errorf("%w: ping failed: %v", ErrStorageUnavailable, err)
+	}
+	return limiter, nil
+}
@@ -0,0 +1,66 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiterChecked(t *testing.T) {
+	ctx := context.Background()
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	}
+
+	t.Run("reachable", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewLimiterChecked(ctx, client, config)
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("closed client", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+		require.NoError(t, client.Close())
+
+		_, err := NewLimiterChecked(ctx, client, config)
+		assert.ErrorIs(t, err, ErrStorageUnavailable)
+		assert.ErrorContains(t, err, redis.ErrClosed.Error())
+	})
+
+	t.Run("closed client with fail open", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+		require.NoError(t, client.Close())
+
+		failOpen := *config
+		failOpen.FailOpen = true
+		limiter, err := NewLimiterChecked(ctx, client, &failOpen)
+		require.NoError(t, err)
+		assert.NotNil(t, limiter)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		_, err := NewLimiterChecked(ctx, client, &Config{Algorithm: FixedWindow})
+		assert.ErrorContains(t, err, "invalid config")
+
+		_, err = NewLimiterChecked(ctx, client, &Config{Algorithm: Concurrency, Limit: 1})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,18 @@
+package ratelimiter
+
+import "fmt"
+
+// classLimit returns the limit configured for an operation class.
+func (c *Config) classLimit(class string) (int64, error) {
+	limit, ok := c.ClassLimits[class]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownClass, class)
+	}
+	return limit, nil
+}
+
+// classKey returns the key of the class's sub-bucket under key. Both parts are
+// escaped, so a class sub-bucket can never share state with a plain key.
+func classKey(key, class string) string {
+	return Key().Part(key).Dim("class", class).Build()
+}
@@ -0,0 +1,79 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// classifiedLimiter routes every key to the limiter its classifier picks.
+type classifiedLimiter struct {
+	classifier func(key string) RateLimiter
+
+	mu   sync.Mutex
+	seen map[RateLimiter]struct{}
+}
+
+// NewClassifiedLimiter returns a RateLimiter that routes each key to the
+// limiter classifier returns for it, e.g. a token bucket for "user:" keys
+// and a fixed window for "ip:" keys. Keys routed to different limiters never
+// share quota.
+//
+// classifier is called on every request and should return limiters created
+// up front rather than new ones. Close closes every distinct limiter it has
+// returned so far, each once.
+func NewClassifiedLimiter(classifier func(key string) RateLimiter) RateLimiter {
+	return &classifiedLimiter{
+		classifier: classifier,
+		seen:       make(map[RateLimiter]struct{}),
+	}
+}
+
+// Allow checks a single request against the key's limiter.
+func (c *classifiedLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return c.AllowN(ctx, key, 1)
+}
+
+// AllowN checks N requests against the key's limiter.
+func (c *classifiedLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	return c.route(key).AllowN(ctx, key, n)
+}
+
+// Reset clears the key in its limiter.
+func (c *classifiedLimiter) Reset(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	return c.route(key).Reset(ctx, key)
+}
+
+// Close closes every distinct limiter the classifier has returned.
+func (c *classifiedLimiter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for limiter := range c.seen {
+		// Limiters sharing a Redis client each close it; only the first succeeds
+		if err := limiter.Close(); err != nil && !errors.Is(err, redis.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	c.seen = make(map[RateLimiter]struct{})
+	return errors.Join(errs...)
+}
+
+// route returns the key's limiter, remembering it for Close.
+func (c *classifiedLimiter) route(key string) RateLimiter {
+	limiter := c.classifier(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[limiter] = struct{}{}
+	return limiter
+}
@@ -0,0 +1,99 @@
+package ratelimiter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeCounter counts how often the wrapped limiter is closed
+type closeCounter struct {
+	RateLimiter
+	closes int
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return c.RateLimiter.Close()
+}
+
+func TestClassifiedLimiter_RoutesKeyClasses(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	bucket, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     3,
+		Window:    time.Minute,
+		Prefix:    "users",
+	})
+	require.NoError(t, err)
+	window, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Minute,
+		Prefix:    "ips",
+	})
+	require.NoError(t, err)
+
+	users := &closeCounter{RateLimiter: bucket}
+	ips := &closeCounter{RateLimiter: window}
+	limiter := NewClassifiedLimiter(func(key string) RateLimiter {
+		if strings.HasPrefix(key, "user:") {
+			return users
+		}
+		return ips
+	})
+
+	ctx := context.Background()
+
+	for range 2 {
+		result, err := limiter.Allow(ctx, "ip:1.2.3.4")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "ip keys use the fixed window limit of 2")
+
+	// The exhausted ip key leaves user keys untouched
+	for range 3 {
+		result, err := limiter.Allow(ctx, "user:alice")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(3), result.Limit)
+	}
+	result, err = limiter.Allow(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "user keys use the token bucket limit of 3")
+
+	require.NoError(t, limiter.Reset(ctx, "ip:1.2.3.4"))
+	result, err = limiter.Allow(ctx, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "resetting an ip key must not reset user keys")
+
+	// Both limiters share one client, so only the first Close succeeds
+	require.NoError(t, limiter.Close())
+	assert.Equal(t, 1, users.closes)
+	assert.Equal(t, 1, ips.closes)
+}
+
+func TestClassifiedLimiter_InvalidKey(t *testing.T) {
+	limiter := NewClassifiedLimiter(func(key string) RateLimiter {
+		t.Fatal("classifier must not be called for an empty key")
+		return nil
+	})
+
+	_, err := limiter.Allow(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, limiter.Reset(context.Background(), ""), ErrInvalidKey)
+	assert.NoError(t, limiter.Close())
+}
@@ -0,0 +1,20 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// crossSlotError turns a Redis CROSSSLOT error into one wrapping ErrCrossSlot
+// that explains the fix. Other errors are returned unchanged.
+//
+// Redis Cluster only runs a script if all its keys live in the same slot.
+// Redirects during resharding (MOVED/ASK) are followed by the client and are
+// not errors, but keys in different slots can never succeed, so the error is
+// a configuration problem rather than an outage.
+func crossSlotError(err error) error {
+	if err == nil || !strings.HasPrefix(err.Error(), "CROSSSLOT") {
+		return err
+	}
+	return fmt.Errorf("%w: %v (keys checked together must share a hash tag such as \"{user:1}\"; see Config.HashTagKeys)", ErrCrossSlot, err)
+}
@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crossSlotHook fails every script with the error Redis Cluster returns when
+// a script's keys hash to different slots.
+type crossSlotHook struct{}
+
+func (crossSlotHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (crossSlotHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "eval" || cmd.Name() == "evalsha" {
+			err := errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (crossSlotHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// clusterHashTag returns the part of key Redis Cluster hashes: the content of
+// the first non-empty {...}, or the whole key.
+func clusterHashTag(key string) string {
+	start := strings.Index(key, "{")
+	if start < 0 {
+		return key
+	}
+	end := strings.Index(key[start+1:], "}")
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+func TestCrossSlotError(t *testing.T) {
+	assert.Nil(t, crossSlotError(nil))
+
+	other := errors.New("connection refused")
+	assert.Equal(t, other, crossSlotError(other))
+
+	err := crossSlotError(errors.New("CROSSSLOT Keys in request don't hash to the same slot"))
+	assert.ErrorIs(t, err, ErrCrossSlot)
+	assert.Contains(t, err.Error(), "CROSSSLOT")
+	assert.Contains(t, err.Error(), "Config.HashTagKeys")
+}
+
+func TestCrossSlot_SlidingWindowSurfacesConfigError(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+	client.AddHook(crossSlotHook{})
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		FailOpen:  true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// Failing open would silently disable limiting on a misconfigured cluster
+	result, err := limiter.Allow(context.Background(), "user:1")
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrCrossSlot)
+	assert.Contains(t, err.Error(), "hash tag")
+}
+
+func TestCrossSlot_HierarchicalSurfacesConfigError(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+	client.AddHook(crossSlotHook{})
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, Prefix: "user"},
+		{Algorithm: FixedWindow, Limit: 100, Window: time.Minute, Prefix: "org"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	_, err = limiter.Allow(context.Background(), []string{"1", "2"})
+	assert.ErrorIs(t, err, ErrCrossSlot)
+}
+
+func TestSlidingWindow_HashTagKeys(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:   SlidingWindow,
+		Limit:       10,
+		Window:      time.Minute,
+		SubWindows:  3,
+		HashTagKeys: true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	sw := limiter.(*slidingWindowLimiter)
+	assert.Equal(t, "ratelimit:{user:123}:1640000040", sw.formatKey("user:123", 1640000040))
+
+	// Every key read by one decision hashes to the same slot, so the script
+	// keeps working as slots move between nodes during resharding
+	for _, granularity := range []int{1, 3} {
+		for _, key := range sw.bucketKeys("user:123", 1640000040, granularity) {
+			assert.Equal(t, "user:123", clusterHashTag(key), key)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ratelimiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// CompositeKeyPrefix starts every key returned by CompositeKey
+	CompositeKeyPrefix = "ck:"
+
+	// compositeKeyHashBytes is how much of the SHA-256 digest a composite key
+	// keeps: 128 bits, hex encoded into 32 characters.
+	compositeKeyHashBytes = 16
+)
+
+// compositeKeyMappingScript stores the parts a composite key was built from,
+// unless the key already maps to different parts.
+//
+// KEYS[1]: The Redis key holding the mapping
+// ARGV[1]: The encoded parts
+// ARGV[2]: The mapping's TTL in milliseconds
+//
+// Returns: 1 if stored (or refreshed), 0 if the key maps to other parts.
+const compositeKeyMappingScript = `
+local existing = redis.call('GET', KEYS[1])
+if existing and existing ~= ARGV[1] then
+    return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`
+
+// CompositeKey hashes a tuple of request attributes (e.g. method, path and
+// user) into a compact, fixed-length key such as "ck:9f86d081884c7d65...".
+//
+// The same parts always give the same key. Parts are escaped before hashing
+// (see KeyBuilder), so ("a:b", "c") and ("a", "b:c") give different keys.
+// Returns "" if no parts are given, which limiters reject as ErrInvalidKey.
+// Use CompositeKeys to be able to decode a key back to its parts.
+func CompositeKey(parts ...string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(encodeCompositeParts(parts)))
+	return CompositeKeyPrefix + hex.EncodeToString(sum[:compositeKeyHashBytes])
+}
+
+// CompositeKeys builds composite keys like CompositeKey and records in Redis
+// which parts each key was built from, so operators can decode a hashed key
+// seen in logs or metrics back to its parts.
+//
+// Mappings expire after the TTL given to NewCompositeKeys; every Key call
+// refreshes its mapping. Key also detects hash collisions: a key that
+// already maps to different parts is reported as ErrKeyCollision.
+//
+// Example:
+//
+//	key, err := keys.Key(ctx, r.Method, r.URL.Path, userID)
+//	result, err := limiter.Allow(ctx, key)
+//	// later, while debugging:
+//	parts, found, err := keys.Decode(ctx, key)
+type CompositeKeys struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewCompositeKeys creates a CompositeKeys storing mappings under prefix for
+// ttl. An empty prefix uses DefaultPrefix.
+func NewCompositeKeys(client *redis.Client, prefix string, ttl time.Duration) (*CompositeKeys, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if ttl < time.Millisecond {
+		return nil, fmt.Errorf("mapping ttl must be at least 1ms, got: %v", ttl)
+	}
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	return &CompositeKeys{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}, nil
+}
+
+// Key returns CompositeKey(parts...) after recording its mapping.
+// Returns ErrInvalidKey if no parts are given and ErrKeyCollision if the key
+// already maps to different parts.
+func (c *CompositeKeys) Key(ctx context.Context, parts ...string) (string, error) {
+	key := CompositeKey(parts...)
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	stored, err := c.client.Eval(ctx, compositeKeyMappingScript, []string{c.formatKey(key)},
+		encodeCompositeParts(parts), c.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return "", fmt.Errorf("failed to store composite key mapping: %w", err)
+	}
+	if stored == 0 {
+		return "", fmt.Errorf("%w: %s", ErrKeyCollision, key)
+	}
+	return key, nil
+}
+
+// Decode returns the parts key was built from by Key. found is false if key
+// has no mapping, e.g. because it expired or was built with CompositeKey.
+func (c *CompositeKeys) Decode(ctx context.Context, key string) (parts []string, found bool, err error) {
+	if key == "" {
+		return nil, false, ErrInvalidKey
+	}
+
+	encoded, err := c.client.Get(ctx, c.formatKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read composite key mapping: %w", err)
+	}
+	return decodeCompositeParts(encoded), true, nil
+}
+
+// formatKey formats the Redis key holding the mapping for key.
+func (c *CompositeKeys) formatKey(key string) string {
+	return c.prefix + ":composite:" + key
+}
+
+// encodeCompositeParts joins escaped parts with KeySeparator.
+func encodeCompositeParts(parts []string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = escapeKeySegment(part)
+	}
+	return strings.Join(escaped, KeySeparator)
+}
+
+// decodeCompositeParts splits encoded on unescaped separators and unescapes
+// each part, reversing encodeCompositeParts.
+func decodeCompositeParts(encoded string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		switch {
+		case encoded[i] == '\\' && i+1 < len(encoded):
+			i++
+			part.WriteByte(encoded[i])
+		case strings.HasPrefix(encoded[i:], KeySeparator):
+			parts = append(parts, part.String())
+			part.Reset()
+			i += len(KeySeparator) - 1
+		default:
+			part.WriteByte(encoded[i])
+		}
+	}
+	return append(parts, part.String())
+}
@@ -0,0 +1,88 @@
+package ratelimiter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeKey(t *testing.T) {
+	key := CompositeKey("GET", "/api/orders", "user:123")
+	assert.Equal(t, key, CompositeKey("GET", "/api/orders", "user:123"), "same parts must give the same key")
+	assert.True(t, strings.HasPrefix(key, CompositeKeyPrefix))
+	assert.Len(t, key, len(CompositeKeyPrefix)+2*compositeKeyHashBytes)
+
+	// Parts are escaped, so moving a separator between parts changes the key
+	assert.NotEqual(t, CompositeKey("a:b", "c"), CompositeKey("a", "b:c"))
+	assert.NotEqual(t, CompositeKey("a", "b"), CompositeKey("b", "a"))
+
+	// Long parts still give a bounded key
+	assert.Len(t, CompositeKey(strings.Repeat("x", 10000)), len(key))
+
+	assert.Empty(t, CompositeKey())
+}
+
+func TestCompositeKeys(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	keys, err := NewCompositeKeys(client, "", time.Hour)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	parts := []string{"POST", "/api/a:b", `back\slash`, ""}
+	key, err := keys.Key(ctx, parts...)
+	require.NoError(t, err)
+	assert.Equal(t, CompositeKey(parts...), key)
+
+	decoded, found, err := keys.Decode(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, parts, decoded)
+
+	// The mapping expires with the configured TTL
+	assert.Equal(t, time.Hour, mr.TTL("ratelimit:composite:"+key))
+
+	// Asking again for the same parts is not a collision
+	again, err := keys.Key(ctx, parts...)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	// Keys without a mapping aren't decodable
+	_, found, err = keys.Decode(ctx, CompositeKey("never", "stored"))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = keys.Key(ctx)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestCompositeKeys_DetectsCollisions(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	keys, err := NewCompositeKeys(client, "", time.Hour)
+	require.NoError(t, err)
+
+	// Simulate a collision: the key already maps to other parts
+	key := CompositeKey("a", "b")
+	require.NoError(t, mr.Set("ratelimit:composite:"+key, "c:d"))
+
+	_, err = keys.Key(context.Background(), "a", "b")
+	assert.ErrorIs(t, err, ErrKeyCollision)
+}
+
+func TestNewCompositeKeys_Validation(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	_, err := NewCompositeKeys(nil, "", time.Hour)
+	assert.Error(t, err)
+
+	_, err = NewCompositeKeys(client, "", 0)
+	assert.Error(t, err)
+}
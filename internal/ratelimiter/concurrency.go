@@ -0,0 +1,247 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// concurrencyAcquireScript atomically claims a slot if one is free.
+	//
+	// KEYS[1]: The Redis key for the in-flight counter
+	// ARGV[1]: The maximum number of slots (limit)
+	// ARGV[2]: The safety TTL in milliseconds
+	//
+	// Returns: {acquired (0/1), in_flight}
+	concurrencyAcquireScript = `
+local current = tonumber(redis.call('GET', KEYS[1]) or 0)
+if current + 1 > tonumber(ARGV[1]) then
+    return {0, current}
+end
+
+current = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return {1, current}
+`
+
+	// concurrencyReleaseScript frees a slot, never taking the counter below 0.
+	// The counter may already be gone if the safety TTL reclaimed it, in which
+	// case there is nothing to release.
+	//
+	// KEYS[1]: The Redis key for the in-flight counter
+	//
+	// Returns: The number of requests still in flight
+	concurrencyReleaseScript = `
+local current = tonumber(redis.call('GET', KEYS[1]) or 0)
+if current <= 0 then
+    return 0
+end
+return redis.call('DECR', KEYS[1])
+`
+)
+
+// ConcurrencyLimiter limits how many requests per key may be in flight at once.
+//
+// Unlike the window algorithms, quota is returned when a request finishes
+// rather than when time passes. Implementations must be safe for concurrent
+// use by multiple goroutines.
+type ConcurrencyLimiter interface {
+	// Acquire claims a slot for the given key
+	//
+	// When Result.Allowed is true, the returned release function must be
+	// called once the request finishes to free the slot. When denied, the
+	// release function is a no-op. Release is idempotent, never takes the
+	// in-flight count below 0, and is safe to defer so that it also runs
+	// when the request panics.
+	//
+	// Example:
+	//   result, release, err := limiter.Acquire(ctx, "user:12345")
+	//   if err != nil || !result.Allowed {
+	//       return errTooManyInFlight
+	//   }
+	//   defer release()
+	Acquire(ctx context.Context, key string) (*Result, func(), error)
+
+	// AcquireCtx claims a slot whose lifetime is tied to ctx
+	//
+	// The slot is released automatically when ctx is cancelled or its
+	// deadline passes, so callers don't need an explicit release. Use a
+	// per-request context: a slot acquired with a long-lived context is held
+	// until that context ends (or the safety TTL reclaims it).
+	//
+	// Example:
+	//   ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	//   defer cancel() // Frees the slot when the handler returns
+	//   result, err := limiter.AcquireCtx(ctx, "user:12345")
+	AcquireCtx(ctx context.Context, key string) (*Result, error)
+
+	// Reset clears all slots held for the given key
+	Reset(ctx context.Context, key string) error
+
+	// Close releases any resources held by the limiter
+	Close() error
+}
+
+// concurrencyLimiter implements ConcurrencyLimiter with a Redis counter of
+// in-flight requests per key.
+type concurrencyLimiter struct {
+	client *redis.Client
+	config *Config
+}
+
+// NewConcurrency creates a new concurrency limiter.
+//
+// Config.Limit is the maximum number of requests in flight per key.
+// Config.Window is a safety TTL: if slots are leaked (e.g. a process crashes
+// before releasing), the counter expires once no slot has been acquired for
+// Window.
+func NewConcurrency(client *redis.Client, config *Config) (ConcurrencyLimiter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	// Validate and apply defaults
+	cfg := config.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if cfg.Algorithm != Concurrency {
+		return nil, fmt.Errorf("invalid config: concurrency limiter requires %s, got %s", Concurrency, cfg.Algorithm)
+	}
+
+	return &concurrencyLimiter{
+		client: client,
+		config: cfg,
+	}, nil
+}
+
+// Acquire claims a slot for the given key and returns its release function.
+func (c *concurrencyLimiter) Acquire(ctx context.Context, key string) (*Result, func(), error) {
+	redisKey := c.formatKey(key)
+
+	acquired, inFlight, err := c.tryAcquire(ctx, redisKey)
+	if err != nil {
+		if c.config.FailOpen {
+			// Fail open: allow the request, nothing to release
+			return c.stamp(Result{
+				Allowed:    true,
+				Limit:      c.config.Limit,
+				Remaining:  0,
+				RetryAfter: 0,
+			}), func() {}, nil
+		}
+		return nil, func() {}, fmt.Errorf("failed to acquire slot: %w", backendError(err))
+	}
+
+	if !acquired {
+		return c.stamp(Result{
+			Allowed:    false,
+			Limit:      c.config.Limit,
+			Remaining:  0,
+			RetryAfter: 0,
+		}), func() {}, nil
+	}
+
+	result := c.stamp(Result{
+		Allowed:    true,
+		Limit:      c.config.Limit,
+		Remaining:  c.config.Limit - inFlight,
+		RetryAfter: 0,
+	})
+
+	return result, c.releaseFunc(ctx, redisKey), nil
+}
+
+// stamp stamps an Acquire decision, which claims one slot or none.
+func (c *concurrencyLimiter) stamp(result Result) *Result {
+	result, _ = c.config.stampDecision(result, nil)
+	return &result
+}
+
+// AcquireCtx claims a slot and releases it automatically when ctx is done.
+func (c *concurrencyLimiter) AcquireCtx(ctx context.Context, key string) (*Result, error) {
+	result, release, err := c.Acquire(ctx, key)
+	if err != nil || !result.Allowed {
+		return result, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		release()
+	}()
+
+	return result, nil
+}
+
+// Reset clears all slots held for the given key.
+func (c *concurrencyLimiter) Reset(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.formatKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+	c.config.observeReset(ctx, key)
+	return nil
+}
+
+// Close closes the rate limiter and releases resources.
+func (c *concurrencyLimiter) Close() error {
+	if c.client != nil {
+		return c.client.Close()
+	}
+	return nil
+}
+
+// releaseFunc returns an idempotent function that frees one slot of redisKey.
+func (c *concurrencyLimiter) releaseFunc(ctx context.Context, redisKey string) func() {
+	// Release must still run after the request context is cancelled
+	releaseCtx := context.WithoutCancel(ctx)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_ = c.client.Eval(releaseCtx, concurrencyReleaseScript, []string{redisKey}).Err()
+		})
+	}
+}
+
+// formatKey formats the Redis key for the key's in-flight counter.
+func (c *concurrencyLimiter) formatKey(key string) string {
+	return c.config.FormatKey(key) + ":inflight"
+}
+
+// tryAcquire runs the acquire script and returns whether a slot was claimed
+// and the number of requests in flight.
+func (c *concurrencyLimiter) tryAcquire(ctx context.Context, key string) (bool, int64, error) {
+	if err := c.config.checkCallBudget(ctx); err != nil {
+		return false, 0, err
+	}
+
+	ttl := c.config.effectiveTTL(c.config.Window).Milliseconds()
+
+	result, err := c.client.Eval(ctx, concurrencyAcquireScript, []string{key}, c.config.Limit, ttl).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 2 {
+		return false, 0, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+
+	acquiredInt, ok := resultSlice[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected acquired type: %T", resultSlice[0])
+	}
+
+	inFlight, ok := resultSlice[1].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected in-flight type: %T", resultSlice[1])
+	}
+
+	return acquiredInt == 1, inFlight, nil
+}
@@ -0,0 +1,270 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrency_Integration_AcquireRelease(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     2,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+
+	result, release1, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	result, release2, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	// Third concurrent request should be denied
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Finishing one request frees its slot immediately
+	release1()
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	release2()
+}
+
+func TestConcurrency_Integration_AcquireCtxReleasesOnCancel(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	key := "user:123"
+	reqCtx, cancel := context.WithCancel(context.Background())
+
+	result, err := limiter.AcquireCtx(reqCtx, key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// Slot is held while the context is alive
+	result, _, err = limiter.Acquire(context.Background(), key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Cancelling the context frees the slot
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		result, _, err := limiter.Acquire(context.Background(), key)
+		return err == nil && result.Allowed
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConcurrency_Integration_AcquireCtxReleasesOnTimeout(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	key := "user:123"
+	reqCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := limiter.AcquireCtx(reqCtx, key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	assert.Eventually(t, func() bool {
+		result, _, err := limiter.Acquire(context.Background(), key)
+		return err == nil && result.Allowed
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConcurrency_Integration_SafetyTTL(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    10 * time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+
+	// Leak a slot by never releasing it
+	result, _, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Leaked slot is reclaimed once the safety TTL passes
+	mr.FastForward(11 * time.Second)
+
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestConcurrency_Integration_Reset(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+
+	_, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+
+	require.NoError(t, limiter.Reset(ctx, key))
+
+	result, _, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestConcurrency_Integration_DoubleRelease(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     2,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+	redisKey := limiter.(*concurrencyLimiter).formatKey(key)
+
+	_, release1, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	_, release2, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+
+	// Releasing the same slot twice only frees it once
+	release1()
+	release1()
+
+	value, err := mr.Get(redisKey)
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	result, _, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "double release must not free a second slot")
+
+	release2()
+}
+
+func TestConcurrency_Integration_ReleaseAfterTTL(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    10 * time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+	redisKey := limiter.(*concurrencyLimiter).formatKey(key)
+
+	_, release, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+
+	// The safety TTL reclaims the slot before the request finishes
+	mr.FastForward(11 * time.Second)
+	require.False(t, mr.Exists(redisKey))
+
+	// A late release must not drive the counter negative
+	release()
+	assert.False(t, mr.Exists(redisKey))
+
+	// So the limit still holds afterwards
+	result, _, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, _, err = limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestConcurrency_Integration_ReleaseAfterPanic(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:123"
+
+	handle := func() {
+		_, release, err := limiter.Acquire(ctx, key)
+		require.NoError(t, err)
+		defer release()
+		panic("handler failed")
+	}
+	assert.Panics(t, handle)
+
+	result, _, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "deferred release should free the slot after a panic")
+}
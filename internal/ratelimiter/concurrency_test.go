@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConcurrency(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	tests := []struct {
+		name        string
+		client      *redis.Client
+		config      *Config
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:   "valid config",
+			client: client,
+			config: &Config{
+				Algorithm: Concurrency,
+				Limit:     10,
+				Window:    time.Minute,
+			},
+			expectError: false,
+		},
+		{
+			name:        "nil client",
+			client:      nil,
+			config:      &Config{Algorithm: Concurrency, Limit: 10, Window: time.Minute},
+			expectError: true,
+			errorMsg:    "redis client cannot be nil",
+		},
+		{
+			name:        "nil config",
+			client:      client,
+			config:      nil,
+			expectError: true,
+			errorMsg:    "config cannot be nil",
+		},
+		{
+			name:   "invalid config - zero limit",
+			client: client,
+			config: &Config{
+				Algorithm: Concurrency,
+				Limit:     0,
+				Window:    time.Minute,
+			},
+			expectError: true,
+			errorMsg:    "invalid config",
+		},
+		{
+			name:   "wrong algorithm",
+			client: client,
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     10,
+				Window:    time.Minute,
+			},
+			expectError: true,
+			errorMsg:    "concurrency limiter requires",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewConcurrency(tt.client, tt.config)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, limiter)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, limiter)
+			}
+		})
+	}
+}
@@ -2,50 +2,311 @@
 package ratelimiter
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	// DefaultPrefix is the default Redis key prefix
 	DefaultPrefix = "ratelimit"
+
+	// MaxScriptKeys is the maximum number of Redis keys a single Lua script may
+	// touch. Redis runs scripts on its single thread, so a script over many
+	// keys blocks every other client until it finishes. Configs and calls that
+	// would exceed it are rejected before reaching Redis.
+	MaxScriptKeys = 64
+
+	// MaxSubWindows is the maximum number of sub-windows a sliding window can
+	// be divided into. Each decision reads one key per sub-window plus one,
+	// which keeps it within MaxScriptKeys.
+	MaxSubWindows = 60
+
+	// MinTTLRefreshFraction is the smallest non-zero Config.TTLRefreshFraction.
+	// Keys live for two windows, so refreshing below half keeps at least one
+	// window of TTL, which is all the state ever needs.
+	MinTTLRefreshFraction = 0.5
+
+	// DefaultLocalCacheTTL is how long a cached limiter trusts a local denial
+	// when Config.LocalCacheTTL is not set.
+	DefaultLocalCacheTTL = time.Second
+
+	// DefaultMinKeyTTL is the shortest TTL set on a Redis key when
+	// Config.MinKeyTTL is not set.
+	DefaultMinKeyTTL = time.Second
 )
 
 // Validate checks if the configuration is valid
-// Returns an error describing what is invalid
+// Returns an error describing what is invalid. Every problem found is reported
+// as a *ValidationError naming the offending field; use ValidationErrors to
+// list them.
 func (c *Config) Validate() error {
 	if c == nil {
 		return fmt.Errorf("config cannot be nil")
 	}
 
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
 	// Validate algorithm
 	switch c.Algorithm {
-	case TokenBucket, SlidingWindow, FixedWindow:
+	case TokenBucket, SlidingWindow, FixedWindow, Concurrency:
 		// Valid algorithm
 	case "":
-		return fmt.Errorf("algorithm is required")
+		invalid("Algorithm", "algorithm is required")
 	default:
-		return fmt.Errorf("unknown algorithm: %s (must be one of: token_bucket, sliding_window, fixed_window)", c.Algorithm)
+		invalid("Algorithm", "unknown algorithm: %s (must be one of: token_bucket, sliding_window, fixed_window, concurrency)", c.Algorithm)
 	}
 
 	// Validate limit
 	if c.Limit <= 0 {
-		return fmt.Errorf("limit must be greater than 0, got: %d", c.Limit)
+		invalid("Limit", "limit must be greater than 0, got: %d", c.Limit)
 	}
 
 	// Validate window
-	if c.Window <= 0 {
-		return fmt.Errorf("window must be greater than 0, got: %v", c.Window)
-	}
+	windowValid := false
+	switch {
+	case c.Window <= 0:
+		invalid("Window", "window must be greater than 0, got: %v", c.Window)
 
 	// Window should be reasonable (at least 1 millisecond, at most 365 days)
-	if c.Window < time.Millisecond {
-		return fmt.Errorf("window too small: %v (minimum: 1ms)", c.Window)
+	case c.Window < time.Millisecond:
+		invalid("Window", "window too small: %v (minimum: 1ms)", c.Window)
+	case c.Window > 365*24*time.Hour:
+		invalid("Window", "window too large: %v (maximum: 365 days)", c.Window)
+
+	// Window algorithms use the window in whole seconds for key suffixes and TTLs
+	case (c.Algorithm == FixedWindow || c.Algorithm == SlidingWindow) && c.Window%time.Second != 0:
+		invalid("Window", "window must be a whole number of seconds for %s, got: %v", c.Algorithm, c.Window)
+	default:
+		windowValid = true
+	}
+
+	// Validate window alignment
+	switch c.Alignment {
+	case "", AlignedToEpoch:
+		// Valid for every algorithm
+	case AlignedToFirstRequest:
+		if c.Algorithm != FixedWindow {
+			invalid("Alignment", "alignment %s is only supported for %s, got: %s", c.Alignment, FixedWindow, c.Algorithm)
+		}
+	default:
+		invalid("Alignment", "unknown alignment: %s (must be one of: epoch, first_request)", c.Alignment)
+	}
+
+	// Validate minimum interval
+	if c.MinInterval < 0 {
+		invalid("MinInterval", "min interval must not be negative, got: %v", c.MinInterval)
+	} else if c.MinInterval > 0 {
+		switch {
+		case c.Algorithm != FixedWindow && c.Algorithm != TokenBucket:
+			invalid("MinInterval", "min interval is only supported for %s and %s, got: %s", FixedWindow, TokenBucket, c.Algorithm)
+		case c.Alignment == AlignedToFirstRequest:
+			invalid("MinInterval", "min interval is not supported with alignment %s", AlignedToFirstRequest)
+		case c.MinInterval < time.Millisecond:
+			invalid("MinInterval", "min interval too small: %v (minimum: 1ms)", c.MinInterval)
+		}
+	}
+
+	// Validate post-reset grace
+	if c.PostResetGrace < 0 {
+		invalid("PostResetGrace", "post-reset grace must not be negative, got: %d", c.PostResetGrace)
+	} else if c.PostResetGrace > 0 {
+		switch {
+		case c.Algorithm != FixedWindow:
+			invalid("PostResetGrace", "post-reset grace is only supported for %s, got: %s", FixedWindow, c.Algorithm)
+		case c.Alignment == AlignedToFirstRequest:
+			invalid("PostResetGrace", "post-reset grace is not supported with alignment %s", AlignedToFirstRequest)
+		case c.PostResetGracePeriod < 0 || (c.Window > 0 && c.PostResetGracePeriod > c.Window):
+			invalid("PostResetGracePeriod", "post-reset grace period must be between 0 and window (%v), got: %v", c.Window, c.PostResetGracePeriod)
+		}
+	}
+
+	// Validate key time resolution
+	if c.KeyTimeResolution < 0 {
+		invalid("KeyTimeResolution", "key time resolution must not be negative, got: %v", c.KeyTimeResolution)
+	} else if c.KeyTimeResolution > 0 {
+		switch {
+		case c.Algorithm != FixedWindow:
+			invalid("KeyTimeResolution", "key time resolution is only supported for %s, got: %s", FixedWindow, c.Algorithm)
+		case c.Alignment == AlignedToFirstRequest:
+			invalid("KeyTimeResolution", "key time resolution is not supported with alignment %s", AlignedToFirstRequest)
+		case windowValid && c.KeyTimeResolution%c.Window != 0:
+			invalid("KeyTimeResolution", "key time resolution must be a multiple of the window %v, got: %v", c.Window, c.KeyTimeResolution)
+		}
+	}
+
+	// Validate per-key windows
+	if c.WindowResolver != nil {
+		switch {
+		case c.Algorithm != FixedWindow:
+			invalid("WindowResolver", "window resolver is only supported for %s, got: %s", FixedWindow, c.Algorithm)
+		case c.Alignment == AlignedToFirstRequest:
+			invalid("WindowResolver", "window resolver is not supported with alignment %s", AlignedToFirstRequest)
+		case c.KeyTimeResolution > 0:
+			invalid("WindowResolver", "window resolver is not supported with KeyTimeResolution")
+		case c.PostResetGrace > 0:
+			invalid("WindowResolver", "window resolver is not supported with PostResetGrace")
+		}
+	}
+
+	// Validate windowed state
+	if c.WindowedState {
+		switch {
+		case c.Algorithm != TokenBucket:
+			invalid("WindowedState", "windowed state is only supported for %s, got: %s", TokenBucket, c.Algorithm)
+		case windowValid && c.Window%time.Second != 0:
+			invalid("WindowedState", "windowed state requires a whole number of seconds, got window: %v", c.Window)
+		}
+	}
+
+	// Validate fail-open estimate
+	if c.FailOpenEstimate && !c.FailOpen {
+		invalid("FailOpenEstimate", "fail-open estimate requires FailOpen")
+	}
+
+	// Validate min call budget
+	if c.MinCallBudget < 0 {
+		invalid("MinCallBudget", "min call budget must not be negative, got: %v", c.MinCallBudget)
+	}
+
+	// Validate active schedule
+	if len(c.ActiveSchedule) > 0 && c.Algorithm == Concurrency {
+		invalid("ActiveSchedule", "active schedule is not supported for %s", Concurrency)
+	}
+	for i, window := range c.ActiveSchedule {
+		if err := window.validate(); err != nil {
+			invalid("ActiveSchedule", "window %d: %v", i, err)
+		}
 	}
-	if c.Window > 365*24*time.Hour {
-		return fmt.Errorf("window too large: %v (maximum: 365 days)", c.Window)
+
+	// Validate denied key tracking
+	if c.TrackTopDenied < 0 {
+		invalid("TrackTopDenied", "track top denied must not be negative, got: %d", c.TrackTopDenied)
+	}
+
+	// Validate critical reserve
+	switch {
+	case c.ReserveForCritical < 0:
+		invalid("ReserveForCritical", "reserve for critical must not be negative, got: %d", c.ReserveForCritical)
+	case c.ReserveForCritical > 0 && c.Algorithm == Concurrency:
+		invalid("ReserveForCritical", "reserve for critical is not supported for %s", Concurrency)
+	case c.Limit > 0 && c.ReserveForCritical >= c.Limit:
+		invalid("ReserveForCritical", "reserve for critical (%d) must be less than limit (%d)", c.ReserveForCritical, c.Limit)
+	}
+
+	// Validate observe-only warmup
+	switch {
+	case c.ObserveFirst < 0:
+		invalid("ObserveFirst", "observe first must not be negative, got: %d", c.ObserveFirst)
+	case c.ObserveFirst > 0 && c.Algorithm == Concurrency:
+		invalid("ObserveFirst", "observe first is not supported for %s", Concurrency)
 	}
 
+	// Validate local cache TTL
+	if c.LocalCacheTTL < 0 {
+		invalid("LocalCacheTTL", "local cache ttl must not be negative, got: %v", c.LocalCacheTTL)
+	}
+
+	// Validate minimum key TTL
+	if c.MinKeyTTL < 0 {
+		invalid("MinKeyTTL", "min key ttl must not be negative, got: %v", c.MinKeyTTL)
+	}
+
+	// Validate class limits
+	for class, limit := range c.ClassLimits {
+		if class == "" {
+			invalid("ClassLimits", "class name must not be empty")
+		} else if limit <= 0 {
+			invalid("ClassLimits", "limit for class %q must be greater than 0, got: %d", class, limit)
+		}
+	}
+
+	// Validate initial tokens
+	if c.InitialTokens < 0 {
+		invalid("InitialTokens", "initial tokens must not be negative, got: %d", c.InitialTokens)
+	} else if c.Limit > 0 && c.InitialTokens > c.Limit {
+		invalid("InitialTokens", "initial tokens (%d) cannot exceed limit (%d)", c.InitialTokens, c.Limit)
+	}
+
+	// Validate initial fill
+	if !(c.InitialFill >= 0 && c.InitialFill <= 1) {
+		invalid("InitialFill", "initial fill must be between 0 and 1, got: %v", c.InitialFill)
+	}
+
+	// Strict mode rejects refill rates the whole-token Remaining can't represent
+	if c.Strict && c.Algorithm == TokenBucket && c.Limit > 0 && windowValid {
+		if rate := float64(c.Limit) / c.Window.Seconds(); rate < 1 {
+			invalid("Limit", "refill rate %.6g tokens/s is below one token per second (limit %d per %v); raise the limit or shorten the window, or disable Strict", rate, c.Limit, c.Window)
+		}
+	}
+
+	// Validate state retention
+	if c.StateRetention < 0 {
+		invalid("StateRetention", "state retention must not be negative, got: %v", c.StateRetention)
+	} else if c.StateRetention > 0 {
+		switch {
+		case c.Algorithm != TokenBucket:
+			invalid("StateRetention", "state retention is only supported for %s, got: %s", TokenBucket, c.Algorithm)
+		case c.WindowedState:
+			invalid("StateRetention", "state retention is not supported with windowed state")
+		case c.StateRetention < c.Window:
+			invalid("StateRetention", "state retention must be at least the window (%v), got: %v", c.Window, c.StateRetention)
+		}
+	}
+	if c.ExpireFullBuckets {
+		switch {
+		case c.Algorithm != TokenBucket:
+			invalid("ExpireFullBuckets", "expiring full buckets is only supported for %s, got: %s", TokenBucket, c.Algorithm)
+		case c.WindowedState:
+			invalid("ExpireFullBuckets", "expiring full buckets is not supported with windowed state")
+		}
+	}
+
+	// Validate TTL refresh fraction
+	if c.TTLRefreshFraction != 0 && (c.TTLRefreshFraction < MinTTLRefreshFraction || c.TTLRefreshFraction > 1) {
+		invalid("TTLRefreshFraction", "ttl refresh fraction must be 0 or between %v and 1, got: %v", MinTTLRefreshFraction, c.TTLRefreshFraction)
+	}
+
+	// Validate sub-windows
+	if c.SubWindows < 0 {
+		invalid("SubWindows", "sub-windows must not be negative, got: %d", c.SubWindows)
+	} else if c.Algorithm == SlidingWindow && c.SubWindows > 1 && windowValid {
+		if err := validateSubWindows(c.Window, c.SubWindows); err != nil {
+			invalid("SubWindows", "%v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateScriptKeys checks that a script over the given number of keys stays
+// within MaxScriptKeys
+func validateScriptKeys(keys int) error {
+	if keys > MaxScriptKeys {
+		return fmt.Errorf("%w: %d keys in one script exceeds the maximum of %d", ErrTooManyKeys, keys, MaxScriptKeys)
+	}
+	return nil
+}
+
+// validateSubWindows checks that window divides into the given number of
+// whole-second sub-windows
+func validateSubWindows(window time.Duration, subWindows int) error {
+	if subWindows < 1 || subWindows > MaxSubWindows {
+		return fmt.Errorf("sub-windows must be between 1 and %d, got: %d (each sub-window is a Redis key read by one script)", MaxSubWindows, subWindows)
+	}
+	if window%(time.Duration(subWindows)*time.Second) != 0 {
+		return fmt.Errorf("window %v cannot be divided into %d whole-second sub-windows", window, subWindows)
+	}
 	return nil
 }
 
@@ -57,6 +318,16 @@ func (c *Config) WithDefaults() *Config {
 	}
 
 	result := *c // Copy
+	result.ClassLimits = maps.Clone(c.ClassLimits)
+	result.Denylist = slices.Clone(c.Denylist)
+	result.ActiveSchedule = slices.Clone(c.ActiveSchedule)
+	result.denied = nil
+	if len(c.Denylist) > 0 {
+		result.denied = make(map[string]struct{}, len(c.Denylist))
+		for _, key := range c.Denylist {
+			result.denied[key] = struct{}{}
+		}
+	}
 
 	// Apply default prefix if not set
 	if result.Prefix == "" {
@@ -66,13 +337,103 @@ func (c *Config) WithDefaults() *Config {
 	return &result
 }
 
+// Fingerprint returns a deterministic hash of the fields that affect rate
+// limiting decisions, for use as a cache key or to detect config drift
+// Defaults are applied first and fields the algorithm ignores are left out,
+// so two configs that behave identically have the same fingerprint.
+// Hooks (Observer, MetricKeyLabel, LimitResolver, WindowResolver, RequestCost,
+// OnKeyCreated, OnExhausted, OnPanic) are not included.
+func (c *Config) Fingerprint() string {
+	cfg := c.WithDefaults()
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	var b strings.Builder
+	field := func(name string, value any) {
+		fmt.Fprintf(&b, "%s=%v\n", name, value)
+	}
+
+	field("algorithm", cfg.Algorithm)
+	field("limit", cfg.Limit)
+	field("window", int64(cfg.Window))
+	field("prefix", cfg.Prefix)
+	field("tag_keys_with_algorithm", cfg.TagKeysWithAlgorithm)
+	field("fail_open", cfg.FailOpen)
+	field("fail_open_estimate", cfg.FailOpenEstimate)
+	field("round_retry_after", cfg.RoundRetryAfter)
+	field("limit_change_window", int64(cfg.LimitChangeWindow))
+	field("local_cache_ttl", int64(cmp.Or(cfg.LocalCacheTTL, DefaultLocalCacheTTL)))
+	field("min_key_ttl", int64(cmp.Or(cfg.MinKeyTTL, DefaultMinKeyTTL)))
+	field("min_call_budget", int64(cfg.MinCallBudget))
+	field("reserve_for_critical", cfg.ReserveForCritical)
+	field("observe_first", cfg.ObserveFirst)
+
+	switch cfg.Algorithm {
+	case FixedWindow:
+		field("cap_counter_at_limit", cfg.CapCounterAtLimit)
+		field("alignment", cmp.Or(cfg.Alignment, AlignedToEpoch))
+		field("min_interval", int64(cfg.MinInterval))
+		field("key_time_resolution", int64(cfg.KeyTimeResolution))
+		field("post_reset_grace", cfg.PostResetGrace)
+		if cfg.PostResetGrace > 0 {
+			// The period only matters when there is a grace to decay
+			field("post_reset_grace_period", int64(cmp.Or(cfg.PostResetGracePeriod, cfg.Window/10)))
+		}
+	case TokenBucket:
+		// A bucket starts full both without InitialTokens or InitialFill and
+		// with InitialTokens == Limit
+		initial := cfg.initialTokens(cfg.Limit)
+		if initial == 0 {
+			initial = cfg.Limit
+		}
+		field("initial_tokens", initial)
+		field("ttl_refresh_fraction", cfg.TTLRefreshFraction)
+		field("min_interval", int64(cfg.MinInterval))
+		field("windowed_state", cfg.WindowedState)
+		field("state_retention", int64(cmp.Or(cfg.StateRetention, 2*cfg.Window)))
+		field("expire_full_buckets", cfg.ExpireFullBuckets)
+	case SlidingWindow:
+		field("sub_windows", max(cfg.SubWindows, 1))
+		field("hash_tag_keys", cfg.HashTagKeys)
+		field("ttl_refresh_fraction", cfg.TTLRefreshFraction)
+	}
+
+	denylist := slices.Sorted(maps.Keys(cfg.denied))
+	for _, key := range denylist {
+		field("denylist", strconv.Quote(key))
+	}
+
+	if len(cfg.ActiveSchedule) > 0 {
+		field("schedule_location", cmp.Or(cfg.ScheduleLocation, time.UTC))
+		for _, window := range cfg.ActiveSchedule {
+			field("schedule", fmt.Sprintf("%v %d-%d", window.Days, int64(window.Start), int64(window.End)))
+		}
+	}
+
+	classes := slices.Sorted(maps.Keys(cfg.ClassLimits))
+	for _, class := range classes {
+		field("class:"+strconv.Quote(class), cfg.ClassLimits[class])
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
 // KeyPrefix returns the full prefix to use for Redis keys
-// Handles the case where prefix is explicitly set to empty string
+// Handles the case where prefix is explicitly set to empty string, and
+// appends the algorithm when Config.TagKeysWithAlgorithm is set
 func (c *Config) KeyPrefix() string {
 	if c == nil {
 		return DefaultPrefix
 	}
 	// Note: We don't apply defaults here - empty string means "no prefix"
+	if c.TagKeysWithAlgorithm && c.Algorithm != "" {
+		if c.Prefix == "" {
+			return string(c.Algorithm)
+		}
+		return c.Prefix + ":" + string(c.Algorithm)
+	}
 	return c.Prefix
 }
 
@@ -1,6 +1,8 @@
 package ratelimiter
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -45,6 +47,15 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid concurrency config",
+			config: &Config{
+				Algorithm: Concurrency,
+				Limit:     10,
+				Window:    time.Minute,
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing algorithm",
 			config: &Config{
@@ -124,6 +135,85 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "window too large",
 		},
+		{
+			name: "fixed window not whole seconds",
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     100,
+				Window:    1500 * time.Millisecond,
+			},
+			wantErr: true,
+			errMsg:  "window must be a whole number of seconds",
+		},
+		{
+			name: "sliding window not whole seconds",
+			config: &Config{
+				Algorithm: SlidingWindow,
+				Limit:     100,
+				Window:    1500 * time.Millisecond,
+			},
+			wantErr: true,
+			errMsg:  "window must be a whole number of seconds",
+		},
+		{
+			name: "fixed window aligned to first request",
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     100,
+				Window:    time.Minute,
+				Alignment: AlignedToFirstRequest,
+			},
+			wantErr: false,
+		},
+		{
+			name: "first request alignment on sliding window",
+			config: &Config{
+				Algorithm: SlidingWindow,
+				Limit:     100,
+				Window:    time.Minute,
+				Alignment: AlignedToFirstRequest,
+			},
+			wantErr: true,
+			errMsg:  "alignment first_request is only supported for fixed_window",
+		},
+		{
+			name: "unknown alignment",
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     100,
+				Window:    time.Minute,
+				Alignment: "hourly",
+			},
+			wantErr: true,
+			errMsg:  "unknown alignment",
+		},
+		{
+			name: "fixed window 90 seconds",
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     100,
+				Window:    90 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "sliding window 90 seconds",
+			config: &Config{
+				Algorithm: SlidingWindow,
+				Limit:     100,
+				Window:    90 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "token bucket fractional seconds",
+			config: &Config{
+				Algorithm: TokenBucket,
+				Limit:     100,
+				Window:    1500 * time.Millisecond,
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid with custom prefix",
 			config: &Config{
@@ -144,6 +234,354 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid sliding window sub-windows",
+			config: &Config{
+				Algorithm:  SlidingWindow,
+				Limit:      100,
+				Window:     time.Minute,
+				SubWindows: 6,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative sub-windows",
+			config: &Config{
+				Algorithm:  SlidingWindow,
+				Limit:      100,
+				Window:     time.Minute,
+				SubWindows: -1,
+			},
+			wantErr: true,
+			errMsg:  "sub-windows must not be negative",
+		},
+		{
+			name: "too many sub-windows",
+			config: &Config{
+				Algorithm:  SlidingWindow,
+				Limit:      100,
+				Window:     time.Hour,
+				SubWindows: MaxSubWindows + 1,
+			},
+			wantErr: true,
+			errMsg:  "sub-windows must be between",
+		},
+		{
+			name: "sub-windows not whole seconds",
+			config: &Config{
+				Algorithm:  SlidingWindow,
+				Limit:      100,
+				Window:     time.Minute,
+				SubWindows: 7,
+			},
+			wantErr: true,
+			errMsg:  "whole-second sub-windows",
+		},
+		{
+			name: "valid ttl refresh fraction",
+			config: &Config{
+				Algorithm:          TokenBucket,
+				Limit:              100,
+				Window:             time.Minute,
+				TTLRefreshFraction: 0.5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "ttl refresh fraction too small",
+			config: &Config{
+				Algorithm:          TokenBucket,
+				Limit:              100,
+				Window:             time.Minute,
+				TTLRefreshFraction: 0.2,
+			},
+			wantErr: true,
+			errMsg:  "ttl refresh fraction",
+		},
+		{
+			name: "ttl refresh fraction too large",
+			config: &Config{
+				Algorithm:          SlidingWindow,
+				Limit:              100,
+				Window:             time.Minute,
+				TTLRefreshFraction: 1.5,
+			},
+			wantErr: true,
+			errMsg:  "ttl refresh fraction",
+		},
+		{
+			name: "valid initial tokens",
+			config: &Config{
+				Algorithm:     TokenBucket,
+				Limit:         100,
+				Window:        time.Minute,
+				InitialTokens: 10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative local cache ttl",
+			config: &Config{
+				Algorithm:     FixedWindow,
+				Limit:         100,
+				Window:        time.Minute,
+				LocalCacheTTL: -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "local cache ttl must not be negative",
+		},
+		{
+			name: "negative min key ttl",
+			config: &Config{
+				Algorithm: FixedWindow,
+				Limit:     100,
+				Window:    time.Minute,
+				MinKeyTTL: -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "min key ttl must not be negative",
+		},
+		{
+			name: "valid min interval",
+			config: &Config{
+				Algorithm:   TokenBucket,
+				Limit:       100,
+				Window:      time.Minute,
+				MinInterval: 200 * time.Millisecond,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative min interval",
+			config: &Config{
+				Algorithm:   FixedWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				MinInterval: -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "min interval must not be negative",
+		},
+		{
+			name: "min interval with sliding window",
+			config: &Config{
+				Algorithm:   SlidingWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				MinInterval: time.Second,
+			},
+			wantErr: true,
+			errMsg:  "min interval is only supported for fixed_window and token_bucket",
+		},
+		{
+			name: "min interval with first-request alignment",
+			config: &Config{
+				Algorithm:   FixedWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				Alignment:   AlignedToFirstRequest,
+				MinInterval: time.Second,
+			},
+			wantErr: true,
+			errMsg:  "min interval is not supported with alignment first_request",
+		},
+		{
+			name: "post-reset grace with token bucket",
+			config: &Config{
+				Algorithm:      TokenBucket,
+				Limit:          100,
+				Window:         time.Minute,
+				PostResetGrace: 10,
+			},
+			wantErr: true,
+			errMsg:  "post-reset grace is only supported for fixed_window",
+		},
+		{
+			name: "post-reset grace period longer than window",
+			config: &Config{
+				Algorithm:            FixedWindow,
+				Limit:                100,
+				Window:               time.Minute,
+				PostResetGrace:       10,
+				PostResetGracePeriod: 2 * time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "post-reset grace period must be between 0 and window",
+		},
+		{
+			name: "window resolver with sliding window",
+			config: &Config{
+				Algorithm:      SlidingWindow,
+				Limit:          100,
+				Window:         time.Minute,
+				WindowResolver: func(string) time.Duration { return time.Hour },
+			},
+			wantErr: true,
+			errMsg:  "window resolver is only supported for fixed_window",
+		},
+		{
+			name: "window resolver with key time resolution",
+			config: &Config{
+				Algorithm:         FixedWindow,
+				Limit:             100,
+				Window:            time.Minute,
+				KeyTimeResolution: time.Hour,
+				WindowResolver:    func(string) time.Duration { return time.Hour },
+			},
+			wantErr: true,
+			errMsg:  "window resolver is not supported with KeyTimeResolution",
+		},
+		{
+			name: "state retention shorter than window",
+			config: &Config{
+				Algorithm:      TokenBucket,
+				Limit:          100,
+				Window:         time.Hour,
+				StateRetention: time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "state retention must be at least the window",
+		},
+		{
+			name: "expire full buckets with fixed window",
+			config: &Config{
+				Algorithm:         FixedWindow,
+				Limit:             100,
+				Window:            time.Hour,
+				ExpireFullBuckets: true,
+			},
+			wantErr: true,
+			errMsg:  "expiring full buckets is only supported for token_bucket",
+		},
+		{
+			name: "valid key time resolution",
+			config: &Config{
+				Algorithm:         FixedWindow,
+				Limit:             100,
+				Window:            time.Second,
+				KeyTimeResolution: time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "key time resolution not a multiple of the window",
+			config: &Config{
+				Algorithm:         FixedWindow,
+				Limit:             100,
+				Window:            7 * time.Second,
+				KeyTimeResolution: time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "key time resolution must be a multiple of the window",
+		},
+		{
+			name: "key time resolution with token bucket",
+			config: &Config{
+				Algorithm:         TokenBucket,
+				Limit:             100,
+				Window:            time.Second,
+				KeyTimeResolution: time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "key time resolution is only supported for fixed_window",
+		},
+		{
+			name: "valid windowed state",
+			config: &Config{
+				Algorithm:     TokenBucket,
+				Limit:         100,
+				Window:        time.Minute,
+				WindowedState: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "windowed state with fixed window",
+			config: &Config{
+				Algorithm:     FixedWindow,
+				Limit:         100,
+				Window:        time.Minute,
+				WindowedState: true,
+			},
+			wantErr: true,
+			errMsg:  "windowed state is only supported for token_bucket",
+		},
+		{
+			name: "windowed state with fractional window",
+			config: &Config{
+				Algorithm:     TokenBucket,
+				Limit:         100,
+				Window:        1500 * time.Millisecond,
+				WindowedState: true,
+			},
+			wantErr: true,
+			errMsg:  "windowed state requires a whole number of seconds",
+		},
+		{
+			name: "valid class limits",
+			config: &Config{
+				Algorithm:   FixedWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				ClassLimits: map[string]int64{"read": 100, "write": 10},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero class limit",
+			config: &Config{
+				Algorithm:   FixedWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				ClassLimits: map[string]int64{"write": 0},
+			},
+			wantErr: true,
+			errMsg:  "limit for class \"write\" must be greater than 0",
+		},
+		{
+			name: "empty class name",
+			config: &Config{
+				Algorithm:   FixedWindow,
+				Limit:       100,
+				Window:      time.Minute,
+				ClassLimits: map[string]int64{"": 10},
+			},
+			wantErr: true,
+			errMsg:  "class name must not be empty",
+		},
+		{
+			name: "negative initial tokens",
+			config: &Config{
+				Algorithm:     TokenBucket,
+				Limit:         100,
+				Window:        time.Minute,
+				InitialTokens: -1,
+			},
+			wantErr: true,
+			errMsg:  "initial tokens must not be negative",
+		},
+		{
+			name: "initial tokens above limit",
+			config: &Config{
+				Algorithm:     TokenBucket,
+				Limit:         100,
+				Window:        time.Minute,
+				InitialTokens: 101,
+			},
+			wantErr: true,
+			errMsg:  "cannot exceed limit",
+		},
+		{
+			name: "initial fill above one",
+			config: &Config{
+				Algorithm:   TokenBucket,
+				Limit:       100,
+				Window:      time.Minute,
+				InitialFill: 1.5,
+			},
+			wantErr: true,
+			errMsg:  "initial fill must be between 0 and 1",
+		},
 		{
 			name: "valid with fail-open",
 			config: &Config{
@@ -177,6 +615,43 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_ReportsEveryField(t *testing.T) {
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     0,
+		Window:    -time.Second,
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() expected error, got nil")
+	}
+
+	fields := ValidationErrors(err)
+	if len(fields) != 2 {
+		t.Fatalf("ValidationErrors() = %v, want 2 errors", fields)
+	}
+	if fields[0].Field != "Limit" || fields[1].Field != "Window" {
+		t.Errorf("fields = %q, %q, want Limit, Window", fields[0].Field, fields[1].Field)
+	}
+
+	// The message stays human-readable and names both problems
+	want := "limit must be greater than 0, got: 0\nwindow must be greater than 0, got: -1s"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// Constructors wrap the error, and the fields can still be extracted
+	wrapped := fmt.Errorf("invalid config: %w", err)
+	if got := ValidationErrors(wrapped); len(got) != 2 {
+		t.Errorf("ValidationErrors(wrapped) = %v, want 2 errors", got)
+	}
+
+	if got := ValidationErrors(nil); got != nil {
+		t.Errorf("ValidationErrors(nil) = %v, want nil", got)
+	}
+}
+
 func TestConfig_WithDefaults(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -337,6 +812,137 @@ func TestConfig_FormatKey(t *testing.T) {
 	}
 }
 
+func TestConfig_Fingerprint(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			Algorithm: FixedWindow,
+			Limit:     100,
+			Window:    time.Minute,
+			Prefix:    "api",
+		}
+	}
+
+	if base().Fingerprint() != base().Fingerprint() {
+		t.Error("equal configs should have equal fingerprints")
+	}
+
+	// Configs that behave identically fingerprint identically
+	same := []struct {
+		name string
+		a, b *Config
+	}{
+		{
+			name: "default prefix",
+			a:    &Config{Algorithm: FixedWindow, Limit: 100, Window: time.Minute},
+			b:    &Config{Algorithm: FixedWindow, Limit: 100, Window: time.Minute, Prefix: DefaultPrefix},
+		},
+		{
+			name: "hooks are ignored",
+			a:    base(),
+			b: func() *Config {
+				c := base()
+				c.Observer = &recordingObserver{}
+				c.OnKeyCreated = func(string) {}
+				return c
+			}(),
+		},
+		{
+			name: "field the algorithm ignores",
+			a:    base(),
+			b: func() *Config {
+				c := base()
+				c.SubWindows = 6
+				return c
+			}(),
+		},
+		{
+			name: "initial tokens equal to limit",
+			a:    &Config{Algorithm: TokenBucket, Limit: 10, Window: time.Minute},
+			b:    &Config{Algorithm: TokenBucket, Limit: 10, Window: time.Minute, InitialTokens: 10},
+		},
+		{
+			name: "initial fill of one",
+			a:    &Config{Algorithm: TokenBucket, Limit: 10, Window: time.Minute},
+			b:    &Config{Algorithm: TokenBucket, Limit: 10, Window: time.Minute, InitialFill: 1},
+		},
+		{
+			name: "default min key ttl",
+			a:    base(),
+			b: func() *Config {
+				c := base()
+				c.MinKeyTTL = DefaultMinKeyTTL
+				return c
+			}(),
+		},
+		{
+			name: "grace period without a grace",
+			a:    base(),
+			b: func() *Config {
+				c := base()
+				c.PostResetGracePeriod = time.Second
+				return c
+			}(),
+		},
+		{
+			name: "class limits in any order",
+			a:    &Config{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, ClassLimits: map[string]int64{"read": 10, "write": 1}},
+			b:    &Config{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, ClassLimits: map[string]int64{"write": 1, "read": 10}},
+		},
+	}
+	for _, tt := range same {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a.Fingerprint() != tt.b.Fingerprint() {
+				t.Error("fingerprints should match")
+			}
+		})
+	}
+
+	// Any decision-affecting change gives a different fingerprint
+	changes := map[string]func(c *Config){
+		"limit":     func(c *Config) { c.Limit = 101 },
+		"window":    func(c *Config) { c.Window = time.Hour },
+		"prefix":    func(c *Config) { c.Prefix = "other" },
+		"algorithm": func(c *Config) { c.Algorithm = SlidingWindow },
+		"fail open": func(c *Config) { c.FailOpen = true },
+		"alignment": func(c *Config) { c.Alignment = AlignedToFirstRequest },
+		"classes":   func(c *Config) { c.ClassLimits = map[string]int64{"read": 1} },
+		"key tag":   func(c *Config) { c.TagKeysWithAlgorithm = true },
+		"grace":     func(c *Config) { c.PostResetGrace = 5 },
+	}
+	for name, change := range changes {
+		t.Run("changed "+name, func(t *testing.T) {
+			changed := base()
+			change(changed)
+			if changed.Fingerprint() == base().Fingerprint() {
+				t.Errorf("changing %s should change the fingerprint", name)
+			}
+		})
+	}
+
+	t.Run("changed grace period", func(t *testing.T) {
+		graced := func(period time.Duration) *Config {
+			c := base()
+			c.PostResetGrace = 5
+			c.PostResetGracePeriod = period
+			return c
+		}
+		if graced(time.Second).Fingerprint() == graced(2*time.Second).Fingerprint() {
+			t.Error("changing the grace period should change the fingerprint")
+		}
+	})
+
+	t.Run("changed fail open estimate", func(t *testing.T) {
+		failOpen := base()
+		failOpen.FailOpen = true
+		estimated := base()
+		estimated.FailOpen = true
+		estimated.FailOpenEstimate = true
+		if estimated.Fingerprint() == failOpen.Fingerprint() {
+			t.Error("changing FailOpenEstimate should change the fingerprint")
+		}
+	})
+}
+
 func TestConfig_KeyPrefix(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -369,6 +975,15 @@ func TestConfig_KeyPrefix(t *testing.T) {
 			},
 			want: "",
 		},
+		{
+			name: "tagged with algorithm",
+			config: &Config{
+				Algorithm:            SlidingWindow,
+				Prefix:               "api",
+				TagKeysWithAlgorithm: true,
+			},
+			want: "api:sliding_window",
+		},
 	}
 
 	for _, tt := range tests {
@@ -381,6 +996,50 @@ func TestConfig_KeyPrefix(t *testing.T) {
 	}
 }
 
+func TestConfig_MetricLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		key    string
+		want   string
+	}{
+		{
+			name:   "nil config returns default",
+			config: nil,
+			key:    "user:123",
+			want:   DefaultMetricKeyLabel,
+		},
+		{
+			name:   "unset mapping returns default",
+			config: &Config{},
+			key:    "user:123",
+			want:   DefaultMetricKeyLabel,
+		},
+		{
+			name: "custom mapping",
+			config: &Config{
+				MetricKeyLabel: func(key string) string {
+					if len(key) > 4 && key[:4] == "user" {
+						return "user"
+					}
+					return "other"
+				},
+			},
+			key:  "user:123",
+			want: "user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.MetricLabel(tt.key)
+			if got != tt.want {
+				t.Errorf("MetricLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
@@ -395,3 +1054,48 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConfig_Validate_StrictRefillRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:    "one token per hour is rejected in strict mode",
+			config:  Config{Algorithm: TokenBucket, Limit: 1, Window: time.Hour, Strict: true},
+			wantErr: true,
+		},
+		{
+			name:   "one token per hour is accepted by default",
+			config: Config{Algorithm: TokenBucket, Limit: 1, Window: time.Hour},
+		},
+		{
+			name:   "one token per second is accepted in strict mode",
+			config: Config{Algorithm: TokenBucket, Limit: 60, Window: time.Minute, Strict: true},
+		},
+		{
+			name:   "window algorithms are not affected",
+			config: Config{Algorithm: FixedWindow, Limit: 1, Window: time.Hour, Strict: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			fields := ValidationErrors(err)
+			if len(fields) != 1 || fields[0].Field != "Limit" {
+				t.Errorf("ValidationErrors() = %v, want one Limit error", fields)
+			}
+			if !strings.Contains(err.Error(), "below one token per second") {
+				t.Errorf("Validate() error = %q, want it to explain the refill rate", err)
+			}
+		})
+	}
+}
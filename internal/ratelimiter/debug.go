@@ -0,0 +1,117 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// debugResponse is the JSON body served by DebugHandler.
+type debugResponse struct {
+	Limiters []debugLimiter `json:"limiters"`
+}
+
+// debugLimiter describes one registered limiter.
+type debugLimiter struct {
+	Name           string         `json:"name"`
+	Algorithm      Algorithm      `json:"algorithm,omitempty"`
+	Limit          int64          `json:"limit,omitempty"`
+	Window         string         `json:"window,omitempty"`
+	Prefix         string         `json:"prefix,omitempty"`
+	LimitChangedAt *time.Time     `json:"limit_changed_at,omitempty"`
+	Info           map[string]any `json:"info,omitempty"`
+	Status         *debugStatus   `json:"status,omitempty"`
+}
+
+// debugStatus is a key's Peek status.
+type debugStatus struct {
+	Key        string    `json:"key"`
+	Allowed    bool      `json:"allowed"`
+	Limit      int64     `json:"limit"`
+	Remaining  int64     `json:"remaining"`
+	Overage    int64     `json:"overage"`
+	ResetAt    time.Time `json:"reset_at"`
+	RetryAfter string    `json:"retry_after"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DebugHandler returns an http.Handler, e.g. for /debug/ratelimit, that lists
+// the limiters in registry as JSON with their Describe and DebugInfo details.
+//
+// Query parameters:
+//   - key: also report each limiter's Peek status for this key
+//   - limiter: only report the limiter with this name (404 if unknown)
+//
+// Peek does not consume quota, so querying the handler never affects the
+// limits it reports. Limiters that don't implement Describe or Peek are
+// listed by name only. The handler exposes keys and limits, so it should
+// not be reachable from untrusted networks.
+func DebugHandler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		names := registry.Names()
+		if name := r.URL.Query().Get("limiter"); name != "" {
+			if _, ok := registry.Get(name); !ok {
+				http.Error(w, "unknown limiter: "+name, http.StatusNotFound)
+				return
+			}
+			names = []string{name}
+		}
+
+		key := r.URL.Query().Get("key")
+		response := debugResponse{Limiters: make([]debugLimiter, 0, len(names))}
+		for _, name := range names {
+			limiter, ok := registry.Get(name)
+			if !ok {
+				continue
+			}
+			response.Limiters = append(response.Limiters, describeForDebug(r, name, limiter, key))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// describeForDebug builds the debug entry for one limiter.
+func describeForDebug(r *http.Request, name string, limiter RateLimiter, key string) debugLimiter {
+	entry := debugLimiter{Name: name}
+
+	if describer, ok := limiter.(interface{ Describe() QuotaInfo }); ok {
+		info := describer.Describe()
+		entry.Algorithm = info.Algorithm
+		entry.Limit = info.Limit
+		entry.Window = info.Window.String()
+		entry.Prefix = info.Prefix
+		if !info.LimitChangedAt.IsZero() {
+			entry.LimitChangedAt = &info.LimitChangedAt
+		}
+	}
+
+	if provider, ok := limiter.(DebugInfoProvider); ok {
+		entry.Info = provider.DebugInfo()
+	}
+
+	if peeker, ok := limiter.(Peeker); ok && key != "" {
+		status := &debugStatus{Key: key}
+		result, err := peeker.Peek(r.Context(), key)
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Allowed = result.Allowed
+			status.Limit = result.Limit
+			status.Remaining = result.Remaining
+			status.Overage = result.Overage
+			status.ResetAt = result.ResetAt
+			status.RetryAfter = result.RetryAfter.String()
+		}
+		entry.Status = status
+	}
+
+	return entry
+}
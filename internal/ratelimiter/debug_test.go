@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDebugRegistry(t *testing.T) (*Registry, RateLimiter) {
+	t.Helper()
+
+	client, mr := setupMiniredis(t)
+	t.Cleanup(mr.Close)
+
+	api, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	uploads, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+		Prefix:    "uploads",
+	})
+	require.NoError(t, err)
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register("api", api))
+	require.NoError(t, registry.Register("uploads", uploads))
+	require.NoError(t, registry.Register("noop", NewNoop()))
+	return registry, api
+}
+
+func getDebug(t *testing.T, handler http.Handler, target string) (*httptest.ResponseRecorder, debugResponse) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var response debugResponse
+	if recorder.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	}
+	return recorder, response
+}
+
+func TestDebugHandler_ListsLimiters(t *testing.T) {
+	registry, _ := newDebugRegistry(t)
+	handler := DebugHandler(registry)
+
+	recorder, response := getDebug(t, handler, "/debug/ratelimit")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"algorithm":"fixed_window"`)
+
+	require.Len(t, response.Limiters, 3)
+	api := response.Limiters[0]
+	assert.Equal(t, "api", api.Name)
+	assert.Equal(t, FixedWindow, api.Algorithm)
+	assert.Equal(t, int64(10), api.Limit)
+	assert.Equal(t, "1m0s", api.Window)
+	assert.Nil(t, api.Status, "status is only reported for a queried key")
+
+	// Limiters without Describe are listed by name
+	assert.Equal(t, debugLimiter{Name: "noop"}, response.Limiters[1])
+
+	assert.Equal(t, TokenBucket, response.Limiters[2].Algorithm)
+	assert.Equal(t, "uploads", response.Limiters[2].Prefix)
+}
+
+func TestDebugHandler_PeeksKey(t *testing.T) {
+	registry, api := newDebugRegistry(t)
+	handler := DebugHandler(registry)
+
+	_, err := api.AllowN(context.Background(), "user:1", 4)
+	require.NoError(t, err)
+
+	recorder, response := getDebug(t, handler, "/debug/ratelimit?key=user:1&limiter=api")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"remaining":6`)
+
+	require.Len(t, response.Limiters, 1)
+	status := response.Limiters[0].Status
+	require.NotNil(t, status)
+	assert.Equal(t, "user:1", status.Key)
+	assert.True(t, status.Allowed)
+	assert.Equal(t, int64(10), status.Limit)
+	assert.Equal(t, int64(6), status.Remaining)
+
+	// Querying doesn't consume quota
+	_, response = getDebug(t, handler, "/debug/ratelimit?key=user:1&limiter=api")
+	assert.Equal(t, int64(6), response.Limiters[0].Status.Remaining)
+}
+
+func TestDebugHandler_Errors(t *testing.T) {
+	registry, _ := newDebugRegistry(t)
+	handler := DebugHandler(registry)
+
+	recorder, _ := getDebug(t, handler, "/debug/ratelimit?limiter=missing")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/ratelimit", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, http.MethodGet, recorder.Header().Get("Allow"))
+}
@@ -0,0 +1,116 @@
+package ratelimiter
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// decisionStats counts a limiter's decisions in process for DebugInfo and,
+// with Config.TrackTopDenied, the most denied keys.
+type decisionStats struct {
+	allowed atomic.Int64
+	denied  atomic.Int64
+	errors  atomic.Int64
+
+	// deniedKeys is nil unless Config.TrackTopDenied is set
+	deniedKeys *deniedTracker
+
+	// exhausted is nil unless Config.OnExhausted is set
+	exhausted *exhaustionNotifier
+}
+
+// keyStats records decisions made for one key.
+type keyStats struct {
+	stats *decisionStats
+	key   string
+}
+
+// forKey returns a recorder for the key's decisions, so that denials can be
+// attributed to it.
+func (s *decisionStats) forKey(key string) keyStats {
+	return keyStats{stats: s, key: key}
+}
+
+// record counts a decision and passes it through unchanged.
+func (k keyStats) record(result *Result, err error) (*Result, error) {
+	if err == nil {
+		k.count(result)
+	} else {
+		k.stats.errors.Add(1)
+	}
+	return result, err
+}
+
+// recordValue is record for decisions made by value.
+func (k keyStats) recordValue(result Result, err error) (Result, error) {
+	if err == nil {
+		k.count(&result)
+	} else {
+		k.stats.errors.Add(1)
+	}
+	return result, err
+}
+
+// count counts a decision that was made without error.
+func (k keyStats) count(result *Result) {
+	if result.Allowed {
+		k.stats.allowed.Add(1)
+		return
+	}
+	k.stats.denied.Add(1)
+	if k.stats.deniedKeys != nil {
+		k.stats.deniedKeys.record(k.key)
+	}
+	k.stats.exhausted.denied(k.key, result)
+}
+
+// scriptSHA returns the SHA1 Redis identifies a Lua script by (as used by EVALSHA).
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// debugInfo returns the DebugInfo entries shared by every algorithm: the
+// effective configuration, the current limit and the decision counts.
+// Hooks are reported only as whether they are set, and the Redis client is
+// left out, so the result never holds credentials.
+func (c *Config) debugInfo(limit *dynamicLimit, stats *decisionStats) map[string]any {
+	info := map[string]any{
+		"algorithm":               string(c.Algorithm),
+		"limit":                   limit.load(),
+		"configured_limit":        c.Limit,
+		"window":                  c.Window.String(),
+		"prefix":                  c.Prefix,
+		"fail_open":               c.FailOpen,
+		"fail_open_estimate":      c.FailOpenEstimate,
+		"round_retry_after":       c.RoundRetryAfter,
+		"class_limits":            len(c.ClassLimits),
+		"denylist_size":           len(c.Denylist),
+		"active_schedule":         len(c.ActiveSchedule),
+		"observer":                c.Observer != nil,
+		"limit_resolver":          c.LimitResolver != nil,
+		"window_resolver":         c.WindowResolver != nil,
+		"on_exhausted":            c.OnExhausted != nil,
+		"on_panic":                c.OnPanic != nil,
+		"observe_first":           c.ObserveFirst,
+		"fingerprint":             c.Fingerprint(),
+		"decisions_allowed":       stats.allowed.Load(),
+		"decisions_denied":        stats.denied.Load(),
+		"decisions_errors":        stats.errors.Load(),
+		"limit_change_window":     c.LimitChangeWindow.String(),
+		"limit_changed_at":        "",
+		"local_cache_ttl":         c.LocalCacheTTL.String(),
+		"min_call_budget":         c.MinCallBudget.String(),
+		"min_key_ttl":             c.MinKeyTTL.String(),
+		"reserve_for_critical":    c.ReserveForCritical,
+		"track_top_denied":        c.TrackTopDenied,
+		"tag_keys_with_algorithm": c.TagKeysWithAlgorithm,
+		"ttl_refresh_fraction":    c.TTLRefreshFraction,
+	}
+	if changedAt := limit.lastChange(); !changedAt.IsZero() {
+		info["limit_changed_at"] = changedAt.Format(time.RFC3339Nano)
+	}
+	return info
+}
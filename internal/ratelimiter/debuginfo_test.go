@@ -0,0 +1,98 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugInfo_TokenBucket(t *testing.T) {
+	_, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	mr.RequireAuth("s3cret-password")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), Password: "s3cret-password"})
+
+	config := &Config{
+		Algorithm:   TokenBucket,
+		Limit:       60,
+		Window:      time.Minute,
+		Prefix:      "api",
+		MinInterval: 100 * time.Millisecond,
+	}
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:1") // within MinInterval
+	require.NoError(t, err)
+
+	info := limiter.(DebugInfoProvider).DebugInfo()
+	assert.Equal(t, "token_bucket", info["algorithm"])
+	assert.Equal(t, int64(60), info["limit"])
+	assert.Equal(t, int64(60), info["configured_limit"])
+	assert.Equal(t, "1m0s", info["window"])
+	assert.Equal(t, "api", info["prefix"])
+	assert.Equal(t, 1.0, info["refill_rate"])
+	assert.Equal(t, "2m0s", info["key_ttl"])
+	assert.Equal(t, "100ms", info["min_interval"])
+	assert.Equal(t, scriptSHA(tokenBucketScript), info["script_sha"])
+	assert.Len(t, info["script_sha"], 40)
+	assert.Equal(t, config.Fingerprint(), info["fingerprint"])
+	assert.Equal(t, int64(1), info["decisions_allowed"])
+	assert.Equal(t, int64(1), info["decisions_denied"])
+	assert.Equal(t, int64(0), info["decisions_errors"])
+
+	// SetLimit is reflected while the configured limit is kept
+	require.NoError(t, limiter.(LimitSetter).SetLimit(30))
+	info = limiter.(DebugInfoProvider).DebugInfo()
+	assert.Equal(t, int64(30), info["limit"])
+	assert.Equal(t, int64(60), info["configured_limit"])
+	assert.Equal(t, 0.5, info["refill_rate"])
+	assert.NotEmpty(t, info["limit_changed_at"])
+
+	body, err := json.Marshal(info)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "s3cret-password")
+}
+
+func TestDebugInfo_WindowAlgorithms(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	fixed, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	info := fixed.(DebugInfoProvider).DebugInfo()
+	assert.Equal(t, "fixed_window", info["algorithm"])
+	assert.Equal(t, "1m0s", info["key_ttl"])
+	assert.Equal(t, "epoch", info["alignment"])
+	assert.Equal(t, scriptSHA(fixedWindowScript), info["script_sha"])
+
+	sliding, err := NewSlidingWindow(client, &Config{
+		Algorithm:  SlidingWindow,
+		Limit:      10,
+		Window:     time.Minute,
+		SubWindows: 6,
+	})
+	require.NoError(t, err)
+
+	info = sliding.(DebugInfoProvider).DebugInfo()
+	assert.Equal(t, "sliding_window", info["algorithm"])
+	assert.Equal(t, "1m10s", info["key_ttl"])
+	assert.Equal(t, 6, info["sub_windows"])
+	assert.Equal(t, "10s", info["sub_window"])
+	assert.Equal(t, scriptSHA(slidingWindowScript), info["script_sha"])
+}
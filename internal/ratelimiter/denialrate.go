@@ -0,0 +1,119 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DenialRateLimiter is a RateLimiter that also reports the fraction of its
+// recent decisions that were denials.
+//
+// A persistently high denial rate usually means a limit is too aggressive,
+// which makes it a useful signal for alerting and autotuning.
+type DenialRateLimiter interface {
+	RateLimiter
+
+	// DenialRate returns the fraction (0.0-1.0) of decisions made within the
+	// last window that were denied. Returns 0 when no decisions were made.
+	// The window is capped at the retention given to NewDenialRateTracker.
+	DenialRate(window time.Duration) float64
+}
+
+// denialBucket holds the decision counts for one second.
+type denialBucket struct {
+	second  int64
+	allowed uint64
+	denied  uint64
+}
+
+// denialRateTracker decorates a RateLimiter with an in-process rolling count
+// of allowed and denied decisions, kept in per-second buckets.
+type denialRateTracker struct {
+	RateLimiter
+
+	mu      sync.Mutex
+	buckets []denialBucket
+	now     func() time.Time
+}
+
+// NewDenialRateTracker wraps limiter and tracks its denial rate over a rolling
+// window of up to retention (rounded up to whole seconds, minimum 1s).
+//
+// Memory use is bounded by retention, not by traffic or key cardinality.
+// Decisions that returned an error without a Result are not counted.
+func NewDenialRateTracker(limiter RateLimiter, retention time.Duration) DenialRateLimiter {
+	seconds := int64((retention + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return &denialRateTracker{
+		RateLimiter: limiter,
+		buckets:     make([]denialBucket, seconds),
+		now:         time.Now,
+	}
+}
+
+// Allow checks a single request and records the decision.
+func (d *denialRateTracker) Allow(ctx context.Context, key string) (*Result, error) {
+	result, err := d.RateLimiter.Allow(ctx, key)
+	d.record(result)
+	return result, err
+}
+
+// AllowN checks N requests and records the decision.
+func (d *denialRateTracker) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	result, err := d.RateLimiter.AllowN(ctx, key, n)
+	d.record(result)
+	return result, err
+}
+
+// DenialRate returns the fraction of decisions denied within the last window.
+func (d *denialRateTracker) DenialRate(window time.Duration) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now().Unix()
+	seconds := int64((window + time.Second - 1) / time.Second)
+	if seconds > int64(len(d.buckets)) {
+		seconds = int64(len(d.buckets))
+	}
+
+	var allowed, denied uint64
+	for _, bucket := range d.buckets {
+		if bucket.second > now-seconds && bucket.second <= now {
+			allowed += bucket.allowed
+			denied += bucket.denied
+		}
+	}
+
+	total := allowed + denied
+	if total == 0 {
+		return 0
+	}
+	return float64(denied) / float64(total)
+}
+
+// record adds a decision to the bucket for the current second.
+func (d *denialRateTracker) record(result *Result) {
+	if result == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now().Unix()
+	bucket := &d.buckets[now%int64(len(d.buckets))]
+	if bucket.second != now {
+		// Reuse a slot last written a full retention period ago
+		*bucket = denialBucket{second: now}
+	}
+
+	if result.Allowed {
+		bucket.allowed++
+	} else {
+		bucket.denied++
+	}
+}
@@ -0,0 +1,127 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedLimiter returns a fixed sequence of decisions
+type scriptedLimiter struct {
+	decisions []bool
+	err       error
+	calls     int
+}
+
+func (s *scriptedLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return s.AllowN(ctx, key, 1)
+}
+
+func (s *scriptedLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	allowed := s.decisions[s.calls%len(s.decisions)]
+	s.calls++
+	return &Result{Allowed: allowed, Limit: 10}, nil
+}
+
+func (s *scriptedLimiter) Reset(ctx context.Context, key string) error { return nil }
+
+func (s *scriptedLimiter) Close() error { return nil }
+
+// fakeClock is a manually advanced clock for tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestDenialRateTracker_KnownMix(t *testing.T) {
+	// 3 allows followed by 1 deny, repeated
+	inner := &scriptedLimiter{decisions: []bool{true, true, true, false}}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+
+	limiter := NewDenialRateTracker(inner, time.Minute)
+	limiter.(*denialRateTracker).now = clock.Now
+
+	ctx := context.Background()
+	for i := 0; i < 40; i++ {
+		_, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		if i%10 == 9 {
+			clock.Advance(time.Second)
+		}
+	}
+
+	assert.InDelta(t, 0.25, limiter.DenialRate(time.Minute), 0.001)
+}
+
+func TestDenialRateTracker_RollingWindow(t *testing.T) {
+	inner := &scriptedLimiter{decisions: []bool{false}}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+
+	limiter := NewDenialRateTracker(inner, time.Minute)
+	limiter.(*denialRateTracker).now = clock.Now
+
+	ctx := context.Background()
+
+	// 10 denials, then 30 seconds later 10 allows
+	for i := 0; i < 10; i++ {
+		_, _ = limiter.Allow(ctx, "user:1")
+	}
+	clock.Advance(30 * time.Second)
+	inner.decisions = []bool{true}
+	for i := 0; i < 10; i++ {
+		_, _ = limiter.AllowN(ctx, "user:1", 1)
+	}
+
+	assert.InDelta(t, 0.5, limiter.DenialRate(time.Minute), 0.001)
+	assert.InDelta(t, 0.0, limiter.DenialRate(10*time.Second), 0.001, "denials are older than 10s")
+
+	// Windows larger than the retention are capped
+	assert.InDelta(t, 0.5, limiter.DenialRate(time.Hour), 0.001)
+
+	// Once everything ages out there is no data
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, 0.0, limiter.DenialRate(time.Minute))
+}
+
+func TestDenialRateTracker_IgnoresErrors(t *testing.T) {
+	inner := &scriptedLimiter{err: errors.New("redis down")}
+	limiter := NewDenialRateTracker(inner, time.Minute)
+
+	result, err := limiter.Allow(context.Background(), "user:1")
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, 0.0, limiter.DenialRate(time.Minute))
+}
+
+func TestDenialRateTracker_WithRedis(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	inner, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     4,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+
+	limiter := NewDenialRateTracker(inner, time.Minute)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+	}
+
+	assert.InDelta(t, 0.2, limiter.DenialRate(time.Minute), 0.001)
+}
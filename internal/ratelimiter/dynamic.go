@@ -0,0 +1,133 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// dynamicLimit holds a limiter's limit so it can be changed with SetLimit
+// while the limiter is serving requests.
+type dynamicLimit struct {
+	limit atomic.Int64
+
+	// changedAt is the Unix time in nanoseconds of the last SetLimit, 0 if never changed
+	changedAt atomic.Int64
+}
+
+// newDynamicLimit creates a dynamicLimit starting at limit.
+func newDynamicLimit(limit int64) *dynamicLimit {
+	d := &dynamicLimit{}
+	d.limit.Store(limit)
+	return d
+}
+
+// load returns the current limit.
+func (d *dynamicLimit) load() int64 {
+	return d.limit.Load()
+}
+
+// keyLimit returns the limit that applies to key: Config.LimitResolver's
+// override when it returns one, otherwise limit.
+func (c *Config) keyLimit(key string, limit int64) int64 {
+	if c.LimitResolver == nil {
+		return limit
+	}
+	if override := c.LimitResolver(key); override > 0 {
+		return override
+	}
+	return limit
+}
+
+// keyWindow returns the window that applies to key: Config.WindowResolver's
+// override when it returns one, otherwise Window. Counters are keyed by the
+// window's start in Unix seconds, so an override that isn't a whole number
+// of seconds is ignored, as Validate rejects such a Window.
+func (c *Config) keyWindow(key string) time.Duration {
+	if c.WindowResolver == nil {
+		return c.Window
+	}
+	if override := c.WindowResolver(key); override > 0 && override%time.Second == 0 {
+		return override
+	}
+	return c.Window
+}
+
+// set changes the limit and records when it changed.
+func (d *dynamicLimit) set(limit int64) error {
+	if limit <= 0 {
+		return fmt.Errorf("%w: limit must be greater than 0, got: %d", ErrInvalidConfig, limit)
+	}
+	if d.limit.Swap(limit) != limit {
+		d.changedAt.Store(time.Now().UnixNano())
+	}
+	return nil
+}
+
+// lastChange returns when the limit last changed, or the zero time if it never has.
+func (d *dynamicLimit) lastChange() time.Time {
+	changedAt := d.changedAt.Load()
+	if changedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, changedAt)
+}
+
+// changedWithin reports whether the limit changed less than window ago.
+// A window of 0 disables the check.
+func (d *dynamicLimit) changedWithin(window time.Duration) bool {
+	if window <= 0 {
+		return false
+	}
+	changedAt := d.changedAt.Load()
+	return changedAt != 0 && time.Since(time.Unix(0, changedAt)) < window
+}
+
+// describe builds the QuotaInfo for a limiter using config and its current limit.
+func (d *dynamicLimit) describe(config *Config) QuotaInfo {
+	return QuotaInfo{
+		Algorithm:      config.Algorithm,
+		Limit:          d.load(),
+		Window:         config.Window,
+		Prefix:         config.Prefix,
+		LimitChangedAt: d.lastChange(),
+	}
+}
+
+// allowNDecision checks n requests with limiter and records the call as a
+// Decision. Like allowDescribe, the recorded Limit is the one the decision used.
+func allowNDecision(ctx context.Context, limiter interface {
+	RateLimiter
+	LimitSetter
+}, key string, n int64) (*Decision, error) {
+	quota := limiter.Describe()
+	result, err := limiter.AllowN(ctx, key, n)
+	if result != nil {
+		quota.Limit = result.Limit
+	}
+	return &Decision{
+		Key:       key,
+		N:         n,
+		Algorithm: quota.Algorithm,
+		Limit:     quota.Limit,
+		Window:    quota.Window,
+		Result:    result,
+	}, err
+}
+
+// allowDescribe checks a single request with limiter and describes the quota
+// it was checked against. The described Limit is the one the decision used,
+// so a concurrent SetLimit or a Config.LimitResolver override can't make the
+// two disagree.
+func allowDescribe(ctx context.Context, limiter interface {
+	RateLimiter
+	LimitSetter
+}, key string) (*Result, QuotaInfo, error) {
+	quota := limiter.Describe()
+	result, err := limiter.Allow(ctx, key)
+	if result != nil {
+		quota.Limit = result.Limit
+	}
+	return result, quota, err
+}
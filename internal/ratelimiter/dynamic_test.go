@@ -0,0 +1,336 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicLimit_Set(t *testing.T) {
+	d := newDynamicLimit(10)
+	assert.Equal(t, int64(10), d.load())
+	assert.True(t, d.lastChange().IsZero())
+	assert.False(t, d.changedWithin(time.Hour))
+
+	err := d.set(0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, int64(10), d.load())
+
+	// Setting the same limit is not a change
+	require.NoError(t, d.set(10))
+	assert.True(t, d.lastChange().IsZero())
+
+	require.NoError(t, d.set(20))
+	assert.Equal(t, int64(20), d.load())
+	assert.False(t, d.lastChange().IsZero())
+	assert.True(t, d.changedWithin(time.Hour))
+	assert.False(t, d.changedWithin(0), "zero window disables the check")
+}
+
+func TestSetLimit_LimitChangedRecently(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:         FixedWindow,
+		Limit:             2,
+		Window:            time.Hour,
+		LimitChangeWindow: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	setter, ok := limiter.(LimitSetter)
+	require.True(t, ok, "fixed window should implement LimitSetter")
+
+	ctx := context.Background()
+	key := "user:123"
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.False(t, result.LimitChangedRecently)
+	}
+
+	result, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Raising the limit takes effect on the existing counter and is flagged
+	require.NoError(t, setter.SetLimit(10))
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(10), result.Limit)
+	assert.True(t, result.LimitChangedRecently)
+
+	info := setter.Describe()
+	assert.Equal(t, FixedWindow, info.Algorithm)
+	assert.Equal(t, int64(10), info.Limit)
+	assert.Equal(t, time.Hour, info.Window)
+	assert.WithinDuration(t, time.Now(), info.LimitChangedAt, time.Second)
+
+	// The flag clears once LimitChangeWindow has passed
+	time.Sleep(60 * time.Millisecond)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.LimitChangedRecently)
+}
+
+func TestSetLimit_AllAlgorithms(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	constructors := map[Algorithm]func(*Config) (RateLimiter, error){
+		TokenBucket:   func(c *Config) (RateLimiter, error) { return NewTokenBucket(client, c) },
+		SlidingWindow: func(c *Config) (RateLimiter, error) { return NewSlidingWindow(client, c) },
+		FixedWindow:   func(c *Config) (RateLimiter, error) { return NewFixedWindow(client, c) },
+	}
+
+	for algorithm, newLimiter := range constructors {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, err := newLimiter(&Config{
+				Algorithm:         algorithm,
+				Limit:             5,
+				Window:            time.Hour,
+				Prefix:            string(algorithm),
+				LimitChangeWindow: time.Minute,
+			})
+			require.NoError(t, err)
+
+			setter := limiter.(LimitSetter)
+			assert.True(t, setter.Describe().LimitChangedAt.IsZero())
+			assert.Error(t, setter.SetLimit(-1))
+
+			require.NoError(t, setter.SetLimit(1))
+			assert.Equal(t, int64(1), setter.Describe().Limit)
+
+			result, err := limiter.Allow(context.Background(), "user:123")
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), result.Limit)
+			assert.True(t, result.LimitChangedRecently)
+		})
+	}
+}
+
+func TestLimitResolver_ResultReportsEffectiveLimit(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	constructors := map[Algorithm]func(*Config) (RateLimiter, error){
+		TokenBucket:   func(c *Config) (RateLimiter, error) { return NewTokenBucket(client, c) },
+		SlidingWindow: func(c *Config) (RateLimiter, error) { return NewSlidingWindow(client, c) },
+		FixedWindow:   func(c *Config) (RateLimiter, error) { return NewFixedWindow(client, c) },
+	}
+
+	for algorithm, newLimiter := range constructors {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, err := newLimiter(&Config{
+				Algorithm: algorithm,
+				Limit:     2,
+				Window:    time.Hour,
+				Prefix:    string(algorithm),
+				LimitResolver: func(key string) int64 {
+					if key == "user:premium" {
+						return 5
+					}
+					return 0
+				},
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			for i := int64(1); i <= 5; i++ {
+				result, err := limiter.Allow(ctx, "user:premium")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, int64(5), result.Limit)
+				assert.Equal(t, 5-i, result.Remaining)
+			}
+			result, err := limiter.Allow(ctx, "user:premium")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, int64(5), result.Limit)
+
+			result, err = limiter.AllowN(ctx, "user:basic", 2)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(2), result.Limit)
+			result, err = limiter.Allow(ctx, "user:basic")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, int64(2), result.Limit)
+
+			// Peek and SetLimit agree with decisions
+			peeked, err := limiter.(Peeker).Peek(ctx, "user:premium")
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), peeked.Limit)
+
+			require.NoError(t, limiter.(LimitSetter).SetLimit(3))
+			result, err = limiter.Allow(ctx, "user:other")
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), result.Limit)
+			result, err = limiter.Allow(ctx, "user:premium")
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), result.Limit, "the override wins over SetLimit")
+
+			// A permanent denial reports the key's limit too
+			result, err = limiter.AllowN(ctx, "user:basic2", 4)
+			require.NoError(t, err)
+			assert.True(t, result.Permanent)
+			assert.Equal(t, int64(3), result.Limit)
+		})
+	}
+}
+
+func TestAllowDescribe_MatchesConfigAndState(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	constructors := map[Algorithm]func(*Config) (RateLimiter, error){
+		TokenBucket:   func(c *Config) (RateLimiter, error) { return NewTokenBucket(client, c) },
+		SlidingWindow: func(c *Config) (RateLimiter, error) { return NewSlidingWindow(client, c) },
+		FixedWindow:   func(c *Config) (RateLimiter, error) { return NewFixedWindow(client, c) },
+	}
+
+	for algorithm, newLimiter := range constructors {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, err := newLimiter(&Config{
+				Algorithm: algorithm,
+				Limit:     2,
+				Window:    time.Hour,
+				Prefix:    "describe:" + string(algorithm),
+				LimitResolver: func(key string) int64 {
+					if key == "user:premium" {
+						return 4
+					}
+					return 0
+				},
+			})
+			require.NoError(t, err)
+
+			describing, ok := limiter.(DescribingLimiter)
+			require.True(t, ok, "%s should implement DescribingLimiter", algorithm)
+
+			ctx := context.Background()
+			for _, wantRemaining := range []int64{1, 0} {
+				result, quota, err := describing.AllowDescribe(ctx, "user:1")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, wantRemaining, result.Remaining)
+				assert.Equal(t, QuotaInfo{
+					Algorithm: algorithm,
+					Limit:     2,
+					Window:    time.Hour,
+					Prefix:    "describe:" + string(algorithm),
+				}, quota)
+			}
+
+			result, quota, err := describing.AllowDescribe(ctx, "user:1")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, int64(2), quota.Limit)
+
+			// The described limit is the one applied to the key
+			result, quota, err = describing.AllowDescribe(ctx, "user:premium")
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), result.Remaining)
+			assert.Equal(t, int64(4), quota.Limit)
+			assert.Equal(t, result.Limit, quota.Limit)
+
+			require.NoError(t, limiter.(LimitSetter).SetLimit(5))
+			result, quota, err = describing.AllowDescribe(ctx, "user:2")
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), result.Remaining)
+			assert.Equal(t, int64(5), quota.Limit)
+			assert.False(t, quota.LimitChangedAt.IsZero())
+		})
+	}
+}
+
+func TestAllowNDecision_RecordsInputsAndResult(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	constructors := map[Algorithm]func(*Config) (RateLimiter, error){
+		TokenBucket:   func(c *Config) (RateLimiter, error) { return NewTokenBucket(client, c) },
+		SlidingWindow: func(c *Config) (RateLimiter, error) { return NewSlidingWindow(client, c) },
+		FixedWindow:   func(c *Config) (RateLimiter, error) { return NewFixedWindow(client, c) },
+	}
+
+	for algorithm, newLimiter := range constructors {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, err := newLimiter(&Config{
+				Algorithm: algorithm,
+				Limit:     5,
+				Window:    time.Hour,
+				Prefix:    "decision:" + string(algorithm),
+				LimitResolver: func(key string) int64 {
+					if key == "user:premium" {
+						return 10
+					}
+					return 0
+				},
+			})
+			require.NoError(t, err)
+
+			deciding, ok := limiter.(DecisionLimiter)
+			require.True(t, ok, "%s should implement DecisionLimiter", algorithm)
+
+			ctx := context.Background()
+			decision, err := deciding.AllowNDecision(ctx, "user:1", 3)
+			require.NoError(t, err)
+			require.NotNil(t, decision.Result)
+			assert.Equal(t, "user:1", decision.Key)
+			assert.Equal(t, int64(3), decision.N)
+			assert.Equal(t, algorithm, decision.Algorithm)
+			assert.Equal(t, int64(5), decision.Limit)
+			assert.Equal(t, time.Hour, decision.Window)
+			assert.True(t, decision.Result.Allowed)
+			assert.Equal(t, int64(2), decision.Result.Remaining)
+
+			decision, err = deciding.AllowNDecision(ctx, "user:1", 3)
+			require.NoError(t, err)
+			assert.False(t, decision.Result.Allowed)
+
+			// The recorded limit is the one applied to the key
+			decision, err = deciding.AllowNDecision(ctx, "user:premium", 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), decision.Limit)
+			assert.Equal(t, decision.Result.Limit, decision.Limit)
+
+			decision, err = deciding.AllowNDecision(ctx, "user:1", 0)
+			assert.ErrorIs(t, err, ErrInvalidN)
+			assert.Nil(t, decision.Result)
+			assert.Equal(t, int64(0), decision.N)
+		})
+	}
+}
+
+func TestKeyWindow_IgnoresFractionalSeconds(t *testing.T) {
+	windows := map[string]time.Duration{
+		"user:half":      500 * time.Millisecond,
+		"user:fraction":  1500 * time.Millisecond,
+		"user:negative":  -time.Hour,
+		"user:two":       2 * time.Second,
+		"user:unchanged": 0,
+	}
+	config := &Config{
+		Window:         time.Minute,
+		WindowResolver: func(key string) time.Duration { return windows[key] },
+	}
+
+	// Windows are keyed by their start in Unix seconds, so only whole
+	// seconds override Window
+	assert.Equal(t, time.Minute, config.keyWindow("user:half"))
+	assert.Equal(t, time.Minute, config.keyWindow("user:fraction"))
+	assert.Equal(t, time.Minute, config.keyWindow("user:negative"))
+	assert.Equal(t, time.Minute, config.keyWindow("user:unchanged"))
+	assert.Equal(t, 2*time.Second, config.keyWindow("user:two"))
+}
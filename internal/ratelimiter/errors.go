@@ -15,6 +15,85 @@ var (
 	// ErrInvalidN indicates the N parameter for AllowN is invalid
 	ErrInvalidN = errors.New("invalid n: must be greater than 0")
 
+	// ErrInvalidCost indicates a fractional cost is not a finite number greater than 0
+	ErrInvalidCost = errors.New("invalid cost: must be a finite number greater than 0")
+
+	// ErrInvalidGranularity indicates the sub-window granularity is invalid for the window
+	ErrInvalidGranularity = errors.New("invalid granularity")
+
+	// ErrTooManyKeys indicates a single check would touch more Redis keys than MaxScriptKeys
+	ErrTooManyKeys = errors.New("too many keys for one script")
+
+	// ErrUnknownClass indicates AllowClass was called with a class missing from Config.ClassLimits
+	ErrUnknownClass = errors.New("unknown operation class")
+
+	// ErrCrossSlot indicates the keys of one check hash to different Redis Cluster
+	// slots, so the check's script cannot run (Redis CROSSSLOT error)
+	ErrCrossSlot = errors.New("keys hash to different cluster slots")
+
+	// ErrPermanentDenial indicates a request was denied in a way retrying can
+	// never fix (see Result.Permanent)
+	ErrPermanentDenial = errors.New("request permanently denied")
+
+	// ErrInsufficientBudget indicates the context deadline was too close
+	// (see Config.MinCallBudget) to attempt a call to Redis
+	ErrInsufficientBudget = errors.New("context deadline too close for a storage call")
+
 	// ErrClosed indicates the rate limiter has been closed
 	ErrClosed = errors.New("rate limiter is closed")
+
+	// ErrKeyTypeMismatch indicates a Redis key holds state of a type the
+	// limiter's algorithm doesn't use, typically written by a limiter with
+	// another algorithm sharing the same prefix (Redis WRONGTYPE error)
+	ErrKeyTypeMismatch = errors.New("redis key holds another algorithm's state")
+
+	// ErrPanic indicates a decision was abandoned because a callback such as
+	// Config.LimitResolver panicked (see Config.OnPanic)
+	ErrPanic = errors.New("rate limiter recovered from a panic")
+
+	// ErrKeyCollision indicates different parts hashed to the same composite
+	// key (see CompositeKeys)
+	ErrKeyCollision = errors.New("composite key collision")
+
+	// ErrMemoryUsageUnavailable indicates Redis doesn't support MEMORY USAGE
+	// (added in Redis 4.0), so MemoryReporter can't measure a key
+	ErrMemoryUsageUnavailable = errors.New("redis MEMORY USAGE unavailable")
 )
+
+// ValidationError describes one invalid Config field, so that callers such as
+// an admin API can point at the offending input
+type ValidationError struct {
+	// Field is the name of the invalid Config field, e.g. "Limit" or "Window"
+	Field string
+
+	// Message is the human-readable description of the problem
+	Message string
+}
+
+// Error returns the human-readable message.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors returns every ValidationError in err's tree, in order.
+// It accepts the error from Config.Validate as well as constructor errors
+// wrapping it. Returns nil if err contains none.
+func ValidationErrors(err error) []*ValidationError {
+	var found []*ValidationError
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case *ValidationError:
+			found = append(found, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+	return found
+}
@@ -0,0 +1,59 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// exhaustionNotifier calls Config.OnExhausted for over-limit denials, at most
+// once per key per Window-long period. Only the keys notified in the current
+// period are remembered, so memory is bounded by the keys exhausted within
+// one window.
+type exhaustionNotifier struct {
+	notify func(key string, resetAt time.Time)
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	notified map[string]struct{}
+}
+
+// newExhaustionNotifier returns the notifier for config, or nil unless
+// OnExhausted is set.
+func newExhaustionNotifier(config *Config) *exhaustionNotifier {
+	if config.OnExhausted == nil {
+		return nil
+	}
+	return &exhaustionNotifier{
+		notify: func(key string, resetAt time.Time) {
+			defer config.recoverCallback()
+			config.OnExhausted(key, resetAt)
+		},
+		window: config.Window,
+		now:    time.Now,
+	}
+}
+
+// denied notifies the key's exhaustion if result was denied for being over
+// the limit and the key hasn't been notified in the current period. A nil
+// notifier does nothing.
+func (e *exhaustionNotifier) denied(key string, result *Result) {
+	if e == nil || result.Reason != ReasonLimitExceeded {
+		return
+	}
+
+	e.mu.Lock()
+	if start := e.now().Truncate(e.window); e.notified == nil || !start.Equal(e.start) {
+		e.start = start
+		e.notified = make(map[string]struct{})
+	}
+	_, notified := e.notified[key]
+	e.notified[key] = struct{}{}
+	e.mu.Unlock()
+
+	// Called outside the lock, so a slow callback doesn't serialize other keys
+	if !notified {
+		e.notify(key, result.ResetAt)
+	}
+}
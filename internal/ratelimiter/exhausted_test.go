@@ -0,0 +1,117 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exhaustionRecorder records OnExhausted calls
+type exhaustionRecorder struct {
+	mu      sync.Mutex
+	keys    []string
+	resetAt []time.Time
+}
+
+func (r *exhaustionRecorder) record(key string, resetAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, key)
+	r.resetAt = append(r.resetAt, resetAt)
+}
+
+func TestOnExhausted_OncePerWindow(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	recorder := &exhaustionRecorder{}
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:   FixedWindow,
+		Limit:       2,
+		Window:      time.Minute,
+		OnExhausted: recorder.record,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	limiter.(*fixedWindowLimiter).stats.exhausted.now = clock.Now
+
+	ctx := context.Background()
+	var denied *Result
+	for range 10 {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		if !result.Allowed {
+			denied = result
+		}
+	}
+	require.NotNil(t, denied)
+
+	// Sustained over-limit traffic fires once for the window
+	assert.Equal(t, []string{"user:1"}, recorder.keys)
+	assert.Equal(t, denied.ResetAt, recorder.resetAt[0])
+
+	// Another key is notified on its own
+	for range 3 {
+		_, err := limiter.Allow(ctx, "user:2")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"user:1", "user:2"}, recorder.keys)
+
+	// The next window fires again
+	clock.Advance(time.Minute)
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2", "user:1"}, recorder.keys)
+}
+
+func TestOnExhausted_NotForOtherDenials(t *testing.T) {
+	recorder := &exhaustionRecorder{}
+	config := &Config{
+		Algorithm:   TokenBucket,
+		Limit:       2,
+		Window:      time.Minute,
+		OnExhausted: recorder.record,
+	}
+
+	t.Run("backend down", func(t *testing.T) {
+		client, _ := unreachableClient(t)
+		limiter, err := NewTokenBucket(client, config)
+		require.NoError(t, err)
+
+		_, err = limiter.Allow(context.Background(), "user:1")
+		assert.Error(t, err)
+
+		failOpen := *config
+		failOpen.FailOpen = true
+		limiter, err = NewTokenBucket(client, &failOpen)
+		require.NoError(t, err)
+
+		result, err := limiter.Allow(context.Background(), "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("permanent denial", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewTokenBucket(client, config)
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, err := limiter.AllowN(context.Background(), "user:1", 3)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, ReasonExceedsLimit, result.Reason)
+	})
+
+	assert.Empty(t, recorder.keys)
+}
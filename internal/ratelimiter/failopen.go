@@ -0,0 +1,70 @@
+package ratelimiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// failOpenEstimate approximates each key's usage in process while Redis is
+// unavailable, for Config.FailOpenEstimate, so fail-open results can report
+// a decreasing Remaining instead of 0.
+//
+// Counts start from zero when the outage starts, as the usage stored in
+// Redis can't be read, and are kept per window: at each window boundary, and
+// when Redis answers again, they are dropped. Memory is bounded by the keys
+// seen within one window of an outage.
+type failOpenEstimate struct {
+	window time.Duration
+
+	// active is true while counts are held, so the success path can skip
+	// the mutex
+	active atomic.Bool
+
+	mu    sync.Mutex
+	start time.Time
+	used  map[string]int64
+}
+
+// newFailOpenEstimate returns the estimate for config, or nil unless both
+// FailOpen and FailOpenEstimate are set.
+func newFailOpenEstimate(config *Config) *failOpenEstimate {
+	if !config.FailOpen || !config.FailOpenEstimate {
+		return nil
+	}
+	return &failOpenEstimate{window: config.Window}
+}
+
+// remaining counts n against the key for a fail-open allow at now and
+// returns the quota the key has left by the estimate. A nil estimate always
+// reports 0.
+func (e *failOpenEstimate) remaining(key string, n, limit int64, now time.Time) int64 {
+	if e == nil {
+		return 0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if start := now.Truncate(e.window); e.used == nil || !start.Equal(e.start) {
+		e.start = start
+		e.used = make(map[string]int64)
+	}
+	e.used[key] += n
+	e.active.Store(true)
+	return max(limit-e.used[key], 0)
+}
+
+// recovered drops the estimate once Redis answers again, since its counts
+// are authoritative from then on.
+func (e *failOpenEstimate) recovered() {
+	if e == nil || !e.active.Load() {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.used = nil
+	e.active.Store(false)
+}
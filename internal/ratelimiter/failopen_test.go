@@ -0,0 +1,124 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailOpenEstimate_RemainingDecreasesDuringOutage(t *testing.T) {
+	ctx := context.Background()
+	for algorithm, newLimiter := range map[Algorithm]func(*Config) (RateLimiter, error){
+		TokenBucket: func(c *Config) (RateLimiter, error) {
+			client, _ := unreachableClient(t)
+			return NewTokenBucket(client, c)
+		},
+		SlidingWindow: func(c *Config) (RateLimiter, error) {
+			client, _ := unreachableClient(t)
+			return NewSlidingWindow(client, c)
+		},
+		FixedWindow: func(c *Config) (RateLimiter, error) {
+			client, _ := unreachableClient(t)
+			return NewFixedWindow(client, c)
+		},
+	} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, err := newLimiter(&Config{
+				Algorithm:        algorithm,
+				Limit:            5,
+				Window:           time.Hour,
+				FailOpen:         true,
+				FailOpenEstimate: true,
+			})
+			require.NoError(t, err)
+
+			for _, want := range []int64{4, 3, 2} {
+				result, err := limiter.Allow(ctx, "user:1")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, want, result.Remaining)
+			}
+
+			// Keys are estimated separately, and the estimate stops at 0
+			for range 2 {
+				result, err := limiter.AllowN(ctx, "user:2", 5)
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, int64(0), result.Remaining)
+			}
+		})
+	}
+}
+
+func TestFailOpenEstimate_ResetsWhenRedisRecovers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	// Fail fast while Redis is down
+	client := redis.NewClient(&redis.Options{
+		Addr:               mr.Addr(),
+		MaxRetries:         -1,
+		DialerRetries:      1,
+		DialerRetryTimeout: time.Millisecond,
+	})
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:        FixedWindow,
+		Limit:            5,
+		Window:           time.Hour,
+		FailOpen:         true,
+		FailOpenEstimate: true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	mr.Close()
+	for _, want := range []int64{4, 3} {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, want, result.Remaining)
+	}
+
+	// Redis answers again, and its count is authoritative
+	require.NoError(t, mr.Restart())
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Remaining)
+
+	// A new outage starts a new estimate
+	mr.Close()
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Remaining)
+}
+
+func TestFailOpenEstimate_DisabledReportsZero(t *testing.T) {
+	client, _ := unreachableClient(t)
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Hour,
+		FailOpen:  true,
+	})
+	require.NoError(t, err)
+
+	result, err := limiter.Allow(context.Background(), "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+}
+
+func TestFailOpenEstimate_RequiresFailOpen(t *testing.T) {
+	err := (&Config{
+		Algorithm:        FixedWindow,
+		Limit:            5,
+		Window:           time.Hour,
+		FailOpenEstimate: true,
+	}).Validate()
+	require.Error(t, err)
+	assert.Equal(t, "FailOpenEstimate", ValidationErrors(err)[0].Field)
+}
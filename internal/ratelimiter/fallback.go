@@ -0,0 +1,91 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// fallbackLimiter checks its limiters in order, moving on to the next one
+// only when a limiter's storage fails.
+type fallbackLimiter struct {
+	limiters []RateLimiter
+}
+
+// NewFallbackLimiter creates a RateLimiter that checks limiters in order,
+// e.g. a limiter on the primary Redis, then one on a secondary Redis.
+//
+// A limiter is only skipped when its storage fails: an error wrapping
+// ErrStorageUnavailable or a *BackendError. Denials, invalid arguments and
+// misconfigurations such as ErrKeyTypeMismatch are returned as they are. If
+// every limiter's storage fails, the last one's error is returned.
+//
+// Each limiter applies its own fail policy, so all but the last should be
+// built without Config.FailOpen: a limiter that fails open never reports a
+// storage failure, and the chain would stop at it. The last limiter's
+// FailOpen then decides what happens when every backend is down.
+//
+// The limiters keep separate state, so the chain is only as consistent as
+// the backend serving each request: quota used on the primary is invisible
+// to the secondary, and a key can be admitted up to its limit on each
+// backend while traffic moves between them. Keys are not copied back when
+// the primary recovers; its state resumes where it was left.
+func NewFallbackLimiter(limiters ...RateLimiter) (RateLimiter, error) {
+	if len(limiters) == 0 {
+		return nil, fmt.Errorf("at least one limiter is required")
+	}
+	for i, limiter := range limiters {
+		if limiter == nil {
+			return nil, fmt.Errorf("limiter %d cannot be nil", i)
+		}
+	}
+
+	return &fallbackLimiter{limiters: append([]RateLimiter(nil), limiters...)}, nil
+}
+
+// Allow checks a single request against the first limiter whose storage is available.
+func (f *fallbackLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+// AllowN checks N requests against the first limiter whose storage is available.
+func (f *fallbackLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	var result *Result
+	var err error
+	for _, limiter := range f.limiters {
+		result, err = limiter.AllowN(ctx, key, n)
+		if !storageFailed(err) {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// Reset resets the key in every limiter, since any of them may hold its state.
+func (f *fallbackLimiter) Reset(ctx context.Context, key string) error {
+	var errs []error
+	for _, limiter := range f.limiters {
+		if err := limiter.Reset(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every limiter.
+func (f *fallbackLimiter) Close() error {
+	var errs []error
+	for _, limiter := range f.limiters {
+		if err := limiter.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// storageFailed reports whether err means the limiter's storage failed, as
+// opposed to the request or the configuration being at fault.
+func storageFailed(err error) bool {
+	var backendErr *BackendError
+	return errors.Is(err, ErrStorageUnavailable) || errors.As(err, &backendErr)
+}
@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackLimiter_SecondaryServesWhenPrimaryIsDown(t *testing.T) {
+	primaryRedis := miniredis.RunT(t)
+	// Fail fast once the primary is down
+	primaryClient := redis.NewClient(&redis.Options{
+		Addr:               primaryRedis.Addr(),
+		MaxRetries:         -1,
+		DialerRetries:      1,
+		DialerRetryTimeout: time.Millisecond,
+	})
+	secondaryClient, secondaryRedis := setupMiniredis(t)
+	defer secondaryRedis.Close()
+
+	config := &Config{Algorithm: FixedWindow, Limit: 2, Window: time.Hour}
+	primary, err := NewFixedWindow(primaryClient, config)
+	require.NoError(t, err)
+	secondary, err := NewFixedWindow(secondaryClient, config)
+	require.NoError(t, err)
+
+	limiter, err := NewFallbackLimiter(primary, secondary)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	// While the primary is up it serves every request
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Remaining)
+	assert.Empty(t, secondaryRedis.Keys())
+
+	// Once it is down the secondary takes over, with its own state
+	primaryRedis.Close()
+	for _, allowed := range []bool{true, true, false} {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed)
+	}
+	assert.NotEmpty(t, secondaryRedis.Keys())
+}
+
+func TestFallbackLimiter_AllBackendsDown(t *testing.T) {
+	down := func(failOpen bool) RateLimiter {
+		client, _ := unreachableClient(t)
+		limiter, err := NewFixedWindow(client, &Config{Algorithm: FixedWindow, Limit: 2, Window: time.Hour, FailOpen: failOpen})
+		require.NoError(t, err)
+		return limiter
+	}
+	ctx := context.Background()
+
+	// The last limiter's fail policy decides
+	limiter, err := NewFallbackLimiter(down(false), down(false))
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:1")
+	var backendErr *BackendError
+	assert.ErrorAs(t, err, &backendErr)
+
+	limiter, err = NewFallbackLimiter(down(false), down(true))
+	require.NoError(t, err)
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestFallbackLimiter_DoesNotSkipOnOtherErrors(t *testing.T) {
+	primary := &scriptedLimiter{err: ErrInvalidKey}
+	secondary := &scriptedLimiter{decisions: []bool{true}}
+
+	limiter, err := NewFallbackLimiter(primary, secondary)
+	require.NoError(t, err)
+
+	_, err = limiter.Allow(context.Background(), "user:1")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.Zero(t, secondary.calls)
+}
+
+func TestNewFallbackLimiter_Validation(t *testing.T) {
+	_, err := NewFallbackLimiter()
+	assert.Error(t, err)
+
+	_, err = NewFallbackLimiter(NewNoop(), nil)
+	assert.Error(t, err)
+}
@@ -1,8 +1,11 @@
 package ratelimiter
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,16 +17,277 @@ const (
 	// This ensures the counter automatically expires at the end of the window.
 	//
 	// KEYS[1]: The Redis key for the counter
+	// KEYS[2]: The key holding the last allowed request's timestamp (only used when ARGV[4] > 0)
 	// ARGV[1]: The increment amount (n)
-	// ARGV[2]: The TTL in seconds (window duration)
+	// ARGV[2]: The TTL in milliseconds (window duration, or the rest of the key's time bucket)
+	// ARGV[3]: 1 caps the counter at the limit, 0 disables capping
+	// ARGV[4]: Minimum interval between allowed requests in milliseconds (0 disables spacing)
+	// ARGV[5]: Current timestamp in milliseconds
+	// ARGV[6]: The limit
+	// ARGV[7]: Value the counter saturates at if the increment would overflow int64
+	// ARGV[8]: Hash field holding the counter ("" when KEYS[1] is the counter itself)
+	// ARGV[9]: Quota the request must leave for critical requests (0 = none)
+	// ARGV[10]: Config.PostResetGrace (0 = none)
+	// ARGV[11]: The period the grace decays over, in milliseconds
+	// ARGV[12]: The window's start timestamp in milliseconds
 	//
-	// Returns: The new counter value after incrementing
+	// The grace is added to the limit: all of it at the start of the window,
+	// decaying linearly to none by the end of the period, from the time
+	// elapsed since the window started.
+	//
+	// Returns: {count, created (0/1), wait_ms, crossed (0/1), grace, held (0/1)}
+	// count is the new counter value after incrementing, or the stored value
+	// unchanged when it is already above the cap. created is 1 when this call
+	// created the counter. wait_ms > 0 means the request arrived less than the
+	// minimum interval after the last allowed one; the counter is then left
+	// unchanged and wait_ms is the rest of the interval. A request that would
+	// dip into the reserve is denied without counting, so such denials can't
+	// use up the reserve; held is then 1 and count the stored value.
+	// crossed is 1 when this call took the counter from within the limit to
+	// over it, i.e. it is the window's first over-limit denial. grace is the
+	// grace the limit included.
 	fixedWindowScript = `
-local current = redis.call('INCRBY', KEYS[1], ARGV[1])
+local field = ARGV[8]
+local function read()
+    if field == '' then
+        return tonumber(redis.call('GET', KEYS[1]) or 0)
+    end
+    return tonumber(redis.call('HGET', KEYS[1], field) or 0)
+end
+local function incr(n)
+    if field == '' then
+        return redis.pcall('INCRBY', KEYS[1], n)
+    end
+    return redis.pcall('HINCRBY', KEYS[1], field, n)
+end
+
+local grace = 0
+local grace_max = tonumber(ARGV[10])
+if grace_max > 0 then
+    local period = tonumber(ARGV[11])
+    local left = period - (tonumber(ARGV[5]) - tonumber(ARGV[12]))
+    if left > 0 then
+        grace = math.ceil(grace_max * left / period)
+    end
+end
+local limit = tonumber(ARGV[6]) + grace
+
+local interval = tonumber(ARGV[4])
+if interval > 0 then
+    local last = redis.call('GET', KEYS[2])
+    if last then
+        local wait = interval - (tonumber(ARGV[5]) - tonumber(last))
+        if wait > 0 then
+            return {read(), 0, wait, 0, grace, 0}
+        end
+    end
+end
+
+if ARGV[3] == '1' then
+    local existing = read()
+    if existing > limit then
+        return {existing, 0, 0, 0, grace, 0}
+    end
+end
+
+local reserve = tonumber(ARGV[9])
+local admit = limit - reserve
+if reserve > 0 then
+    local existing = read()
+    if existing + tonumber(ARGV[1]) > admit then
+        return {existing, 0, 0, 0, grace, 1}
+    end
+end
+
+local created = 0
+local current = incr(ARGV[1])
+if type(current) == 'table' and current.err then
+    if not string.find(current.err, 'overflow') then
+        return redis.error_reply(current.err)
+    end
+    -- Saturate just above the limit instead of failing: still a denial, and
+    -- a failure could be turned into an allow by FailOpen
+    if field == '' then
+        local pttl = redis.call('PTTL', KEYS[1])
+        redis.call('SET', KEYS[1], ARGV[7])
+        if pttl > 0 then
+            redis.call('PEXPIRE', KEYS[1], pttl)
+        else
+            redis.call('PEXPIRE', KEYS[1], ARGV[2])
+        end
+    else
+        redis.call('HSET', KEYS[1], field, ARGV[7])
+    end
+    current = incr(0)
+end
 if current == tonumber(ARGV[1]) then
-    redis.call('EXPIRE', KEYS[1], ARGV[2])
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    created = 1
+end
+if interval > 0 and current <= admit then
+    redis.call('SET', KEYS[2], ARGV[5], 'PX', interval)
+end
+local crossed = 0
+if current > limit and current - tonumber(ARGV[1]) <= limit then
+    crossed = 1
 end
-return current
+return {current, created, 0, crossed, grace, 0}
+`
+
+	// compareAndIncrementScript increments the counter only if it currently
+	// holds the expected value and the increment stays within the limit.
+	//
+	// KEYS[1]: The Redis key for the counter
+	// ARGV[1]: The increment amount (n)
+	// ARGV[2]: The TTL in milliseconds (window duration)
+	// ARGV[3]: The expected current counter value
+	// ARGV[4]: The limit (less any reserve)
+	// ARGV[5]: Config.PostResetGrace (0 = none)
+	// ARGV[6]: The period the grace decays over, in milliseconds
+	// ARGV[7]: The window's start timestamp in milliseconds
+	// ARGV[8]: Current timestamp in milliseconds
+	//
+	// The grace is added to the limit as in fixedWindowScript.
+	//
+	// Returns: {consumed (0/1), count, created (0/1), grace}
+	// count is the counter after the call: incremented when consumed,
+	// unchanged otherwise. grace is the grace the limit included.
+	compareAndIncrementScript = `
+local grace = 0
+local grace_max = tonumber(ARGV[5])
+if grace_max > 0 then
+    local period = tonumber(ARGV[6])
+    local left = period - (tonumber(ARGV[8]) - tonumber(ARGV[7]))
+    if left > 0 then
+        grace = math.ceil(grace_max * left / period)
+    end
+end
+
+local current = tonumber(redis.call('GET', KEYS[1]) or 0)
+local n = tonumber(ARGV[1])
+if current ~= tonumber(ARGV[3]) or current + n > tonumber(ARGV[4]) + grace then
+    return {0, current, 0, grace}
+end
+
+current = redis.call('INCRBY', KEYS[1], n)
+local created = 0
+if current == n then
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    created = 1
+end
+return {1, current, created, grace}
+`
+
+	// allowThenResetScript allows a single request if the counter has room for
+	// it and, when allowed, deletes the counter so the next request starts
+	// from a fresh window.
+	//
+	// KEYS[1]: The Redis key for the counter
+	// ARGV[1]: The limit (less any reserve)
+	// ARGV[2]: Hash field holding the counter ("" when KEYS[1] is the counter itself)
+	// ARGV[3]: Config.PostResetGrace (0 = none)
+	// ARGV[4]: The period the grace decays over, in milliseconds
+	// ARGV[5]: The window's start timestamp in milliseconds
+	// ARGV[6]: Current timestamp in milliseconds
+	//
+	// The grace is added to the limit as in fixedWindowScript.
+	//
+	// Returns: {allowed (0/1), count, missing (0/1), grace}
+	// count is the counter before the call; it is left unchanged when denied.
+	// missing is 1 when the key had no counter. grace is the grace the limit
+	// included.
+	allowThenResetScript = `
+local grace = 0
+local grace_max = tonumber(ARGV[3])
+if grace_max > 0 then
+    local period = tonumber(ARGV[4])
+    local left = period - (tonumber(ARGV[6]) - tonumber(ARGV[5]))
+    if left > 0 then
+        grace = math.ceil(grace_max * left / period)
+    end
+end
+
+local stored
+if ARGV[2] == '' then
+    stored = redis.call('GET', KEYS[1])
+else
+    stored = redis.call('HGET', KEYS[1], ARGV[2])
+end
+local missing = 0
+if not stored then
+    missing = 1
+end
+local count = tonumber(stored or 0)
+if count + 1 > tonumber(ARGV[1]) + grace then
+    return {0, count, missing, grace}
+end
+
+if ARGV[2] == '' then
+    redis.call('DEL', KEYS[1])
+else
+    redis.call('HDEL', KEYS[1], ARGV[2])
+end
+return {1, count, missing, grace}
+`
+
+	// alignedWindowScript is the fixedWindowScript counterpart for windows
+	// aligned to the first request. The counter lives in a hash alongside the
+	// window's start time, and the key expires exactly one window after it.
+	//
+	// KEYS[1]: The Redis hash holding the counter and window start
+	// ARGV[1]: The increment amount (n)
+	// ARGV[2]: The window duration in milliseconds
+	// ARGV[3]: Current timestamp in milliseconds
+	// ARGV[4]: Counter cap (0 disables capping)
+	// ARGV[5]: Value the counter saturates at if the increment would overflow int64
+	// ARGV[6]: The rate limit
+	// ARGV[7]: Requests held back for critical requests (Config.ReserveForCritical, or 0)
+	//
+	// Returns: {count, created (0/1), start, crossed (0/1), held (0/1)}
+	// start is the stored first-request timestamp in milliseconds. count,
+	// crossed and held are as in fixedWindowScript.
+	alignedWindowScript = `
+local start = redis.call('HGET', KEYS[1], 'start')
+local created = 0
+if not start then
+    start = ARGV[3]
+    redis.call('HSET', KEYS[1], 'start', start)
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    created = 1
+end
+start = tonumber(start)
+
+local cap = tonumber(ARGV[4])
+if cap > 0 then
+    local existing = tonumber(redis.call('HGET', KEYS[1], 'count') or 0)
+    if existing > cap then
+        return {existing, 0, start, 0, 0}
+    end
+end
+
+local reserve = tonumber(ARGV[7])
+if reserve > 0 then
+    local admit = tonumber(ARGV[6]) - reserve
+    local existing = tonumber(redis.call('HGET', KEYS[1], 'count') or 0)
+    if existing + tonumber(ARGV[1]) > admit then
+        return {existing, created, start, 0, 1}
+    end
+end
+
+local current = redis.pcall('HINCRBY', KEYS[1], 'count', ARGV[1])
+if type(current) == 'table' and current.err then
+    if not string.find(current.err, 'overflow') then
+        return redis.error_reply(current.err)
+    end
+    -- Saturate just above the limit, as in fixedWindowScript
+    redis.call('HSET', KEYS[1], 'count', ARGV[5])
+    current = redis.call('HINCRBY', KEYS[1], 'count', 0)
+end
+local crossed = 0
+if current > tonumber(ARGV[6]) and current - tonumber(ARGV[1]) <= tonumber(ARGV[6]) then
+    crossed = 1
+end
+return {current, created, start, crossed, 0}
 `
 )
 
@@ -32,6 +296,14 @@ return current
 type fixedWindowLimiter struct {
 	client *redis.Client
 	config *Config
+	limit  *dynamicLimit
+	stats  decisionStats
+
+	// estimate is nil unless Config.FailOpenEstimate is set
+	estimate *failOpenEstimate
+
+	// warmup is nil unless Config.ObserveFirst is set
+	warmup *warmupTracker
 }
 
 // NewFixedWindow creates a new Fixed Window rate limiter.
@@ -52,6 +324,14 @@ func NewFixedWindow(client *redis.Client, config *Config) (RateLimiter, error) {
 	return &fixedWindowLimiter{
 		client: client,
 		config: cfg,
+		limit:  newDynamicLimit(cfg.Limit),
+		stats: decisionStats{
+			deniedKeys: newDeniedTracker(cfg.TrackTopDenied),
+			exhausted:  newExhaustionNotifier(cfg),
+		},
+
+		estimate: newFailOpenEstimate(cfg),
+		warmup:   newWarmupTracker(cfg),
 	}, nil
 }
 
@@ -61,72 +341,665 @@ func (f *fixedWindowLimiter) Allow(ctx context.Context, key string) (*Result, er
 }
 
 // AllowN checks if N requests are allowed for the given key.
+// Reports the decision to Config.Observer when one is configured.
+func (f *fixedWindowLimiter) AllowN(ctx context.Context, key string, n int64) (result *Result, err error) {
+	defer f.config.recoverDecision(&result, &err)
+	return f.observeAllowN(ctx, key, n, f.config.keyLimit(key, f.limit.load()))
+}
+
+// AllowValue checks if a single request is allowed for the given key and
+// returns the Result by value, avoiding a heap allocation per call.
+func (f *fixedWindowLimiter) AllowValue(ctx context.Context, key string) (Result, error) {
+	return f.AllowValueN(ctx, key, 1)
+}
+
+// AllowValueN checks if N requests are allowed for the given key and returns
+// the Result by value, avoiding a heap allocation per call.
+func (f *fixedWindowLimiter) AllowValueN(ctx context.Context, key string, n int64) (result Result, err error) {
+	defer f.config.recoverDecisionValue(&result, &err)
+	if f.config.Observer != nil {
+		// Observers receive a *Result, so observed calls go through AllowN
+		return resultValue(f.AllowN(ctx, key, n))
+	}
+	return f.stats.forKey(key).recordValue(f.config.stampDecision(f.allowN(ctx, key, n, f.config.keyLimit(key, f.limit.load()))))
+}
+
+// observeAllowN makes the decision and reports it to Config.Observer when one is configured.
+func (f *fixedWindowLimiter) observeAllowN(ctx context.Context, key string, n, limit int64) (*Result, error) {
+	if f.config.Observer == nil {
+		return f.stats.forKey(key).record(resultPtr(f.config.stampDecision(f.allowN(ctx, key, n, limit))))
+	}
+
+	start := time.Now()
+	result, err := f.stats.forKey(key).record(resultPtr(f.config.stampDecision(f.allowN(ctx, key, n, limit))))
+	f.config.observeDecision(ctx, key, n, start, result, err)
+	return result, err
+}
+
+// recordDecision makes a decision other than AllowN's with decide, against
+// the key's limit, and counts and reports it as observeAllowN does.
+func (f *fixedWindowLimiter) recordDecision(ctx context.Context, key string, n int64, decide func(limit int64) (Result, error)) (*Result, error) {
+	start := time.Now()
+	result, err := f.stats.forKey(key).record(resultPtr(f.config.stampDecision(decide(f.config.keyLimit(key, f.limit.load())))))
+	if f.config.Observer != nil {
+		f.config.observeDecision(ctx, key, n, start, result, err)
+	}
+	return result, err
+}
+
+// allowN makes the rate limit decision for AllowN against the given limit.
 // Uses a Lua script to atomically increment and check the counter.
-func (f *fixedWindowLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+func (f *fixedWindowLimiter) allowN(ctx context.Context, key string, n, limit int64) (Result, error) {
 	if n <= 0 {
-		return nil, ErrInvalidN
+		return Result{}, ErrInvalidN
+	}
+	if result, unlimited := f.config.outsideSchedule(key, limit); unlimited {
+		return result, nil
+	}
+	if result, denied := f.config.permanentDenial(ctx, key, float64(n), limit); denied {
+		return result, nil
 	}
 
-	// Calculate current window start timestamp
 	now := time.Now()
-	windowStart := now.Truncate(f.config.Window).Unix()
 
-	// Format Redis key with window timestamp
-	redisKey := f.formatKey(key, windowStart)
+	var (
+		count       int64
+		created     bool
+		crossed     bool
+		held        bool
+		resetAt     time.Time
+		spacingWait time.Duration
+		err         error
+	)
+	// allowance is what the window admits: the limit, plus any grace early in the window
+	allowance := limit
+	if f.alignedToFirstRequest() {
+		var start int64
+		count, created, start, crossed, held, err = f.incrementAligned(ctx, f.formatAlignedKey(key), n, limit, now)
+		resetAt = f.calculateAlignedResetTime(start)
+		if err != nil {
+			// The stored window start is unknown; assume a window starting now
+			resetAt = now.Add(f.config.Window)
+		}
+	} else {
+		// Calculate current window start timestamp
+		window := f.config.keyWindow(key)
+		windowStart := now.Truncate(window).Unix()
+		resetAt = f.calculateResetTime(windowStart, window)
 
-	// Execute Lua script for atomic increment + check
-	count, err := f.incrementAndCheck(ctx, redisKey, n)
+		// Execute Lua script for atomic increment + check
+		var grace int64
+		count, created, spacingWait, crossed, grace, held, err = f.incrementAndCheck(ctx, key, n, limit, now)
+		allowance += grace
+	}
 	if err != nil {
-		if f.config.FailOpen {
+		if f.config.FailOpen && !misconfigured(err) {
 			// Fail open: allow the request
-			return &Result{
-				Allowed:    true,
-				Limit:      f.config.Limit,
-				Remaining:  0,
-				RetryAfter: 0,
-				ResetAt:    f.calculateResetTime(windowStart),
+			return Result{
+				Allowed:       true,
+				Limit:         limit,
+				Remaining:     f.estimate.remaining(key, n, limit, now),
+				RetryAfter:    0,
+				ResetAt:       resetAt,
+				CheckDuration: time.Since(now),
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(err))
 	}
+	checkDuration := time.Since(now)
+	f.estimate.recovered()
 
-	allowed := count <= f.config.Limit
-	remaining := f.config.Limit - count
+	// Requests that aren't critical leave the reserve for those that are
+	reserve := f.config.reserveFor(ctx)
+	allowed := count <= allowance-reserve && spacingWait == 0 && !held
+	remaining := allowance - count
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	result := &Result{
-		Allowed:    allowed,
-		Limit:      f.config.Limit,
-		Remaining:  remaining,
-		RetryAfter: 0,
-		ResetAt:    f.calculateResetTime(windowStart),
+	result := Result{
+		Allowed:              allowed,
+		Limit:                limit,
+		Remaining:            remaining,
+		Overage:              overage(float64(count), allowance),
+		RetryAfter:           0,
+		ResetAt:              resetAt,
+		FirstSeen:            created,
+		LimitChangedRecently: f.limit.changedWithin(f.config.LimitChangeWindow),
+		RequestsUntilDenied:  f.config.requestsUntilDenied(key, max(remaining-reserve, 0)),
+		CheckDuration:        checkDuration,
+	}
+
+	if created {
+		f.config.notifyKeyCreated(key)
 	}
 
 	if !allowed {
+		result.Reason = ReasonLimitExceeded
 		result.RetryAfter = time.Until(result.ResetAt)
+		if spacingWait > 0 {
+			// Only the minimum interval stands in the way
+			result.Reason = ReasonMinInterval
+			result.RetryAfter = spacingWait
+		}
 		if result.RetryAfter < 0 {
 			result.RetryAfter = 0
 		}
+		result.RetryAfter = f.config.roundRetryAfter(result.RetryAfter)
+	}
+	if crossed {
+		f.config.notifyFirstDenial(key, &result)
 	}
+	f.warmup.observe(ctx, f.client, f.config, key, n, &result)
 
 	return result, nil
 }
 
-// Reset resets the rate limit counter for the given key.
-func (f *fixedWindowLimiter) Reset(ctx context.Context, key string) error {
-	// Calculate current window to delete the right key
-	windowStart := time.Now().Truncate(f.config.Window).Unix()
-	redisKey := f.formatKey(key, windowStart)
+// AllowClass checks if N requests of the given class are allowed for the key,
+// using the class's limit from Config.ClassLimits and its own sub-bucket.
+func (f *fixedWindowLimiter) AllowClass(ctx context.Context, key string, class string, n int64) (result *Result, err error) {
+	defer f.config.recoverDecision(&result, &err)
+	limit, err := f.config.classLimit(class)
+	if err != nil {
+		return nil, err
+	}
+	return f.observeAllowN(ctx, classKey(key, class), n, limit)
+}
+
+// AllowHinted serves the request locally when the caller's hint shows the key
+// is comfortably under its limit, and falls back to AllowN otherwise.
+func (f *fixedWindowLimiter) AllowHinted(ctx context.Context, key string, n int64, localHint int64) (result *Result, err error) {
+	defer f.config.recoverDecision(&result, &err)
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	limit := f.config.keyLimit(key, f.limit.load())
+	// Windows aligned to the first request start at a time only Redis knows,
+	// so a locally served result could not report ResetAt, and request
+	// spacing needs the last allowed time stored in Redis. A reserve or
+	// ObserveFirst needs the stored count too.
+	if f.alignedToFirstRequest() || f.config.MinInterval > 0 || f.config.denylisted(key) ||
+		f.config.reserveFor(ctx) > 0 || f.warmup != nil || !hintIsSafe(limit, n, localHint) {
+		return f.AllowN(ctx, key, n)
+	}
+
+	window := f.config.keyWindow(key)
+	resetAt := f.calculateResetTime(time.Now().Truncate(window).Unix(), window)
+	return f.recordDecision(ctx, key, n, func(limit int64) (Result, error) {
+		return *NewAllowedResult(limit, limit-localHint-n, resetAt), nil
+	})
+}
+
+// SumRemaining returns the quota left across keys in the current window.
+// Counters are read with one pipelined GET per key. A key's usage counts at
+// most Limit, since requests denied after it went over the limit were not
+// served.
+func (f *fixedWindowLimiter) SumRemaining(ctx context.Context, keys []string) (total int64, err error) {
+	defer f.config.recoverError(&err)
+	for _, key := range keys {
+		if key == "" {
+			return 0, ErrInvalidKey
+		}
+	}
+
+	limit := f.limit.load()
+	counts, err := f.readCounts(ctx, keys, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var remaining int64
+	for i, key := range keys {
+		keyLimit := f.config.keyLimit(key, limit)
+		remaining += keyLimit - min(counts[i], keyLimit)
+	}
+	return remaining, nil
+}
+
+// UsedMany returns the quota used by each key in the current window, read
+// with one pipelined GET per key. Usage counts at most Limit, as in
+// SumRemaining.
+func (f *fixedWindowLimiter) UsedMany(ctx context.Context, keys []string) (used map[string]int64, err error) {
+	defer f.config.recoverError(&err)
+	for _, key := range keys {
+		if key == "" {
+			return nil, ErrInvalidKey
+		}
+	}
+
+	limit := f.limit.load()
+	counts, err := f.readCounts(ctx, keys, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	used = make(map[string]int64, len(keys))
+	for i, key := range keys {
+		used[key] = min(counts[i], f.config.keyLimit(key, limit))
+	}
+	return used, nil
+}
+
+// readCounts reads the counters of keys in the window containing now with
+// one pipeline. Missing counters read as 0.
+func (f *fixedWindowLimiter) readCounts(ctx context.Context, keys []string, now time.Time) ([]int64, error) {
+	pipe := f.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		if f.alignedToFirstRequest() {
+			cmds[i] = pipe.HGet(ctx, f.formatAlignedKey(key), "count")
+		} else if redisKey, field := f.counterLocation(key, now); field != "" {
+			cmds[i] = pipe.HGet(ctx, redisKey, field)
+		} else {
+			cmds[i] = pipe.Get(ctx, redisKey)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	counts := make([]int64, len(cmds))
+	for i, cmd := range cmds {
+		count, err := cmd.Int64()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rate limits: %w", err)
+		}
+		counts[i] = count
+	}
+	return counts, nil
+}
+
+// AllowIfCount consumes n only if the key's counter in the current window
+// equals expectedCount. Uses a Lua script so that the comparison and the
+// increment are atomic. Denylist, ActiveSchedule, ReserveForCritical and
+// PostResetGrace apply as in AllowN, and the decision is reported like one.
+func (f *fixedWindowLimiter) AllowIfCount(ctx context.Context, key string, expectedCount, n int64) (result *Result, consumed bool, err error) {
+	defer f.config.recoverDecision(&result, &err)
+	if n <= 0 {
+		return nil, false, ErrInvalidN
+	}
+	if key == "" {
+		return nil, false, ErrInvalidKey
+	}
+	// Counters kept elsewhere (a hash, or alongside the last allowed time)
+	// would need their own compare-and-set script
+	if f.alignedToFirstRequest() || f.config.MinInterval > 0 || f.config.KeyTimeResolution > 0 {
+		return nil, false, fmt.Errorf("%w: AllowIfCount requires %s windows without MinInterval or KeyTimeResolution", ErrInvalidConfig, AlignedToEpoch)
+	}
+
+	result, err = f.recordDecision(ctx, key, n, func(limit int64) (Result, error) {
+		decision, swapped, err := f.compareAndIncrement(ctx, key, expectedCount, n, limit)
+		consumed = swapped
+		return decision, err
+	})
+	return result, consumed && err == nil, err
+}
+
+// compareAndIncrement makes the decision for AllowIfCount against the given
+// limit, and reports whether n was consumed.
+func (f *fixedWindowLimiter) compareAndIncrement(ctx context.Context, key string, expectedCount, n, limit int64) (Result, bool, error) {
+	if result, unlimited := f.config.outsideSchedule(key, limit); unlimited {
+		// Nothing is compared or consumed while the limit isn't enforced
+		return result, true, nil
+	}
+	if result, denied := f.config.permanentDenial(ctx, key, float64(n), limit); denied {
+		return result, false, nil
+	}
+
+	now := time.Now()
+	window := f.config.keyWindow(key)
+	windowStart := now.Truncate(window).Unix()
+	ttl := f.counterTTL(window, now).Milliseconds()
+	reserve := f.config.reserveFor(ctx)
+
+	args := append([]interface{}{n, ttl, expectedCount, limit - reserve}, f.config.graceArgs(now)...)
+	result, err := f.client.Eval(ctx, compareAndIncrementScript, []string{f.formatKey(key, windowStart)}, append(args, now.UnixMilli())...).Result()
+	if err != nil {
+		return Result{}, false, fmt.Errorf("failed to check rate limit: %w", backendError(keyTypeError(err, FixedWindow)))
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 4 {
+		return Result{}, false, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+	consumed, ok := resultSlice[0].(int64)
+	if !ok {
+		return Result{}, false, fmt.Errorf("unexpected consumed type: %T", resultSlice[0])
+	}
+	count, ok := resultSlice[1].(int64)
+	if !ok {
+		return Result{}, false, fmt.Errorf("unexpected count type: %T", resultSlice[1])
+	}
+	created, ok := resultSlice[2].(int64)
+	if !ok {
+		return Result{}, false, fmt.Errorf("unexpected created type: %T", resultSlice[2])
+	}
+	grace, ok := resultSlice[3].(int64)
+	if !ok {
+		return Result{}, false, fmt.Errorf("unexpected grace type: %T", resultSlice[3])
+	}
+	allowance := limit + grace
+
+	if created == 1 {
+		f.config.notifyKeyCreated(key)
+	}
+
+	remaining := max(allowance-count, 0)
+	decision := Result{
+		Allowed:              consumed == 1,
+		Limit:                limit,
+		Remaining:            remaining,
+		Overage:              overage(float64(count), allowance),
+		ResetAt:              f.calculateResetTime(windowStart, window),
+		FirstSeen:            created == 1,
+		LimitChangedRecently: f.limit.changedWithin(f.config.LimitChangeWindow),
+		RequestsUntilDenied:  f.config.requestsUntilDenied(key, max(remaining-reserve, 0)),
+	}
+	// A stale expectedCount can be retried at once; only a full window has to wait
+	if consumed == 0 && count+n > allowance-reserve {
+		decision.Reason = ReasonLimitExceeded
+		decision.RetryAfter = time.Until(decision.ResetAt)
+		if decision.RetryAfter < 0 {
+			decision.RetryAfter = 0
+		}
+		decision.RetryAfter = f.config.roundRetryAfter(decision.RetryAfter)
+	}
+
+	return decision, consumed == 1, nil
+}
+
+// AllowThenReset allows a single request if the key's window has room for it
+// and, when allowed, resets the key in the same Lua script, so no other
+// request can be counted between the check and the reset. Denylist,
+// ActiveSchedule, ReserveForCritical and PostResetGrace apply as in AllowN,
+// and the decision is reported like one.
+func (f *fixedWindowLimiter) AllowThenReset(ctx context.Context, key string) (result *Result, err error) {
+	defer f.config.recoverDecision(&result, &err)
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	// The last allowed time and first-request windows live in other keys
+	// that the script would have to reset too
+	if f.alignedToFirstRequest() || f.config.MinInterval > 0 {
+		return nil, fmt.Errorf("%w: AllowThenReset requires %s windows without MinInterval", ErrInvalidConfig, AlignedToEpoch)
+	}
 
-	if err := f.client.Del(ctx, redisKey).Err(); err != nil {
+	return f.recordDecision(ctx, key, 1, func(limit int64) (Result, error) {
+		return f.allowThenReset(ctx, key, limit)
+	})
+}
+
+// allowThenReset makes the decision for AllowThenReset against the given limit.
+func (f *fixedWindowLimiter) allowThenReset(ctx context.Context, key string, limit int64) (Result, error) {
+	if result, unlimited := f.config.outsideSchedule(key, limit); unlimited {
+		// Nothing is counted, so there is nothing to reset
+		return result, nil
+	}
+	if result, denied := f.config.permanentDenial(ctx, key, 1, limit); denied {
+		return result, nil
+	}
+
+	now := time.Now()
+	window := f.config.keyWindow(key)
+	windowStart := now.Truncate(window).Unix()
+	redisKey, field := f.counterLocation(key, now)
+	reserve := f.config.reserveFor(ctx)
+
+	args := append([]interface{}{limit - reserve, field}, f.config.graceArgs(now)...)
+	result, err := f.client.Eval(ctx, allowThenResetScript, []string{redisKey}, append(args, now.UnixMilli())...).Result()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(keyTypeError(err, FixedWindow)))
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 4 {
+		return Result{}, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+	allowed, ok := resultSlice[0].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected allowed type: %T", resultSlice[0])
+	}
+	count, ok := resultSlice[1].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected count type: %T", resultSlice[1])
+	}
+	missing, ok := resultSlice[2].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected missing type: %T", resultSlice[2])
+	}
+	grace, ok := resultSlice[3].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected grace type: %T", resultSlice[3])
+	}
+	allowance := limit + grace
+
+	// A key seen for the first time is reported although its counter is
+	// reset at once
+	if missing == 1 {
+		f.config.notifyKeyCreated(key)
+	}
+
+	decision := Result{
+		Allowed:              allowed == 1,
+		Limit:                limit,
+		Remaining:            limit, // Reset, so the next request starts fresh
+		ResetAt:              f.calculateResetTime(windowStart, window),
+		FirstSeen:            missing == 1,
+		LimitChangedRecently: f.limit.changedWithin(f.config.LimitChangeWindow),
+	}
+	if !decision.Allowed {
+		decision.Remaining = max(allowance-count, 0)
+		decision.Overage = overage(float64(count), allowance)
+		decision.Reason = ReasonLimitExceeded
+		decision.RetryAfter = time.Until(decision.ResetAt)
+		if decision.RetryAfter < 0 {
+			decision.RetryAfter = 0
+		}
+		decision.RetryAfter = f.config.roundRetryAfter(decision.RetryAfter)
+	}
+	decision.RequestsUntilDenied = f.config.requestsUntilDenied(key, max(decision.Remaining-reserve, 0))
+
+	return decision, nil
+}
+
+// Peek returns the key's status in the current window without incrementing
+// the counter.
+func (f *fixedWindowLimiter) Peek(ctx context.Context, key string) (result *Result, err error) {
+	defer f.config.recoverError(&err)
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	limit := f.config.keyLimit(key, f.limit.load())
+	now := time.Now()
+
+	if f.alignedToFirstRequest() {
+		values, err := f.client.HMGet(ctx, f.formatAlignedKey(key), "count", "start").Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to peek rate limit: %w", keyTypeError(err, FixedWindow))
+		}
+		count, err := parseCount(values[0])
+		if err != nil {
+			return nil, err
+		}
+		start, err := parseCount(values[1])
+		if err != nil {
+			return nil, err
+		}
+
+		// No window has started yet; the next request would start one now
+		resetAt := now.Add(f.config.Window)
+		if start > 0 {
+			resetAt = f.calculateAlignedResetTime(start)
+		}
+		return f.config.peekResult(limit, float64(count), resetAt, now), nil
+	}
+
+	window := f.config.keyWindow(key)
+	windowStart := now.Truncate(window).Unix()
+	redisKey, field := f.counterLocation(key, now)
+	var count int64
+	if field != "" {
+		count, err = f.client.HGet(ctx, redisKey, field).Int64()
+	} else {
+		count, err = f.client.Get(ctx, redisKey).Int64()
+	}
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to peek rate limit: %w", keyTypeError(err, FixedWindow))
+	}
+
+	return f.config.peekResult(limit, float64(count), f.calculateResetTime(windowStart, window), now), nil
+}
+
+// FullResetAt returns when the key will have its full quota again: the end
+// of the current window, or now if the key has used nothing in it.
+func (f *fixedWindowLimiter) FullResetAt(ctx context.Context, key string) (time.Time, error) {
+	status, err := f.Peek(ctx, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if status.Remaining == status.Limit {
+		return time.Now(), nil
+	}
+	return status.ResetAt, nil
+}
+
+// Reset resets the rate limit counter for the given key.
+func (f *fixedWindowLimiter) Reset(ctx context.Context, key string) (err error) {
+	defer f.config.recoverError(&err)
+	if err := f.client.Del(ctx, f.stateKeys(key, time.Now())...).Err(); err != nil {
 		return fmt.Errorf("failed to reset rate limit: %w", err)
 	}
+	f.warmup.reset(key)
 
+	f.config.observeReset(ctx, key)
 	return nil
 }
 
+// MemoryUsage returns the Redis memory used by the key's current state.
+// With KeyTimeResolution this is the key's whole time bucket.
+func (f *fixedWindowLimiter) MemoryUsage(ctx context.Context, key string) (usage int64, err error) {
+	defer f.config.recoverError(&err)
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	return memoryUsage(ctx, f.client, f.stateKeys(key, time.Now()))
+}
+
+// stateKeys returns the Redis keys holding the key's state for the window
+// containing now, including its Config.ObserveFirst count and the mark a
+// hierarchical limiter leaves on its first denial.
+func (f *fixedWindowLimiter) stateKeys(key string, now time.Time) []string {
+	if f.alignedToFirstRequest() {
+		return f.config.withWarmupKey([]string{f.formatAlignedKey(key)}, key)
+	}
+	// With KeyTimeResolution this is the key's whole time bucket, which
+	// holds only the current window and finished ones
+	redisKey, field := f.counterLocation(key, now)
+	keys := []string{redisKey, f.formatLastAllowedKey(key)}
+	if field == "" {
+		keys = append(keys, firstDenialKey(redisKey))
+	}
+	return f.config.withWarmupKey(keys, key)
+}
+
+// MaxBurst returns the worst-case burst for a fixed window: 2 * Limit, plus
+// any PostResetGrace. A client can use the full limit at the very end of one
+// window and again, with the whole grace, at the very start of the next,
+// admitting 2 * Limit + PostResetGrace requests within an arbitrarily short
+// span around the boundary.
+func (f *fixedWindowLimiter) MaxBurst() int64 {
+	return 2*f.limit.load() + f.config.PostResetGrace
+}
+
+// SetLimit changes the limit applied to subsequent decisions.
+func (f *fixedWindowLimiter) SetLimit(limit int64) error {
+	return f.limit.set(limit)
+}
+
+// Describe returns the quota the limiter currently enforces.
+func (f *fixedWindowLimiter) Describe() QuotaInfo {
+	return f.limit.describe(f.config)
+}
+
+// AllowDescribe checks a single request and describes the quota it was
+// checked against, including any Config.WindowResolver override.
+func (f *fixedWindowLimiter) AllowDescribe(ctx context.Context, key string) (result *Result, quota QuotaInfo, err error) {
+	defer f.config.recoverError(&err)
+	result, quota, err = allowDescribe(ctx, f, key)
+	quota.Window = f.config.keyWindow(key)
+	return result, quota, err
+}
+
+// AllowNDecision checks N requests and records the call as a Decision,
+// including any Config.WindowResolver override.
+func (f *fixedWindowLimiter) AllowNDecision(ctx context.Context, key string, n int64) (decision *Decision, err error) {
+	defer f.config.recoverError(&err)
+	decision, err = allowNDecision(ctx, f, key, n)
+	decision.Window = f.config.keyWindow(key)
+	return decision, err
+}
+
+// StartKeyCounter periodically counts the keys under the limiter's prefix.
+func (f *fixedWindowLimiter) StartKeyCounter(ctx context.Context, interval time.Duration) (func(), <-chan int) {
+	return startKeyCounter(ctx, f.client, f.config.keyPattern(), interval)
+}
+
+// Reconcile charges the key's current window the difference between the
+// request's actual cost and ProvisionalCost; see Reconciler.
+func (f *fixedWindowLimiter) Reconcile(ctx context.Context, key string, actualCost int64) (err error) {
+	defer f.config.recoverError(&err)
+	delta, err := reconcileDelta(key, actualCost)
+	if err != nil {
+		return err
+	}
+	return f.adjust(ctx, key, delta)
+}
+
+// adjust adds delta to the key's counter for the current window; see adjuster.
+func (f *fixedWindowLimiter) adjust(ctx context.Context, key string, delta int64) error {
+	// The counter of a first-request window shares its hash with the
+	// window's start, which a created counter would lack
+	if f.alignedToFirstRequest() {
+		return fmt.Errorf("%w: Reconcile requires %s windows", ErrInvalidConfig, AlignedToEpoch)
+	}
+
+	now := time.Now()
+	redisKey, field := f.counterLocation(key, now)
+	return adjustCounter(ctx, f.client, f.config, redisKey, field, delta, f.counterTTL(f.config.keyWindow(key), now))
+}
+
+// FlushAll deletes every key under the limiter's prefix. Meant for tests;
+// see Flusher.
+func (f *fixedWindowLimiter) FlushAll(ctx context.Context) error {
+	return flushKeys(ctx, f.client, f.config)
+}
+
+// DebugInfo returns a snapshot of the limiter's parameters and decision counts.
+func (f *fixedWindowLimiter) DebugInfo() map[string]any {
+	info := f.config.debugInfo(f.limit, &f.stats)
+	script := fixedWindowScript
+	if f.alignedToFirstRequest() {
+		script = alignedWindowScript
+	}
+	info["script_sha"] = scriptSHA(script)
+	info["key_ttl"] = f.counterTTL(f.config.Window, time.Now()).String()
+	info["key_time_resolution"] = f.config.KeyTimeResolution.String()
+	info["alignment"] = string(cmp.Or(f.config.Alignment, AlignedToEpoch))
+	info["cap_counter_at_limit"] = f.config.CapCounterAtLimit
+	info["min_interval"] = f.config.MinInterval.String()
+	return info
+}
+
+// TopDenied returns the most denied keys of the last minute or two, most
+// denied first.
+func (f *fixedWindowLimiter) TopDenied() []KeyCount {
+	return f.stats.topDenied()
+}
+
 // Close closes the rate limiter and releases resources.
 func (f *fixedWindowLimiter) Close() error {
 	if f.client != nil {
@@ -140,24 +1013,190 @@ func (f *fixedWindowLimiter) formatKey(key string, windowStart int64) string {
 	return fmt.Sprintf("%s:%d", f.config.FormatKey(key), windowStart)
 }
 
-// calculateResetTime calculates when the current window will reset.
-func (f *fixedWindowLimiter) calculateResetTime(windowStart int64) time.Time {
-	return time.Unix(windowStart, 0).Add(f.config.Window)
+// counterLocation returns where the key's counter for the window containing
+// now is stored: a Redis key, and the hash field within it when
+// Config.KeyTimeResolution groups several windows into one key ("" otherwise).
+func (f *fixedWindowLimiter) counterLocation(key string, now time.Time) (string, string) {
+	windowStart := now.Truncate(f.config.keyWindow(key)).Unix()
+	if f.config.KeyTimeResolution <= 0 {
+		return f.formatKey(key, windowStart), ""
+	}
+	bucketStart := now.Truncate(f.config.KeyTimeResolution).Unix()
+	return fmt.Sprintf("%s:r%d", f.config.FormatKey(key), bucketStart), strconv.FormatInt(windowStart, 10)
+}
+
+// counterTTL returns the TTL for the counter of the window, of the given
+// length, containing now.
+// A key grouping several windows lives until its last window ends.
+func (f *fixedWindowLimiter) counterTTL(window time.Duration, now time.Time) time.Duration {
+	if f.config.KeyTimeResolution <= 0 {
+		return f.config.effectiveTTL(window)
+	}
+	bucketEnd := now.Truncate(f.config.KeyTimeResolution).Add(f.config.KeyTimeResolution)
+	return f.config.effectiveTTL(bucketEnd.Sub(now))
+}
+
+// formatLastAllowedKey formats the Redis key holding the timestamp of the
+// key's last allowed request, used to enforce Config.MinInterval.
+func (f *fixedWindowLimiter) formatLastAllowedKey(key string) string {
+	return f.config.FormatKey(key) + ":last"
+}
+
+// formatAlignedKey formats the Redis key for windows aligned to the first
+// request. The key holds a single window at a time, so it has no timestamp.
+func (f *fixedWindowLimiter) formatAlignedKey(key string) string {
+	return f.config.FormatKey(key) + ":first"
+}
+
+// alignedToFirstRequest reports whether windows start at each key's first request.
+func (f *fixedWindowLimiter) alignedToFirstRequest() bool {
+	return f.config.Alignment == AlignedToFirstRequest
+}
+
+// calculateResetTime calculates when the current window, of the given length,
+// will reset.
+func (f *fixedWindowLimiter) calculateResetTime(windowStart int64, window time.Duration) time.Time {
+	return time.Unix(windowStart, 0).Add(window)
+}
+
+// calculateAlignedResetTime calculates when a window aligned to the first
+// request will reset, given the stored first-request timestamp in milliseconds.
+func (f *fixedWindowLimiter) calculateAlignedResetTime(firstRequest int64) time.Time {
+	return time.UnixMilli(firstRequest).Add(f.config.Window)
 }
 
-// incrementAndCheck atomically increments the counter and returns the new count.
+// incrementAndCheck atomically increments the counter for key, stored at
+// redisKey, and returns the new count, whether the counter was created by this
+// call, how long until Config.MinInterval has passed since the last
+// allowed request (0 when spacing does not deny the request), whether
+// this call took the counter over its limit, the Config.PostResetGrace
+// the script added to limit, and whether the request was held back for
+// Config.ReserveForCritical without being counted.
 // Uses a Lua script to ensure atomicity.
-func (f *fixedWindowLimiter) incrementAndCheck(ctx context.Context, key string, n int64) (int64, error) {
-	ttl := int64(f.config.Window.Seconds())
-	result, err := f.client.Eval(ctx, fixedWindowScript, []string{key}, n, ttl).Result()
+func (f *fixedWindowLimiter) incrementAndCheck(ctx context.Context, key string, n, limit int64, now time.Time) (int64, bool, time.Duration, bool, int64, bool, error) {
+	if err := f.config.checkCallBudget(ctx); err != nil {
+		return 0, false, 0, false, 0, false, err
+	}
+
+	redisKey, field := f.counterLocation(key, now)
+	ttl := f.counterTTL(f.config.keyWindow(key), now).Milliseconds()
+
+	// Once over the limit every further request is denied anyway, so the
+	// counter only needs to grow until it first exceeds the limit
+	var capCounter int64
+	if f.config.CapCounterAtLimit {
+		capCounter = 1
+	}
+
+	keys := []string{redisKey}
+	if f.config.MinInterval > 0 {
+		keys = append(keys, f.formatLastAllowedKey(key))
+	}
+
+	args := []interface{}{n, ttl, capCounter, f.config.MinInterval.Milliseconds(), now.UnixMilli(), limit,
+		overflowCount(limit + f.config.PostResetGrace), field, f.config.reserveFor(ctx)}
+	result, err := f.client.Eval(ctx, fixedWindowScript, keys, append(args, f.config.graceArgs(now)...)...).Result()
 	if err != nil {
-		return 0, err
+		return 0, false, 0, false, 0, false, keyTypeError(crossSlotError(err), FixedWindow)
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 6 {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+
+	count, ok := resultSlice[0].(int64)
+	if !ok {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected count type: %T", resultSlice[0])
+	}
+
+	created, ok := resultSlice[1].(int64)
+	if !ok {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected created type: %T", resultSlice[1])
 	}
 
-	count, ok := result.(int64)
+	waitMillis, ok := resultSlice[2].(int64)
 	if !ok {
-		return 0, fmt.Errorf("unexpected result type from Redis: %T", result)
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected wait type: %T", resultSlice[2])
 	}
 
-	return count, nil
+	crossed, ok := resultSlice[3].(int64)
+	if !ok {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected crossed type: %T", resultSlice[3])
+	}
+
+	grace, ok := resultSlice[4].(int64)
+	if !ok {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected grace type: %T", resultSlice[4])
+	}
+
+	held, ok := resultSlice[5].(int64)
+	if !ok {
+		return 0, false, 0, false, 0, false, fmt.Errorf("unexpected held type: %T", resultSlice[5])
+	}
+
+	return count, created == 1, time.Duration(waitMillis) * time.Millisecond, crossed == 1, grace, held == 1, nil
+}
+
+// incrementAligned atomically increments the counter of a window aligned to the
+// first request. Returns the new count, whether the window was created by this
+// call, the stored first-request timestamp in milliseconds, whether this
+// call took the counter over limit, and whether the request was held back for
+// Config.ReserveForCritical without being counted.
+func (f *fixedWindowLimiter) incrementAligned(ctx context.Context, key string, n, limit int64, now time.Time) (int64, bool, int64, bool, bool, error) {
+	if err := f.config.checkCallBudget(ctx); err != nil {
+		return 0, false, 0, false, false, err
+	}
+
+	var counterCap int64
+	if f.config.CapCounterAtLimit {
+		counterCap = limit
+	}
+
+	result, err := f.client.Eval(ctx, alignedWindowScript, []string{key},
+		n, f.config.Window.Milliseconds(), now.UnixMilli(), counterCap, overflowCount(limit), limit, f.config.reserveFor(ctx)).Result()
+	if err != nil {
+		return 0, false, 0, false, false, keyTypeError(err, FixedWindow)
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 5 {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+
+	count, ok := resultSlice[0].(int64)
+	if !ok {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected count type: %T", resultSlice[0])
+	}
+
+	created, ok := resultSlice[1].(int64)
+	if !ok {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected created type: %T", resultSlice[1])
+	}
+
+	start, ok := resultSlice[2].(int64)
+	if !ok {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected start type: %T", resultSlice[2])
+	}
+
+	crossed, ok := resultSlice[3].(int64)
+	if !ok {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected crossed type: %T", resultSlice[3])
+	}
+
+	held, ok := resultSlice[4].(int64)
+	if !ok {
+		return 0, false, 0, false, false, fmt.Errorf("unexpected held type: %T", resultSlice[4])
+	}
+
+	return count, created == 1, start, crossed == 1, held == 1, nil
+}
+
+// overflowCount is the value a counter saturates at when an increment would
+// overflow int64: just above the limit, which is enough to deny.
+func overflowCount(limit int64) int64 {
+	if limit == math.MaxInt64 {
+		return limit
+	}
+	return limit + 1
 }
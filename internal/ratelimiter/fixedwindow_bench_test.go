@@ -344,3 +344,96 @@ func BenchmarkFixedWindow_AllowWithResult(b *testing.B) {
 		_ = result.ResetAt
 	}
 }
+
+// BenchmarkFixedWindow_AllowValue compares allocations of the pointer and
+// value result paths
+func BenchmarkFixedWindow_AllowValue(b *testing.B) {
+	client, mr := setupBenchmarkRedis(b)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     1000000000,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer limiter.Close()
+
+	valueLimiter := limiter.(ValueLimiter)
+	ctx := context.Background()
+
+	b.Run("Allow", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := limiter.Allow(ctx, "bench:user:pointer"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("AllowValue", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := valueLimiter.AllowValue(ctx, "bench:user:value"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkFixedWindow_AllowValueN compares allocations of AllowN and
+// AllowValueN. Only decisions made without Redis allocate nothing, and those
+// are denials (here a denylisted key). Every allowed decision takes a Redis
+// round-trip, which allocates in the Redis client; AllowValueN saves only the
+// Result allocation of AllowN.
+func BenchmarkFixedWindow_AllowValueN(b *testing.B) {
+	client, mr := setupBenchmarkRedis(b)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     1000000000,
+		Window:    time.Minute,
+		Denylist:  []string{"bench:user:denied"},
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer limiter.Close()
+
+	valueLimiter := limiter.(ValueLimiter)
+	ctx := context.Background()
+
+	b.Run("AllowN", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := limiter.AllowN(ctx, "bench:user:pointer", 2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("AllowValueN", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := valueLimiter.AllowValueN(ctx, "bench:user:value", 2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("AllowValueN_WithoutRedis", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := valueLimiter.AllowValueN(ctx, "bench:user:denied", 2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,6 +2,8 @@ package ratelimiter
 
 import (
 	"context"
+	"math"
+	"strconv"
 	"testing"
 	"time"
 
@@ -304,6 +306,78 @@ func TestFixedWindow_Integration_ResetAt(t *testing.T) {
 	assert.Equal(t, expectedReset, result.ResetAt)
 }
 
+func TestFixedWindow_Integration_ResetAt_AlignedToFirstRequest(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Alignment: AlignedToFirstRequest,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:aligned"
+
+	// ResetAt is the first request time plus the window, not an epoch boundary
+	before := time.Now()
+	first, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, first.FirstSeen)
+	assert.WithinRange(t, first.ResetAt, before.Add(config.Window).Truncate(time.Millisecond), time.Now().Add(config.Window))
+
+	// Later requests in the same window report the same reset
+	time.Sleep(20 * time.Millisecond)
+	second, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, second.FirstSeen)
+	assert.Equal(t, first.ResetAt, second.ResetAt)
+	assert.Equal(t, int64(8), second.Remaining)
+
+	// Once the window rolls over, the next request starts a new window
+	mr.FastForward(config.Window)
+	before = time.Now()
+	third, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, third.FirstSeen)
+	assert.Equal(t, int64(9), third.Remaining)
+	assert.WithinRange(t, third.ResetAt, before.Add(config.Window).Truncate(time.Millisecond), time.Now().Add(config.Window))
+	assert.True(t, third.ResetAt.After(first.ResetAt))
+}
+
+func TestFixedWindow_Integration_AlignedToFirstRequest_Reset(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Minute,
+		Alignment: AlignedToFirstRequest,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:aligned-reset"
+
+	result, err := limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	require.NoError(t, limiter.Reset(ctx, key))
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.FirstSeen)
+}
+
 func TestFixedWindow_Integration_CustomPrefix(t *testing.T) {
 	client, mr := setupMiniredis(t)
 	defer mr.Close()
@@ -331,3 +405,1009 @@ func TestFixedWindow_Integration_CustomPrefix(t *testing.T) {
 	require.Len(t, keys, 1)
 	assert.Contains(t, keys[0], "custom:")
 }
+
+func TestFixedWindow_Integration_CapCounterAtLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		capAtLimit      bool
+		expectedCounter string
+	}{
+		{
+			name:            "capped counter stops at first over-limit value",
+			capAtLimit:      true,
+			expectedCounter: "6",
+		},
+		{
+			name:            "uncapped counter keeps growing",
+			capAtLimit:      false,
+			expectedCounter: "100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+
+			config := &Config{
+				Algorithm:         FixedWindow,
+				Limit:             5,
+				Window:            time.Hour,
+				CapCounterAtLimit: tt.capAtLimit,
+			}
+
+			limiter, err := NewFixedWindow(client, config)
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			ctx := context.Background()
+			key := "user:hammer"
+
+			// Hammer the key far past its limit
+			for i := 1; i <= 100; i++ {
+				result, err := limiter.Allow(ctx, key)
+				require.NoError(t, err)
+				assert.Equal(t, i <= 5, result.Allowed, "request %d", i)
+				if !result.Allowed {
+					assert.Equal(t, int64(0), result.Remaining)
+					assert.Greater(t, result.RetryAfter, time.Duration(0))
+				}
+			}
+
+			keys := mr.Keys()
+			require.Len(t, keys, 1)
+			counter, err := mr.Get(keys[0])
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCounter, counter)
+		})
+	}
+}
+
+func TestFixedWindow_Integration_ExactRemaining(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:exact"
+
+	result, err := limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	assert.Equal(t, int64(7), result.Remaining)
+
+	// n == remaining is allowed and leaves nothing behind
+	result, err = limiter.AllowN(ctx, key, 7)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestFixedWindow_Integration_AllowValue(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	valueLimiter, ok := limiter.(ValueLimiter)
+	require.True(t, ok, "fixed window should implement ValueLimiter")
+
+	ctx := context.Background()
+
+	// AllowValue and Allow share the same counter and make the same decisions
+	for i := 0; i < 3; i++ {
+		result, err := valueLimiter.AllowValue(ctx, "user:value")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(2-i), result.Remaining)
+		assert.Equal(t, int64(3), result.Limit)
+	}
+
+	result, err := valueLimiter.AllowValue(ctx, "user:value")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	pointer, err := limiter.Allow(ctx, "user:value")
+	require.NoError(t, err)
+	assert.False(t, pointer.Allowed)
+	assert.Equal(t, pointer.ResetAt, result.ResetAt)
+}
+
+func TestFixedWindow_Integration_AllowValueN(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	valueLimiter := limiter.(ValueLimiter)
+	ctx := context.Background()
+
+	// The same sequence on two keys gives the same results by value and by pointer
+	for _, n := range []int64{2, 2, 2, 1} {
+		pointer, err := limiter.AllowN(ctx, "user:pointer", n)
+		require.NoError(t, err)
+		value, err := valueLimiter.AllowValueN(ctx, "user:value", n)
+		require.NoError(t, err)
+		assert.False(t, value.ResetAt.IsZero())
+
+		// RetryAfter and CheckDuration are measured from each call's own time
+		assert.InDelta(t, pointer.RetryAfter, value.RetryAfter, float64(time.Second))
+		pointer.RetryAfter, value.RetryAfter = 0, 0
+		pointer.CheckDuration, value.CheckDuration = 0, 0
+		assert.Equal(t, *pointer, value)
+	}
+
+	_, err = valueLimiter.AllowValueN(ctx, "user:value", 0)
+	assert.ErrorIs(t, err, ErrInvalidN)
+}
+
+func TestFixedWindow_AllowValueN_Allocations(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     1000000,
+		Window:    time.Hour,
+		Denylist:  []string{"user:denied"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	valueLimiter := limiter.(ValueLimiter)
+	ctx := context.Background()
+
+	// Without a Redis call (a denial) nothing escapes to the heap, where
+	// AllowN allocates its Result. Redis round-trips allocate a varying
+	// amount of their own, so they aren't compared.
+	value := testing.AllocsPerRun(100, func() {
+		_, _ = valueLimiter.AllowValueN(ctx, "user:denied", 2)
+	})
+	pointer := testing.AllocsPerRun(100, func() {
+		_, _ = limiter.AllowN(ctx, "user:denied", 2)
+	})
+	assert.Zero(t, value)
+	assert.Equal(t, float64(1), pointer)
+}
+
+func TestFixedWindow_Integration_AllowValue_Observed(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Hour,
+		Observer:  observer,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	result, err := limiter.(ValueLimiter).AllowValue(context.Background(), "user:value")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	observations := observer.all()
+	require.Len(t, observations, 1)
+	assert.Equal(t, result, *observations[0].Result)
+}
+
+func TestFixedWindow_Integration_AllowValue_FailClosed(t *testing.T) {
+	client, mr := setupMiniredis(t)
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	mr.Close()
+
+	result, err := limiter.(ValueLimiter).AllowValue(context.Background(), "user:value")
+	assert.Error(t, err)
+	assert.Equal(t, Result{}, result)
+}
+
+func TestFixedWindow_Integration_FirstSeen(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	var created []string
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:    FixedWindow,
+		Limit:        10,
+		Window:       time.Hour,
+		OnKeyCreated: func(key string) { created = append(created, key) },
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "user:new")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	for i := 0; i < 3; i++ {
+		result, err = limiter.Allow(ctx, "user:new")
+		require.NoError(t, err)
+		assert.False(t, result.FirstSeen)
+	}
+
+	result, err = limiter.Allow(ctx, "user:other")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	assert.Equal(t, []string{"user:new", "user:other"}, created)
+}
+
+func TestFixedWindow_Integration_SumRemaining(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	reader, ok := limiter.(AggregateReader)
+	require.True(t, ok, "fixed window should implement AggregateReader")
+
+	ctx := context.Background()
+	team := []string{"team:1:alice", "team:1:bob", "team:1:carol", "team:1:dave"}
+
+	// alice used 3, bob used 7, carol went over her limit, dave is idle
+	_, err = limiter.AllowN(ctx, team[0], 3)
+	require.NoError(t, err)
+	_, err = limiter.AllowN(ctx, team[1], 7)
+	require.NoError(t, err)
+	for i := 0; i < 15; i++ {
+		_, err = limiter.Allow(ctx, team[2])
+		require.NoError(t, err)
+	}
+
+	remaining, err := reader.SumRemaining(ctx, team)
+	require.NoError(t, err)
+	// 40 total - (3 + 7 + 10) used
+	assert.Equal(t, int64(20), remaining)
+
+	// Read-only: dave's counter was not created
+	keysBefore := len(mr.Keys())
+	_, err = reader.SumRemaining(ctx, team)
+	require.NoError(t, err)
+	assert.Equal(t, keysBefore, len(mr.Keys()))
+
+	// Fully used keys clamp at 0
+	remaining, err = reader.SumRemaining(ctx, team[2:3])
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), remaining)
+
+	// No keys means no quota
+	remaining, err = reader.SumRemaining(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), remaining)
+
+	_, err = reader.SumRemaining(ctx, []string{"team:1:alice", ""})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestFixedWindow_Integration_AllowClass(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:   FixedWindow,
+		Limit:       100,
+		Window:      time.Minute,
+		ClassLimits: map[string]int64{"read": 5, "write": 2},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	classes, ok := limiter.(ClassLimiter)
+	require.True(t, ok, "fixed window should implement ClassLimiter")
+
+	ctx := context.Background()
+	key := "user:123"
+
+	// Writes run out after 2
+	for i := 0; i < 2; i++ {
+		result, err := classes.AllowClass(ctx, key, "write", 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(2), result.Limit)
+	}
+	result, err := classes.AllowClass(ctx, key, "write", 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Reads under the same key are unaffected
+	for i := 0; i < 5; i++ {
+		result, err := classes.AllowClass(ctx, key, "read", 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "read %d should be allowed", i+1)
+		assert.Equal(t, int64(4-i), result.Remaining)
+	}
+	result, err = classes.AllowClass(ctx, key, "read", 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// So is the key's own limit
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(99), result.Remaining)
+
+	_, err = classes.AllowClass(ctx, key, "delete", 1)
+	assert.ErrorIs(t, err, ErrUnknownClass)
+}
+
+func TestFixedWindow_Integration_Peek(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	peeker, ok := limiter.(Peeker)
+	require.True(t, ok, "fixed window should implement Peeker")
+
+	ctx := context.Background()
+	key := "user:peek"
+
+	// An unseen key has its full quota and is not created
+	result, err := peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Remaining)
+	assert.Empty(t, mr.Keys())
+
+	_, err = limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+
+	result, err = peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	// Peeking again reports the same, since nothing was consumed
+	result, err = peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	_, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+
+	result, err = peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+}
+
+func TestFixedWindow_Integration_MinInterval(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:   FixedWindow,
+		Limit:       100,
+		Window:      time.Minute,
+		MinInterval: 200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:spaced"
+
+	result, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// The window has plenty of quota, but the second request comes too soon
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(99), result.Remaining, "a spaced-out request should not use quota")
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, result.RetryAfter, 200*time.Millisecond)
+
+	time.Sleep(result.RetryAfter + 10*time.Millisecond)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(98), result.Remaining)
+
+	// Reset clears the spacing along with the counter
+	require.NoError(t, limiter.Reset(ctx, key))
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestFixedWindow_Integration_AllowIfCount(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	conditional, ok := limiter.(ConditionalLimiter)
+	require.True(t, ok, "fixed window should implement ConditionalLimiter")
+
+	ctx := context.Background()
+	key := "user:cas"
+
+	_, err = limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+
+	// A stale expected count fails without consuming
+	result, swapped, err := conditional.AllowIfCount(ctx, key, 1, 1)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Remaining, "remaining should reflect the actual count")
+	assert.Equal(t, time.Duration(0), result.RetryAfter)
+
+	// The actual count succeeds
+	result, swapped, err = conditional.AllowIfCount(ctx, key, 2, 2)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	// A matching count still cannot exceed the limit
+	result, swapped, err = conditional.AllowIfCount(ctx, key, 4, 2)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	peeked, err := limiter.(Peeker).Peek(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), peeked.Remaining)
+}
+
+func TestFixedWindow_Integration_AllowThenReset(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	resetting, ok := limiter.(ResettingLimiter)
+	require.True(t, ok, "fixed window should implement ResettingLimiter")
+
+	fw := limiter.(*fixedWindowLimiter)
+	ctx := context.Background()
+	key := "user:once"
+	redisKey := fw.formatKey(key, time.Now().Truncate(time.Minute).Unix())
+
+	_, err = limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+
+	// The allow and the reset are one command, so nothing can be counted
+	// between them
+	var calls int
+	client.AddHook(countingHook{calls: &calls})
+
+	result, err := resetting.AllowThenReset(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Remaining)
+	assert.Equal(t, 1, calls)
+	assert.False(t, mr.Exists(redisKey), "counter should be reset")
+
+	// The next call starts fresh
+	result, err = limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// A denial leaves the counter unchanged
+	result, err = resetting.AllowThenReset(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonLimitExceeded, result.Reason)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	stored, err := mr.Get(redisKey)
+	require.NoError(t, err)
+	assert.Equal(t, "3", stored)
+}
+
+func TestFixedWindow_Integration_AllowIfCountAppliesConfig(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	var created []string
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:          FixedWindow,
+		Limit:              3,
+		Window:             time.Minute,
+		Denylist:           []string{"user:blocked"},
+		ReserveForCritical: 1,
+		Observer:           observer,
+		OnKeyCreated:       func(key string) { created = append(created, key) },
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	conditional := limiter.(ConditionalLimiter)
+	ctx := context.Background()
+
+	// A denylisted key is denied without touching Redis
+	result, swapped, err := conditional.AllowIfCount(ctx, "user:blocked", 0, 1)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	assert.True(t, result.Permanent)
+	assert.Equal(t, ReasonDenylisted, result.Reason)
+	assert.Empty(t, mr.Keys())
+
+	// Requests that aren't critical leave the reserve
+	result, swapped, err = conditional.AllowIfCount(ctx, "user:1", 0, 2)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, FixedWindow, result.Algorithm)
+	assert.True(t, result.Atomic)
+	result, swapped, err = conditional.AllowIfCount(ctx, "user:1", 2, 1)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	assert.Equal(t, ReasonLimitExceeded, result.Reason)
+
+	// Every decision is observed
+	assert.Len(t, observer.all(), 3)
+	assert.Equal(t, []string{"user:1"}, created)
+}
+
+func TestFixedWindow_Integration_AllowThenResetAppliesConfig(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	var created []string
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:          FixedWindow,
+		Limit:              3,
+		Window:             time.Minute,
+		Denylist:           []string{"user:blocked"},
+		ReserveForCritical: 1,
+		Observer:           observer,
+		OnKeyCreated:       func(key string) { created = append(created, key) },
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	resetting := limiter.(ResettingLimiter)
+	ctx := context.Background()
+
+	// A denylisted key is denied without touching Redis
+	result, err := resetting.AllowThenReset(ctx, "user:blocked")
+	require.NoError(t, err)
+	assert.True(t, result.Permanent)
+	assert.Equal(t, ReasonDenylisted, result.Reason)
+	assert.Empty(t, mr.Keys())
+
+	// A key first seen by AllowThenReset is reported as created
+	result, err = resetting.AllowThenReset(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.FirstSeen)
+	assert.Equal(t, FixedWindow, result.Algorithm)
+	assert.True(t, result.Atomic)
+	assert.Equal(t, []string{"user:1"}, created)
+
+	// Requests that aren't critical leave the reserve
+	_, err = limiter.AllowN(ctx, "user:1", 2)
+	require.NoError(t, err)
+	result, err = resetting.AllowThenReset(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	result, err = resetting.AllowThenReset(WithCritical(ctx), "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Every decision is observed
+	assert.Len(t, observer.all(), 5)
+}
+
+func TestFixedWindow_AllowThenReset_UnsupportedConfig(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Minute,
+		Alignment: AlignedToFirstRequest,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	_, err = limiter.(ResettingLimiter).AllowThenReset(context.Background(), "user:once")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestFixedWindow_Integration_CounterOverflow(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     100,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fw := limiter.(*fixedWindowLimiter)
+	ctx := context.Background()
+	key := "user:overflow"
+	redisKey := fw.formatKey(key, time.Now().Truncate(time.Minute).Unix())
+
+	mr.Set(redisKey, strconv.FormatInt(math.MaxInt64-10, 10))
+	mr.SetTTL(redisKey, 30*time.Second)
+
+	// The increment would overflow; the counter saturates instead of wrapping
+	// to a negative value that would allow requests
+	result, err := limiter.AllowN(ctx, key, 100)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	stored, err := mr.Get(redisKey)
+	require.NoError(t, err)
+	assert.Equal(t, "101", stored)
+	assert.Equal(t, 30*time.Second, mr.TTL(redisKey), "saturating should keep the window's TTL")
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestFixedWindow_Integration_KeyTimeResolution(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:         FixedWindow,
+		Limit:             3,
+		Window:            10 * time.Second,
+		KeyTimeResolution: time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fw := limiter.(*fixedWindowLimiter)
+	ctx := context.Background()
+	key := "user:coarse"
+
+	for range 2 {
+		result, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	// Both requests were counted in one field of one shared key
+	redisKey, field := fw.counterLocation(key, time.Now())
+	assert.Equal(t, []string{redisKey}, mr.Keys())
+	assert.Equal(t, "2", mr.HGet(redisKey, field))
+	assert.Greater(t, mr.TTL(redisKey), time.Duration(0))
+	assert.LessOrEqual(t, mr.TTL(redisKey), time.Hour)
+
+	peeked, err := limiter.(Peeker).Peek(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), peeked.Remaining)
+
+	remaining, err := limiter.(AggregateReader).SumRemaining(ctx, []string{key})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), remaining)
+
+	result, err := limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	require.NoError(t, limiter.Reset(ctx, key))
+	assert.Empty(t, mr.Keys())
+}
+
+func TestFixedWindow_Integration_UsedMany(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.AllowN(ctx, "user:1", 3)
+	require.NoError(t, err)
+	_, err = limiter.AllowN(ctx, "user:2", 5)
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:2") // Denied, but counted
+	require.NoError(t, err)
+
+	used, err := limiter.(UsageReader).UsedMany(ctx, []string{"user:1", "user:2", "user:untouched"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"user:1": 3, "user:2": 5, "user:untouched": 0}, used)
+	assert.False(t, mr.Exists(limiter.(*fixedWindowLimiter).formatKey("user:untouched", time.Now().Truncate(time.Minute).Unix())))
+
+	_, err = limiter.(UsageReader).UsedMany(ctx, []string{"user:1", ""})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestFixedWindow_Integration_SubSecondWindow(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// Windows are keyed by their start in whole seconds, so two 500ms windows
+	// would share a key; sub-second windows are rejected up front instead of
+	// being stored with a zero TTL
+	_, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    500 * time.Millisecond,
+	})
+	require.Error(t, err)
+	require.NotEmpty(t, ValidationErrors(err))
+	assert.Equal(t, "Window", ValidationErrors(err)[0].Field)
+
+	// The shortest window keeps its counter for the whole window
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	if now.Sub(now.Truncate(time.Second)) > 800*time.Millisecond {
+		// Leave the whole check within one window
+		time.Sleep(time.Second - now.Sub(now.Truncate(time.Second)))
+	}
+
+	key := "user:short"
+	for _, allowed := range []bool{true, true, false} {
+		result, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed)
+	}
+
+	redisKey := limiter.(*fixedWindowLimiter).formatKey(key, time.Now().Truncate(time.Second).Unix())
+	assert.True(t, mr.Exists(redisKey))
+	assert.Equal(t, time.Second, mr.TTL(redisKey))
+}
+
+func TestFixedWindow_Integration_Overage(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for _, want := range []int64{0, 0, 1, 2} {
+		result, err := limiter.Allow(ctx, "user:over")
+		require.NoError(t, err)
+		assert.Equal(t, want, result.Overage)
+	}
+
+	// AllowThenReset reports the overage without going below zero remaining
+	result, err := limiter.(ResettingLimiter).AllowThenReset(ctx, "user:over")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Equal(t, int64(2), result.Overage)
+}
+
+func TestFixedWindow_Integration_FullResetAt(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	result, err := limiter.AllowN(ctx, "user:1", 4)
+	require.NoError(t, err)
+
+	fullAt, err := limiter.(FullResetReporter).FullResetAt(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, result.ResetAt, fullAt, "quota is back in full at the window boundary")
+
+	fullAt, err = limiter.(FullResetReporter).FullResetAt(ctx, "user:untouched")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), fullAt, 100*time.Millisecond, "an unused key is reset now")
+}
+
+func TestFixedWindow_Integration_MinKeyTTL(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Second,
+		MinKeyTTL: 10 * time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	_, err = limiter.Allow(context.Background(), "user:1")
+	require.NoError(t, err)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	assert.Equal(t, 10*time.Second, mr.TTL(keys[0]))
+}
+
+func TestFixedWindow_Integration_PostResetGrace(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:            FixedWindow,
+		Limit:                5,
+		Window:               time.Second,
+		PostResetGrace:       5,
+		PostResetGracePeriod: 500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	admit := func(key string) int64 {
+		var admitted int64
+		for range 20 {
+			result, err := limiter.Allow(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, int64(5), result.Limit)
+			if result.Allowed {
+				admitted++
+			}
+		}
+		return admitted
+	}
+	untilWindowStart := func() time.Duration {
+		now := time.Now()
+		return now.Truncate(time.Second).Add(time.Second).Sub(now)
+	}
+
+	// Right after the reset the window admits more than Limit
+	time.Sleep(untilWindowStart())
+	assert.Greater(t, admit("early"), int64(5))
+
+	// Once the grace period has passed it admits exactly Limit
+	time.Sleep(untilWindowStart() + 600*time.Millisecond)
+	assert.Equal(t, int64(5), admit("late"))
+}
+
+func TestFixedWindow_Integration_WindowResolver(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Minute,
+		LimitResolver: func(key string) int64 {
+			if key == "user:daily" {
+				return 100
+			}
+			return 0
+		},
+		WindowResolver: func(key string) time.Duration {
+			if key == "user:daily" {
+				return 24 * time.Hour
+			}
+			return 0
+		},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	if !now.Truncate(time.Minute).Equal(now.Add(2 * time.Second).Truncate(time.Minute)) {
+		// Keep every decision within the same minute
+		time.Sleep(2 * time.Second)
+		now = time.Now()
+	}
+	minuteStart := now.Truncate(time.Minute)
+	dayStart := now.Truncate(24 * time.Hour)
+
+	// Each key's reset boundary follows its own window
+	minutely, err := limiter.Allow(ctx, "user:minutely")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), minutely.Limit)
+	assert.Equal(t, minuteStart.Add(time.Minute), minutely.ResetAt)
+
+	daily, err := limiter.Allow(ctx, "user:daily")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), daily.Limit)
+	assert.Equal(t, dayStart.Add(24*time.Hour), daily.ResetAt)
+
+	// The counter keys are suffixed with, and expire with, the resolved window
+	minuteKey := "ratelimit:user:minutely:" + strconv.FormatInt(minuteStart.Unix(), 10)
+	dayKey := "ratelimit:user:daily:" + strconv.FormatInt(dayStart.Unix(), 10)
+	assert.True(t, mr.Exists(minuteKey))
+	assert.True(t, mr.Exists(dayKey))
+	assert.Equal(t, time.Minute, mr.TTL(minuteKey))
+	assert.Equal(t, 24*time.Hour, mr.TTL(dayKey))
+
+	peeked, err := limiter.(Peeker).Peek(ctx, "user:daily")
+	require.NoError(t, err)
+	assert.Equal(t, daily.ResetAt, peeked.ResetAt)
+
+	decision, err := limiter.(DecisionLimiter).AllowNDecision(ctx, "user:daily", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, decision.Window)
+}
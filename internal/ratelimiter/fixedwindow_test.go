@@ -212,16 +212,94 @@ func TestFixedWindow_CalculateResetTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fw.config.Window = tt.window
-			result := fw.calculateResetTime(tt.windowStart)
+			result := fw.calculateResetTime(tt.windowStart, tt.window)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+func TestFixedWindow_CalculateAlignedResetTime(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Alignment: AlignedToFirstRequest,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fw := limiter.(*fixedWindowLimiter)
+
+	// The first request's timestamp, not the epoch boundary, anchors the window
+	assert.Equal(t, time.UnixMilli(1640000017250).Add(time.Minute), fw.calculateAlignedResetTime(1640000017250))
+	assert.Equal(t, "ratelimit:user:123:first", fw.formatAlignedKey("user:123"))
+}
+
+func TestFixedWindow_CounterLocation(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:         FixedWindow,
+		Limit:             10,
+		Window:            time.Second,
+		KeyTimeResolution: time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fw := limiter.(*fixedWindowLimiter)
+	minute := time.Unix(1700000040, 0) // A minute boundary
+
+	// Windows within the same minute share a key, each with its own field
+	key1, field1 := fw.counterLocation("user:1", minute.Add(500*time.Millisecond))
+	key2, field2 := fw.counterLocation("user:1", minute.Add(59*time.Second))
+	assert.Equal(t, "ratelimit:user:1:r1700000040", key1)
+	assert.Equal(t, key1, key2)
+	assert.Equal(t, "1700000040", field1)
+	assert.Equal(t, "1700000099", field2)
+	assert.Equal(t, time.Minute, fw.counterTTL(fw.config.Window, minute))
+	assert.Equal(t, time.Second, fw.counterTTL(fw.config.Window, minute.Add(59*time.Second)))
+
+	// The next minute starts a new key
+	key3, _ := fw.counterLocation("user:1", minute.Add(time.Minute))
+	assert.NotEqual(t, key1, key3)
+}
+
+func TestFixedWindow_MaxBurst(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	reporter, ok := limiter.(BurstReporter)
+	require.True(t, ok, "limiter should implement BurstReporter")
+	assert.Equal(t, int64(20), reporter.MaxBurst())
+}
+
 func TestFixedWindow_InterfaceContract(t *testing.T) {
 	// Verify that fixedWindowLimiter implements RateLimiter interface
 	var _ RateLimiter = (*fixedWindowLimiter)(nil)
+	var _ ValueLimiter = (*fixedWindowLimiter)(nil)
+	var _ LimitSetter = (*fixedWindowLimiter)(nil)
+	var _ ClassLimiter = (*fixedWindowLimiter)(nil)
+	var _ Peeker = (*fixedWindowLimiter)(nil)
+	var _ DebugInfoProvider = (*fixedWindowLimiter)(nil)
+	var _ KeyCounter = (*fixedWindowLimiter)(nil)
+	var _ AggregateReader = (*fixedWindowLimiter)(nil)
+	var _ ConditionalLimiter = (*fixedWindowLimiter)(nil)
+	var _ ResettingLimiter = (*fixedWindowLimiter)(nil)
+	var _ UsageReader = (*fixedWindowLimiter)(nil)
+	var _ DeniedTracker = (*fixedWindowLimiter)(nil)
+	var _ Flusher = (*fixedWindowLimiter)(nil)
+	var _ DescribingLimiter = (*fixedWindowLimiter)(nil)
+	var _ Reconciler = (*fixedWindowLimiter)(nil)
+	var _ FullResetReporter = (*fixedWindowLimiter)(nil)
 }
 
 func TestFixedWindow_Close(t *testing.T) {
@@ -0,0 +1,41 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// flushKeys deletes every key under the config's prefix. Keys are found with
+// SCAN in batches of keyCounterBatch and each batch is deleted with one
+// pipeline of single-key DELs, so keys in different Redis Cluster slots can
+// be deleted too.
+func flushKeys(ctx context.Context, client *redis.Client, config *Config) error {
+	if config.KeyPrefix() == "" {
+		return fmt.Errorf("%w: refusing to flush with an empty prefix, which would delete every key in the database", ErrInvalidConfig)
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, config.keyPattern(), keyCounterBatch).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan keys: %w", err)
+		}
+
+		if len(keys) > 0 {
+			pipe := client.Pipeline()
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("failed to delete keys: %w", err)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushAll_DeletesOnlyPrefixedKeys(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+		Prefix:    "tests",
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	// Enough keys to span several SCAN batches
+	for i := range 250 {
+		_, err := limiter.Allow(ctx, "user:"+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	mr.Set("other:key", "1")
+	mr.Set("testsuite:key", "1") // Shares the prefix's first letters only
+	mr.Set("ratelimit:user:1", "1")
+
+	require.NoError(t, limiter.(Flusher).FlushAll(ctx))
+
+	assert.ElementsMatch(t, []string{"other:key", "testsuite:key", "ratelimit:user:1"}, mr.Keys())
+
+	// The limiter starts over
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), result.Remaining)
+}
+
+func TestFlushAll_EscapesGlobCharacters(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Prefix:    "rl*",
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	mr.Set("rlx:user:1", "1")
+
+	require.NoError(t, limiter.(Flusher).FlushAll(ctx))
+	assert.Equal(t, []string{"rlx:user:1"}, mr.Keys())
+}
+
+func TestFlushKeys_RefusesEmptyPrefix(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	mr.Set("unrelated", "1")
+
+	// An empty prefix would match every key in the database
+	err := flushKeys(context.Background(), client, &Config{Prefix: ""})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, []string{"unrelated"}, mr.Keys())
+}
@@ -0,0 +1,17 @@
+package ratelimiter
+
+import (
+	"cmp"
+	"time"
+)
+
+// graceArgs returns the script arguments from which the window scripts
+// compute the extra requests Config.PostResetGrace allows at now: the grace,
+// the period it decays over in milliseconds, and the start of the window
+// containing now in milliseconds. The scripts allow all of the grace at the
+// start of a window, decaying linearly to none by the end of the period, from
+// the time elapsed since the window started.
+func (c *Config) graceArgs(now time.Time) []interface{} {
+	period := cmp.Or(c.PostResetGracePeriod, c.Window/10)
+	return []interface{}{c.PostResetGrace, period.Milliseconds(), now.Truncate(c.Window).UnixMilli()}
+}
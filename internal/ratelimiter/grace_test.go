@@ -0,0 +1,61 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedWindowScript_PostResetGrace(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:      FixedWindow,
+		Limit:          100,
+		Window:         10 * time.Second,
+		PostResetGrace: 10,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	start := time.Unix(1_700_000_400, 0) // a multiple of the 10s window
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int64
+	}{
+		{"window start", 0, 10},
+		{"halfway through the default period", 500 * time.Millisecond, 5},
+		{"rounds up", 950 * time.Millisecond, 1},
+		{"after the period", time.Second, 0},
+		{"late in the window", 9 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The script computes the grace from the time elapsed since the window started
+			_, _, _, _, grace, _, err := limiter.(*fixedWindowLimiter).incrementAndCheck(context.Background(), "user:"+tt.name, 1, 100, start.Add(tt.elapsed))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, grace)
+		})
+	}
+}
+
+func TestFixedWindow_MaxBurst_PostResetGrace(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:      FixedWindow,
+		Limit:          100,
+		Window:         10 * time.Second,
+		PostResetGrace: 10,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	assert.Equal(t, int64(210), limiter.(BurstReporter).MaxBurst())
+}
@@ -0,0 +1,400 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// hierarchicalScript atomically checks every level's fixed window counter
+	// and increments all of them only if every level has room for n requests.
+	//
+	// KEYS[i], i <= L: Counter key for level i, ordered bottom-up (e.g. user, team, org)
+	// KEYS[L+i]: Key marking that level i has denied a request this window
+	// ARGV[1]: The increment amount (n)
+	// ARGV[2i]: The limit for level i
+	// ARGV[2i+1]: The TTL in milliseconds for level i
+	//
+	// Returns: {allowed (0/1), count_1, created_1, crossed_1, ..., crossed_L}
+	// When allowed, count_i is level i's new counter value and created_i is 1
+	// when this call created it; when denied, every counter is left unchanged
+	// and count_i is its current value. Since denials count nothing, a level
+	// marks its first denial of the window instead: crossed_i is 1 when level
+	// i blocked this request and had not blocked one before in its window.
+	hierarchicalScript = `
+local levels = #KEYS / 2
+local n = tonumber(ARGV[1])
+local counts, created, crossed = {}, {}, {}
+local allowed = 1
+for i = 1, levels do
+    counts[i] = tonumber(redis.call('GET', KEYS[i]) or 0)
+    created[i] = 0
+    crossed[i] = 0
+    if counts[i] + n > tonumber(ARGV[i * 2]) then
+        allowed = 0
+    end
+end
+
+if allowed == 1 then
+    for i = 1, levels do
+        counts[i] = redis.call('INCRBY', KEYS[i], n)
+        if counts[i] == n then
+            redis.call('PEXPIRE', KEYS[i], ARGV[i * 2 + 1])
+            created[i] = 1
+        end
+    end
+else
+    for i = 1, levels do
+        if counts[i] + n > tonumber(ARGV[i * 2]) and redis.call('SET', KEYS[levels + i], 1, 'PX', ARGV[i * 2 + 1], 'NX') then
+            crossed[i] = 1
+        end
+    end
+end
+
+local result = {allowed}
+for i = 1, levels do
+    table.insert(result, counts[i])
+    table.insert(result, created[i])
+    table.insert(result, crossed[i])
+end
+return result
+`
+)
+
+// HierarchicalLimiter enforces nested quotas such as org → team → user in a
+// single decision: a request is allowed only if every level has room for it.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type HierarchicalLimiter interface {
+	// Allow checks a single request against every level
+	//
+	// keys holds one key per configured level, in the same bottom-up order
+	// as the levels passed to NewHierarchical.
+	Allow(ctx context.Context, keys []string) (*Result, error)
+
+	// AllowN checks n requests against every level
+	//
+	// The check is atomic across levels: either every level is charged n,
+	// or none is. When denied, Result.DeniedBy holds the key of the
+	// tightest level that lacked quota: the one whose window resets last,
+	// since the request can't be allowed before then, and among those the
+	// one with the least quota left. Result.Limit/Remaining/ResetAt describe
+	// that level. When allowed, they describe the level with the least
+	// quota left.
+	AllowN(ctx context.Context, keys []string, n int64) (*Result, error)
+
+	// Reset clears the current window of every level for the given keys
+	Reset(ctx context.Context, keys []string) error
+
+	// Close releases any resources held by the limiter
+	Close() error
+}
+
+// hierarchicalLimiter implements HierarchicalLimiter on top of fixed window counters.
+type hierarchicalLimiter struct {
+	client *redis.Client
+	levels []*Config
+
+	// stats holds each level's decision counts, nil for the one-off
+	// limiters the parent/child limiter decides with
+	stats []decisionStats
+}
+
+// NewHierarchical creates a limiter enforcing the given levels, ordered
+// bottom-up (most specific first, e.g. user, team, org).
+//
+// Every level must use the FixedWindow algorithm. Levels may use different
+// limits, windows and prefixes. When running against Redis Cluster, the keys
+// passed to Allow must hash to the same slot (e.g. "{org:1}:team:2:user:3").
+func NewHierarchical(client *redis.Client, levels []Config) (HierarchicalLimiter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("at least one level is required")
+	}
+	if err := validateScriptKeys(2 * len(levels)); err != nil {
+		return nil, fmt.Errorf("invalid levels: %w", err)
+	}
+
+	cfgs := make([]*Config, len(levels))
+	stats := make([]decisionStats, len(levels))
+	for i := range levels {
+		cfg := levels[i].WithDefaults()
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config for level %d: %w", i, err)
+		}
+		if cfg.Algorithm != FixedWindow {
+			return nil, fmt.Errorf("invalid config for level %d: hierarchical quotas require %s, got %s", i, FixedWindow, cfg.Algorithm)
+		}
+		if cfg.Alignment == AlignedToFirstRequest {
+			return nil, fmt.Errorf("invalid config for level %d: hierarchical quotas require %s windows", i, AlignedToEpoch)
+		}
+		cfgs[i] = cfg
+		stats[i] = decisionStats{
+			deniedKeys: newDeniedTracker(cfg.TrackTopDenied),
+			exhausted:  newExhaustionNotifier(cfg),
+		}
+	}
+
+	return &hierarchicalLimiter{
+		client: client,
+		levels: cfgs,
+		stats:  stats,
+	}, nil
+}
+
+// Allow checks if a single request is allowed at every level.
+func (h *hierarchicalLimiter) Allow(ctx context.Context, keys []string) (*Result, error) {
+	return h.AllowN(ctx, keys, 1)
+}
+
+// AllowN checks if N requests are allowed at every level.
+// Uses a Lua script so that either all levels are charged or none are. The
+// decision is counted in every level's stats and reported to every level's
+// Observer.
+func (h *hierarchicalLimiter) AllowN(ctx context.Context, keys []string, n int64) (result *Result, err error) {
+	defer h.levels[0].recoverDecision(&result, &err)
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	if err := h.validateKeys(keys); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err = resultPtr(h.levels[0].stampDecision(h.decide(ctx, keys, n)))
+	for i, level := range h.levels {
+		h.stats[i].forKey(keys[i]).record(result, err)
+		if level.Observer != nil {
+			level.observeDecision(ctx, keys[i], n, start, result, err)
+		}
+	}
+	return result, err
+}
+
+// decide makes the decision for AllowN, for keys already validated.
+func (h *hierarchicalLimiter) decide(ctx context.Context, keys []string, n int64) (Result, error) {
+	now := time.Now()
+	resets := make([]time.Time, len(h.levels))
+	redisKeys := make([]string, 2*len(h.levels))
+	args := make([]interface{}, 0, 1+2*len(h.levels))
+	args = append(args, n)
+	for i, level := range h.levels {
+		windowStart := now.Truncate(level.Window).Unix()
+		resets[i] = h.calculateResetTime(level, windowStart)
+		redisKeys[i] = h.formatKey(level, keys[i], windowStart)
+		redisKeys[len(h.levels)+i] = firstDenialKey(redisKeys[i])
+		args = append(args, level.Limit, level.effectiveTTL(level.Window).Milliseconds())
+	}
+
+	allowed, states, err := h.checkLevels(ctx, redisKeys, args)
+	if err != nil {
+		if h.anyFailOpen() && !misconfigured(err) {
+			// Fail open: allow the request
+			return Result{
+				Allowed:       true,
+				Limit:         h.levels[0].Limit,
+				Remaining:     0,
+				RetryAfter:    0,
+				ResetAt:       resets[0],
+				CheckDuration: time.Since(now),
+			}, nil
+		}
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(err))
+	}
+	checkDuration := time.Since(now)
+
+	counts := make([]int64, len(states))
+	for i, state := range states {
+		counts[i] = state.count
+	}
+	index := h.reportedLevel(allowed, counts, n, resets)
+	level := h.levels[index]
+	remaining := max(level.Limit-counts[index], 0)
+	result := Result{
+		Allowed:             allowed,
+		Limit:               level.Limit,
+		Remaining:           remaining,
+		Overage:             overage(float64(counts[index]), level.Limit),
+		RetryAfter:          0,
+		ResetAt:             resets[index],
+		FirstSeen:           states[index].created,
+		RequestsUntilDenied: level.requestsUntilDenied(keys[index], remaining),
+		CheckDuration:       checkDuration,
+	}
+
+	for i, state := range states {
+		if state.created {
+			h.levels[i].notifyKeyCreated(keys[i])
+		}
+	}
+
+	if !allowed {
+		result.DeniedBy = keys[index]
+		result.Reason = ReasonLimitExceeded
+		result.RetryAfter = time.Until(result.ResetAt)
+		if result.RetryAfter < 0 {
+			result.RetryAfter = 0
+		}
+		result.RetryAfter = level.roundRetryAfter(result.RetryAfter)
+	}
+	for i, state := range states {
+		if state.crossed {
+			h.levels[i].notifyFirstDenial(keys[i], &result)
+		}
+	}
+
+	return result, nil
+}
+
+// reportedLevel returns the index of the level a decision is reported
+// against, given each level's counter and when its window resets. When
+// allowed, that is the level with the least quota left. When denied, it is
+// the blocking level whose window resets last, since the request can't be
+// allowed before then, and among those the one with the least quota left.
+// Ties go to the lowest level.
+func (h *hierarchicalLimiter) reportedLevel(allowed bool, counts []int64, n int64, resets []time.Time) int {
+	best := -1
+	for i, level := range h.levels {
+		if !allowed && counts[i]+n <= level.Limit {
+			// Had room; didn't block
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		if !allowed && !resets[i].Equal(resets[best]) {
+			if resets[i].After(resets[best]) {
+				best = i
+			}
+			continue
+		}
+		if level.Limit-counts[i] < h.levels[best].Limit-counts[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Reset clears the current window counter of every level.
+func (h *hierarchicalLimiter) Reset(ctx context.Context, keys []string) error {
+	if err := h.validateKeys(keys); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	redisKeys := make([]string, 0, 2*len(h.levels))
+	for i, level := range h.levels {
+		counterKey := h.formatKey(level, keys[i], now.Truncate(level.Window).Unix())
+		redisKeys = append(redisKeys, counterKey, firstDenialKey(counterKey))
+	}
+
+	if err := h.client.Del(ctx, redisKeys...).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+
+	for i, level := range h.levels {
+		level.observeReset(ctx, keys[i])
+	}
+	return nil
+}
+
+// Close closes the rate limiter and releases resources.
+func (h *hierarchicalLimiter) Close() error {
+	if h.client != nil {
+		return h.client.Close()
+	}
+	return nil
+}
+
+// validateKeys checks that exactly one non-empty key was given per level.
+func (h *hierarchicalLimiter) validateKeys(keys []string) error {
+	if len(keys) != len(h.levels) {
+		return fmt.Errorf("%w: expected %d keys (one per level), got %d", ErrInvalidKey, len(h.levels), len(keys))
+	}
+	for _, key := range keys {
+		if key == "" {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// anyFailOpen reports whether any level is configured to fail open.
+func (h *hierarchicalLimiter) anyFailOpen() bool {
+	for _, level := range h.levels {
+		if level.FailOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// formatKey formats the Redis key with the level's prefix, user key, and window timestamp.
+func (h *hierarchicalLimiter) formatKey(level *Config, key string, windowStart int64) string {
+	return fmt.Sprintf("%s:%d", level.FormatKey(key), windowStart)
+}
+
+// firstDenialKey returns the key marking that the level with the counter at
+// counterKey has denied a request in the counter's window. It shares the
+// counter's hash tag, if any, and so its Redis Cluster slot.
+func firstDenialKey(counterKey string) string {
+	return counterKey + ":denied"
+}
+
+// calculateResetTime calculates when the level's current window will reset.
+func (h *hierarchicalLimiter) calculateResetTime(level *Config, windowStart int64) time.Time {
+	return time.Unix(windowStart, 0).Add(level.Window)
+}
+
+// levelState is what the hierarchical script reports for one level.
+type levelState struct {
+	// count is the level's counter, after the request when it was allowed
+	count int64
+
+	// created is whether the request created the counter
+	created bool
+
+	// crossed is whether the level blocked the request and had not blocked
+	// one before in its window
+	crossed bool
+}
+
+// checkLevels runs the hierarchical script, returning whether the request
+// was allowed and each level's state. keys holds the levels' counter keys
+// followed by their first-denial keys.
+func (h *hierarchicalLimiter) checkLevels(ctx context.Context, keys []string, args []interface{}) (bool, []levelState, error) {
+	result, err := h.client.Eval(ctx, hierarchicalScript, keys, args...).Result()
+	if err != nil {
+		return false, nil, keyTypeError(crossSlotError(err), FixedWindow)
+	}
+
+	levels := len(keys) / 2
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 1+3*levels {
+		return false, nil, fmt.Errorf("unexpected result type from Redis: %T", result)
+	}
+
+	values := make([]int64, len(resultSlice))
+	for i, value := range resultSlice {
+		values[i], ok = value.(int64)
+		if !ok {
+			return false, nil, fmt.Errorf("unexpected result element type: %T", value)
+		}
+	}
+
+	states := make([]levelState, levels)
+	for i := range states {
+		states[i] = levelState{
+			count:   values[1+3*i],
+			created: values[2+3*i] == 1,
+			crossed: values[3+3*i] == 1,
+		}
+	}
+	return values[0] == 1, states, nil
+}
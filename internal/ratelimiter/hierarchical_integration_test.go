@@ -0,0 +1,159 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHierarchical_Integration_ParentBlocksChild(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 5, Window: time.Hour, Prefix: "user"},
+		{Algorithm: FixedWindow, Limit: 10, Window: time.Hour, Prefix: "team"},
+		{Algorithm: FixedWindow, Limit: 12, Window: time.Hour, Prefix: "org"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	alice := []string{"{acme}:alice", "{acme}:team-a", "{acme}"}
+	bob := []string{"{acme}:bob", "{acme}:team-a", "{acme}"}
+	carol := []string{"{acme}:carol", "{acme}:team-b", "{acme}"}
+
+	// Alice uses her full quota, Bob uses all but one of his
+	for i := 0; i < 5; i++ {
+		result, err := limiter.Allow(ctx, alice)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	for i := 0; i < 4; i++ {
+		result, err := limiter.Allow(ctx, bob)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	// Alice is blocked by her own user limit first
+	result, err := limiter.Allow(ctx, alice)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "{acme}:alice", result.DeniedBy)
+	assert.Equal(t, int64(5), result.Limit)
+
+	// Carol is in another team but the org has only 3 requests left
+	result, err = limiter.AllowN(ctx, carol, 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining, "org level is now the tightest")
+	assert.Equal(t, int64(12), result.Limit)
+
+	// Bob is still under his user (4/5) and team (9/10) limits, but the org is full
+	result, err = limiter.Allow(ctx, bob)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "{acme}", result.DeniedBy)
+	assert.Equal(t, int64(12), result.Limit)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	// The denied request must not have consumed from any level
+	windowStart := time.Now().Truncate(time.Hour).Unix()
+	for key, expected := range map[string]string{
+		fmt.Sprintf("user:{acme}:bob:%d", windowStart):    "4",
+		fmt.Sprintf("team:{acme}:team-a:%d", windowStart): "9",
+		fmt.Sprintf("org:{acme}:%d", windowStart):         "12",
+	} {
+		value, err := mr.Get(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, expected, value, key)
+	}
+}
+
+func TestHierarchical_Integration_TightestBlockingLevel(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observers := []*recordingObserver{{}, {}}
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 5, Window: time.Hour, Prefix: "user", Observer: observers[0]},
+		{Algorithm: FixedWindow, Limit: 4, Window: time.Hour, Prefix: "org", Observer: observers[1]},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	keys := []string{"{acme}:alice", "{acme}"}
+	result, err := limiter.AllowN(ctx, keys, 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining, "reports the org, with the least quota left")
+
+	// Both levels block; the org has less quota left than the user
+	result, err = limiter.AllowN(ctx, keys, 3)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "{acme}", result.DeniedBy)
+	assert.Equal(t, int64(4), result.Limit)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	// Every level counts and observes the decisions
+	h := limiter.(*hierarchicalLimiter)
+	for i := range h.levels {
+		assert.Equal(t, int64(1), h.stats[i].allowed.Load())
+		assert.Equal(t, int64(1), h.stats[i].denied.Load())
+		observations := observers[i].all()
+		require.Len(t, observations, 2)
+		assert.Equal(t, int64(3), observations[1].N)
+		assert.Same(t, result, observations[1].Result)
+	}
+}
+
+func TestHierarchical_Integration_Reset(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 1, Window: time.Hour, Prefix: "user"},
+		{Algorithm: FixedWindow, Limit: 1, Window: time.Hour, Prefix: "org"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	keys := []string{"u1", "o1"}
+
+	result, err := limiter.Allow(ctx, keys)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, keys)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	require.NoError(t, limiter.Reset(ctx, keys))
+
+	result, err = limiter.Allow(ctx, keys)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestHierarchical_Integration_FailClosed(t *testing.T) {
+	client, mr := setupMiniredis(t)
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 1, Window: time.Minute},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	mr.Close()
+
+	result, err := limiter.Allow(context.Background(), []string{"u1"})
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
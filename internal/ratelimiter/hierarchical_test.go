@@ -0,0 +1,139 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHierarchical(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	tests := []struct {
+		name        string
+		client      *redis.Client
+		levels      []Config
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:   "valid levels",
+			client: client,
+			levels: []Config{
+				{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, Prefix: "user"},
+				{Algorithm: FixedWindow, Limit: 100, Window: time.Minute, Prefix: "org"},
+			},
+			expectError: false,
+		},
+		{
+			name:        "nil client",
+			client:      nil,
+			levels:      []Config{{Algorithm: FixedWindow, Limit: 10, Window: time.Minute}},
+			expectError: true,
+			errorMsg:    "redis client cannot be nil",
+		},
+		{
+			name:        "no levels",
+			client:      client,
+			levels:      nil,
+			expectError: true,
+			errorMsg:    "at least one level is required",
+		},
+		{
+			name:   "invalid level config",
+			client: client,
+			levels: []Config{
+				{Algorithm: FixedWindow, Limit: 10, Window: time.Minute},
+				{Algorithm: FixedWindow, Limit: 0, Window: time.Minute},
+			},
+			expectError: true,
+			errorMsg:    "invalid config for level 1",
+		},
+		{
+			name:   "unsupported algorithm",
+			client: client,
+			levels: []Config{
+				{Algorithm: TokenBucket, Limit: 10, Window: time.Minute},
+			},
+			expectError: true,
+			errorMsg:    "hierarchical quotas require fixed_window",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewHierarchical(tt.client, tt.levels)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, limiter)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, limiter)
+			}
+		})
+	}
+}
+
+func TestHierarchical_InvalidInput(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, Prefix: "user"},
+		{Algorithm: FixedWindow, Limit: 100, Window: time.Minute, Prefix: "org"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	_, err = limiter.AllowN(ctx, []string{"u1", "o1"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidN)
+
+	_, err = limiter.Allow(ctx, []string{"u1"})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = limiter.Allow(ctx, []string{"u1", ""})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	err = limiter.Reset(ctx, []string{"u1", "o1", "extra"})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestHierarchical_InterfaceContract(t *testing.T) {
+	// Verify that hierarchicalLimiter implements HierarchicalLimiter interface
+	var _ HierarchicalLimiter = (*hierarchicalLimiter)(nil)
+}
+
+func TestHierarchical_ReportedLevel(t *testing.T) {
+	h := &hierarchicalLimiter{levels: []*Config{
+		{Algorithm: FixedWindow, Limit: 1, Window: time.Minute},
+		{Algorithm: FixedWindow, Limit: 3, Window: time.Hour},
+		{Algorithm: FixedWindow, Limit: 10, Window: time.Hour},
+	}}
+	now := time.Unix(1_700_000_000, 0)
+	resets := []time.Time{now.Add(time.Minute), now.Add(time.Hour), now.Add(time.Hour)}
+
+	tests := []struct {
+		name    string
+		allowed bool
+		counts  []int64
+		n       int64
+		want    int
+	}{
+		{"allowed reports the least quota left", true, []int64{0, 3, 2}, 1, 1},
+		{"allowed ties go to the lowest level", true, []int64{0, 2, 9}, 1, 0},
+		{"denied by one level", false, []int64{1, 0, 0}, 1, 0},
+		{"denied reports the level resetting last", false, []int64{1, 1, 0}, 3, 1},
+		{"denied ties on reset report the least quota left", false, []int64{1, 1, 9}, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, h.reportedLevel(tt.allowed, tt.counts, tt.n, resets))
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package ratelimiter
+
+const (
+	// hintHeadroomFraction is the share of the limit a hinted request may
+	// reach and still be served locally. Keeping it at half the limit leaves
+	// room for usage the hint does not know about yet.
+	hintHeadroomFraction = 0.5
+)
+
+// hintIsSafe reports whether a request of size n can be served locally given
+// the caller's usage estimate. Negative hints are treated as unknown.
+func hintIsSafe(limit, n, localHint int64) bool {
+	if localHint < 0 {
+		return false
+	}
+	// Summed in floating point, since localHint+n could overflow for huge hints
+	return float64(localHint)+float64(n) <= float64(limit)*hintHeadroomFraction
+}
@@ -0,0 +1,181 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHintIsSafe(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		n         int64
+		localHint int64
+		want      bool
+	}{
+		{"well under limit", 100, 1, 10, true},
+		{"exactly at headroom", 100, 1, 49, true},
+		{"just over headroom", 100, 1, 50, false},
+		{"large n pushes over headroom", 100, 40, 20, false},
+		{"near limit", 100, 1, 99, false},
+		{"negative hint is unknown", 100, 1, -1, false},
+		{"hint that would overflow", math.MaxInt64, 1, math.MaxInt64, false},
+		{"n that would overflow", math.MaxInt64, math.MaxInt64, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hintIsSafe(tt.limit, tt.n, tt.localHint))
+		})
+	}
+}
+
+func TestAllowHinted_SkipsRedisWhenWellUnderLimit(t *testing.T) {
+	constructors := map[Algorithm]func(*redis.Client, *Config) (RateLimiter, error){
+		FixedWindow:   NewFixedWindow,
+		SlidingWindow: NewSlidingWindow,
+		TokenBucket:   NewTokenBucket,
+	}
+
+	for algorithm, newLimiter := range constructors {
+		t.Run(string(algorithm), func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+
+			config := &Config{
+				Algorithm: algorithm,
+				Limit:     100,
+				Window:    time.Minute,
+				FailOpen:  false,
+			}
+
+			limiter, err := newLimiter(client, config)
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			hinted, ok := limiter.(HintedLimiter)
+			require.True(t, ok, "limiter should implement HintedLimiter")
+
+			// With Redis down and fail-closed, any Redis query would error out
+			mr.Close()
+			ctx := context.Background()
+
+			result, err := hinted.AllowHinted(ctx, "user:hot", 1, 10)
+			require.NoError(t, err, "Redis should not be queried for a safe hint")
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(100), result.Limit)
+			assert.Equal(t, int64(89), result.Remaining)
+			assert.False(t, result.ResetAt.IsZero())
+
+			// Near the limit the limiter must consult Redis
+			result, err = hinted.AllowHinted(ctx, "user:hot", 1, 95)
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestAllowHinted_FallsBackToAllowN(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     2,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	hinted := limiter.(HintedLimiter)
+	ctx := context.Background()
+
+	// A hint at the limit goes to Redis, which still enforces the real count
+	for i := 0; i < 2; i++ {
+		result, err := hinted.AllowHinted(ctx, "user:1", 1, 2)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := hinted.AllowHinted(ctx, "user:1", 1, 2)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	_, err = hinted.AllowHinted(ctx, "user:1", 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidN)
+}
+
+func TestAllowHinted_ReserveAndObserveFirstGoToRedis(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		ctx    context.Context
+		local  bool
+	}{
+		{"reserve", Config{ReserveForCritical: 10}, context.Background(), false},
+		{"reserve, critical request", Config{ReserveForCritical: 10}, WithCritical(context.Background()), true},
+		{"observe first", Config{ObserveFirst: 5}, context.Background(), false},
+	}
+
+	forEachAlgorithm(t, func(t *testing.T, alg algorithmCase) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				client, mr := setupMiniredis(t)
+
+				config := tt.config
+				config.Algorithm = alg.algorithm
+				config.Limit = 100
+				config.Window = time.Minute
+				limiter, err := alg.create(client, &config)
+				require.NoError(t, err)
+				defer limiter.Close()
+
+				// With Redis down and fail-closed, only a local decision succeeds
+				mr.Close()
+				result, err := limiter.(HintedLimiter).AllowHinted(tt.ctx, "user:1", 1, 10)
+				if tt.local {
+					require.NoError(t, err)
+					assert.True(t, result.Allowed)
+				} else {
+					assert.Error(t, err, "the request should have gone to Redis")
+				}
+			})
+		}
+	})
+}
+
+func TestAllowHinted_LocalDecisionsAreRecorded(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		observer := &recordingObserver{}
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     100,
+			Window:    time.Minute,
+			Observer:  observer,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, err := limiter.(HintedLimiter).AllowHinted(context.Background(), "user:1", 2, 10)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, tt.algorithm, result.Algorithm)
+
+		observations := observer.all()
+		require.Len(t, observations, 1)
+		require.NotNil(t, observations[0].Result)
+		assert.True(t, observations[0].Result.Allowed)
+		assert.Equal(t, int64(2), observations[0].N)
+		assert.Equal(t, int64(1), limiter.(DebugInfoProvider).DebugInfo()["decisions_allowed"])
+	})
+}
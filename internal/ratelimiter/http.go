@@ -0,0 +1,64 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Standard rate limit response headers set by RateLimitHeaders.
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// RateLimitHeaders builds the standard rate limit headers for a decision:
+// X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset (Unix seconds)
+// and, when denied, Retry-After in whole seconds rounded up. Permanent denials
+// carry no Retry-After, since retrying would not help.
+func RateLimitHeaders(result *Result) http.Header {
+	headers := make(http.Header)
+	if result == nil {
+		return headers
+	}
+
+	headers.Set(HeaderLimit, strconv.FormatInt(result.Limit, 10))
+	headers.Set(HeaderRemaining, strconv.FormatInt(result.Remaining, 10))
+	if !result.ResetAt.IsZero() {
+		headers.Set(HeaderReset, strconv.FormatInt(result.ResetAt.Unix(), 10))
+	}
+	if !result.Allowed && !result.Permanent {
+		headers.Set(HeaderRetryAfter, strconv.FormatInt(result.RetryAfterSeconds(), 10))
+	}
+	return headers
+}
+
+// CheckHTTP makes the rate limit decision for an HTTP request, keyed by
+// keyFn, and returns everything middleware needs to respond: whether to
+// serve the request, the headers to add and the status to use
+// (http.StatusOK or http.StatusTooManyRequests).
+//
+// Limiters configured to fail open already allow requests when Redis is
+// unavailable, so CheckHTTP only sees their decisions. When the limiter
+// fails closed, the error is returned with http.StatusServiceUnavailable and
+// no headers. An empty key is rejected with http.StatusBadRequest and
+// ErrInvalidKey.
+func CheckHTTP(ctx context.Context, limiter RateLimiter, r *http.Request, keyFn func(*http.Request) string) (allowed bool, headers http.Header, status int, err error) {
+	key := keyFn(r)
+	if key == "" {
+		return false, make(http.Header), http.StatusBadRequest, ErrInvalidKey
+	}
+
+	result, err := limiter.Allow(ctx, key)
+	if err != nil {
+		return false, make(http.Header), http.StatusServiceUnavailable, err
+	}
+
+	headers = RateLimitHeaders(result)
+	if !result.Allowed {
+		return false, headers, http.StatusTooManyRequests, nil
+	}
+	return true, headers, http.StatusOK, nil
+}
@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userKey(r *http.Request) string {
+	return r.Header.Get("X-User-ID")
+}
+
+func TestCheckHTTP_AllowedAndDenied(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-ID", "user:1")
+	resetAt := strconv.FormatInt(time.Now().Truncate(time.Minute).Add(time.Minute).Unix(), 10)
+
+	allowed, headers, status, err := CheckHTTP(ctx, limiter, r, userKey)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "1", headers.Get(HeaderLimit))
+	assert.Equal(t, "0", headers.Get(HeaderRemaining))
+	assert.Equal(t, resetAt, headers.Get(HeaderReset))
+	assert.Empty(t, headers.Get(HeaderRetryAfter))
+
+	allowed, headers, status, err = CheckHTTP(ctx, limiter, r, userKey)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, "1", headers.Get(HeaderLimit))
+	assert.Equal(t, "0", headers.Get(HeaderRemaining))
+	assert.Equal(t, resetAt, headers.Get(HeaderReset))
+
+	retryAfter, err := strconv.Atoi(headers.Get(HeaderRetryAfter))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+	assert.LessOrEqual(t, retryAfter, 60)
+}
+
+func TestCheckHTTP_FailOpenAndClosed(t *testing.T) {
+	ctx := context.Background()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-ID", "user:1")
+
+	for _, failOpen := range []bool{true, false} {
+		client, _ := unreachableClient(t)
+		limiter, err := NewFixedWindow(client, &Config{
+			Algorithm: FixedWindow,
+			Limit:     10,
+			Window:    time.Minute,
+			FailOpen:  failOpen,
+		})
+		require.NoError(t, err)
+
+		allowed, headers, status, err := CheckHTTP(ctx, limiter, r, userKey)
+		if failOpen {
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "10", headers.Get(HeaderLimit))
+		} else {
+			assert.Error(t, err)
+			assert.False(t, allowed)
+			assert.Equal(t, http.StatusServiceUnavailable, status)
+			assert.Empty(t, headers)
+		}
+	}
+}
+
+func TestCheckHTTP_EmptyKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	allowed, _, status, err := CheckHTTP(context.Background(), &scriptedLimiter{decisions: []bool{true}}, r, userKey)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.False(t, allowed)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
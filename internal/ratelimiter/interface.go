@@ -20,6 +20,49 @@ const (
 	// FixedWindow provides simple counter-based rate limiting
 	// Best for: Internal services, soft quotas, high-throughput systems
 	FixedWindow Algorithm = "fixed_window"
+
+	// Concurrency limits the number of requests in flight at the same time
+	// Best for: Expensive endpoints, downstream connection limits, long-running jobs
+	Concurrency Algorithm = "concurrency"
+)
+
+// DenyReason explains why a request was denied, or allowed without being
+// counted
+type DenyReason string
+
+const (
+	// ReasonLimitExceeded means the key has used its quota for now
+	ReasonLimitExceeded DenyReason = "limit_exceeded"
+
+	// ReasonMinInterval means the request came sooner than Config.MinInterval
+	// after the key's last allowed request
+	ReasonMinInterval DenyReason = "min_interval"
+
+	// ReasonDenylisted means the key is in Config.Denylist (permanent)
+	ReasonDenylisted DenyReason = "denylisted"
+
+	// ReasonExceedsLimit means the request asks for more than the limit, or
+	// isn't critical and asks for more than the limit less
+	// Config.ReserveForCritical, so it could never fit however long the
+	// caller waits (permanent)
+	ReasonExceedsLimit DenyReason = "exceeds_limit"
+
+	// ReasonOutsideSchedule means the request was allowed without being
+	// counted because it came outside Config.ActiveSchedule
+	ReasonOutsideSchedule DenyReason = "outside_schedule"
+)
+
+// WindowAlignment controls where fixed windows start
+type WindowAlignment string
+
+const (
+	// AlignedToEpoch starts windows at multiples of Window since the Unix epoch,
+	// so every key shares the same boundaries
+	AlignedToEpoch WindowAlignment = "epoch"
+
+	// AlignedToFirstRequest starts a key's window at its first request, so
+	// each key gets a full Window from the moment it is first used
+	AlignedToFirstRequest WindowAlignment = "first_request"
 )
 
 // Result contains the outcome of a rate limit check
@@ -40,19 +83,86 @@ type Result struct {
 
 	// ResetAt indicates when the rate limit window resets
 	ResetAt time.Time
+
+	// Source is the key of the bucket that served the request when a check
+	// spans several buckets (see OverflowLimiter)
+	// Empty for single-key checks
+	Source string
+
+	// DeniedBy is the key of the bucket that caused the denial when a check
+	// spans several buckets
+	// Empty when Allowed is true or for single-key checks
+	DeniedBy string
+
+	// LimitChangedRecently is true when the limit was changed (see LimitSetter)
+	// less than Config.LimitChangeWindow before this decision
+	// Useful to explain sudden allow/deny shifts
+	LimitChangedRecently bool
+
+	// FirstSeen is true when this call created the key's state in Redis
+	// Window algorithms create state once per window, so FirstSeen is true
+	// for the first request of every window
+	FirstSeen bool
+
+	// Reason explains a denial
+	// Empty when Allowed is true, except ReasonOutsideSchedule and WouldDeny
+	// decisions
+	Reason DenyReason
+
+	// WouldDeny reports that the request was allowed only because its key is
+	// still within Config.ObserveFirst; Reason says why it would have been
+	// denied
+	WouldDeny bool
+
+	// Permanent is true when retrying can never succeed, e.g. for a denylisted
+	// key or a request larger than the limit; RetryAfter and ResetAt are then
+	// zero, since waiting would not help
+	Permanent bool
+
+	// Overage is how far the key's recorded usage is over the limit, e.g. 23
+	// when the (weighted) count is Limit+23; Remaining stops at 0 instead
+	// Window counters go over the limit because denied requests are counted
+	// too (see CapCounterAtLimit); token buckets never go below empty, so
+	// their Overage is always 0
+	Overage int64
+
+	// RequestsUntilDenied estimates how many more requests of typical cost
+	// (see Config.RequestCost) would be allowed before the first denial
+	// Equals Remaining when every request costs 1
+	RequestsUntilDenied int64
+
+	// CheckDuration is the wall time from the start of the check to the
+	// Redis reply, mostly the Redis round-trip
+	// Zero for decisions made without calling Redis, such as permanent denials
+	CheckDuration time.Duration
+
+	// Algorithm is the algorithm of the limiter that made the decision, so
+	// results merged from several limiters can be told apart in logs
+	// Empty for results not made by a limiter, such as NewFailOpenResult
+	Algorithm Algorithm
+
+	// Atomic reports that the decision was all-or-nothing: either all N
+	// requests were admitted or none were, as with AllowN
+	// Set on every decision made by a limiter in this package; false for a
+	// Result that may grant only part of what was asked for, or that no
+	// limiter made, such as NewFailOpenResult
+	Atomic bool
 }
 
 // Config holds configuration for a rate limiter instance
 type Config struct {
 	// Algorithm specifies which rate limiting algorithm to use
-	// Required: must be one of TokenBucket, SlidingWindow, or FixedWindow
+	// Required: must be one of TokenBucket, SlidingWindow, FixedWindow, or Concurrency
 	Algorithm Algorithm
 
 	// Limit is the maximum number of requests allowed within the window
+	// For Concurrency, it is the maximum number of requests in flight
 	// Required: must be > 0
 	Limit int64
 
 	// Window is the time duration for the rate limit
+	// FixedWindow and SlidingWindow require a whole number of seconds
+	// For Concurrency, it is the safety TTL after which leaked slots are reclaimed
 	// Required: must be > 0
 	// Examples: time.Second, time.Minute, time.Hour
 	Window time.Duration
@@ -62,11 +172,337 @@ type Config struct {
 	// Set to empty string "" to disable automatic prefixing
 	Prefix string
 
+	// TagKeysWithAlgorithm adds the algorithm after the prefix in every Redis
+	// key ("ratelimit:token_bucket:user:1"), so limiters with different
+	// algorithms never read each other's state even when they share a Prefix
+	// Without it such limiters fail with ErrKeyTypeMismatch (or silently share
+	// counters, for the two window algorithms)
+	// Changing it orphans existing state, as every key moves
+	// Default: false
+	TagKeysWithAlgorithm bool
+
 	// FailOpen determines behavior when Redis is unavailable
 	// true:  Allow requests when Redis is down (fail-open, prioritizes availability)
 	// false: Deny requests when Redis is down (fail-closed, prioritizes security)
 	// Default: false (fail-closed)
 	FailOpen bool
+
+	// MinCallBudget is the least time a request's context must have left
+	// before its deadline for the limiter to call Redis
+	// Requests with less time left skip Redis and fail open or closed (see
+	// FailOpen) right away with ErrInsufficientBudget, instead of spending
+	// their last milliseconds on a call that would most likely time out
+	// Example: 5ms when Redis round-trips typically take a few milliseconds
+	// Optional: 0 always calls Redis
+	MinCallBudget time.Duration
+
+	// FailOpenEstimate makes fail-open results report Remaining from an
+	// in-process estimate, Limit minus the key's fail-open allows so far in
+	// the window, instead of 0, so rate limit headers degrade gracefully
+	// during an outage
+	// The estimate starts from zero usage and is per process; it is dropped
+	// when Redis answers again
+	// Requires FailOpen
+	// Default: false
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	FailOpenEstimate bool
+
+	// CapCounterAtLimit stops window counters from growing once they exceed Limit
+	// true:  Requests arriving after the counter went over Limit are denied
+	//        without incrementing it, bounding the stored value under DoS traffic
+	// false: Every request increments the counter, even when denied
+	// Default: false
+	// Applies to: FixedWindow
+	CapCounterAtLimit bool
+
+	// Alignment controls where windows start and therefore Result.ResetAt
+	// AlignedToEpoch:        ResetAt is the next multiple of Window since the epoch
+	// AlignedToFirstRequest: ResetAt is the key's first request time plus Window
+	// Default: AlignedToEpoch
+	// Applies to: FixedWindow
+	Alignment WindowAlignment
+
+	// MinInterval is the minimum spacing between allowed requests for a key,
+	// on top of the window limit, to prevent micro-bursts
+	// Example: Limit 100, Window time.Minute, MinInterval 200ms allows 100/min
+	// but never two requests within 200ms; requests arriving sooner are denied
+	// with RetryAfter set to the rest of the interval, without using quota
+	// FixedWindow stores the last allowed time in a second key, so on Redis
+	// Cluster keys must contain a hash tag (e.g. "{user:1}")
+	// Optional: 0 disables spacing
+	// Applies to: FixedWindow (aligned to the epoch), TokenBucket
+	MinInterval time.Duration
+
+	// PostResetGrace is how many requests beyond Limit a window admits right
+	// after it starts, softening the cliff for keys denied just before the reset
+	// The grace decays linearly to nothing over PostResetGracePeriod; requests
+	// admitted during the grace still count towards the window
+	// While the grace lasts, Result.Remaining includes it and can exceed Limit
+	// Optional: 0 disables the grace
+	// Applies to: FixedWindow (aligned to the epoch)
+	PostResetGrace int64
+
+	// PostResetGracePeriod is how long after a window starts PostResetGrace
+	// takes to decay to nothing
+	// Must not exceed Window
+	// Default: Window / 10
+	// Applies to: FixedWindow (aligned to the epoch)
+	PostResetGracePeriod time.Duration
+
+	// RoundRetryAfter rounds Result.RetryAfter up to the next whole second
+	// Use it when RetryAfter is sent in an HTTP Retry-After header, which only
+	// carries whole seconds: truncating 4.3s to 4 makes clients retry too early
+	// Default: false (RetryAfter is exact)
+	RoundRetryAfter bool
+
+	// Strict rejects configs that are valid but likely to surprise
+	// Currently: a token bucket refilling less than one token per second
+	// (e.g. Limit 1, Window time.Hour), whose whole-token Remaining reads 0
+	// for most of the window and whose RetryAfter spans long durations
+	// Default: false (such configs are accepted)
+	// Applies to: TokenBucket
+	Strict bool
+
+	// InitialTokens is the number of tokens a bucket starts with the first
+	// time a key is seen, instead of full capacity
+	// Use it for warm starts after a deploy, so that fresh buckets don't all
+	// allow a full burst at once
+	// 0: Start full (default)
+	// > 0: Start with this many tokens; must not exceed Limit
+	// Applies to: TokenBucket
+	InitialTokens int64
+
+	// InitialFill is the fraction of capacity a bucket starts with the first
+	// time a key is seen, when InitialTokens is not set
+	// Unlike InitialTokens it scales with the key's limit, so one cold-start
+	// policy (e.g. 0.5 for half full) can be shared by endpoints with
+	// different limits; the tokens are rounded down, to at least one
+	// 0 or 1: Start full (default)
+	// Between 0 and 1: Start with this fraction of capacity
+	// Applies to: TokenBucket
+	InitialFill float64
+
+	// SubWindows divides the window into this many sub-windows for sliding window accounting
+	// 0 or 1: Classic two-window approximation (previous and current window)
+	// > 1:    Finer accounting that tracks the true sliding count more closely,
+	//         at the cost of SubWindows+1 Redis keys read per decision
+	// Window must divide into whole-second sub-windows; at most MaxSubWindows
+	// Default: 0
+	// Applies to: SlidingWindow
+	SubWindows int
+
+	// TTLRefreshFraction skips TTL refreshes while a key's remaining TTL is at
+	// least this fraction of its full TTL, turning most EXPIREs on hot keys into no-ops
+	// 0:          Refresh the TTL on every request
+	// 0.5 to 1.0: Refresh only once the remaining TTL drops below the fraction
+	//             (see MinTTLRefreshFraction)
+	// Default: 0
+	// Applies to: TokenBucket, SlidingWindow
+	TTLRefreshFraction float64
+
+	// HashTagKeys wraps the key in a Redis Cluster hash tag ("ratelimit:{user:1}:1700000000")
+	// so that all the window keys one decision reads land in the same slot
+	// true:  Required on Redis Cluster, where a script over keys in different
+	//        slots fails with ErrCrossSlot
+	// false: Keys are used as is; changing it starts every key from fresh state
+	// Default: false
+	// Applies to: SlidingWindow
+	HashTagKeys bool
+
+	// KeyTimeResolution groups the counters of consecutive windows into one
+	// Redis key (a hash with one field per window) covering this much time,
+	// to cut key churn for high-cardinality keys with short windows
+	// Example: Window time.Second, KeyTimeResolution time.Minute creates one
+	// key per minute instead of 60
+	// Counts stay exact; the trade-off is memory and precision of expiry: a
+	// finished window's count is kept until its key expires at the end of the
+	// resolution, up to KeyTimeResolution after the window ended, instead of
+	// expiring with the window
+	// Must be a multiple of Window; requires windows aligned to the epoch
+	// Optional: 0 uses one key per window
+	// Applies to: FixedWindow
+	KeyTimeResolution time.Duration
+
+	// WindowedState stores each key's bucket in a hash per window
+	// ("ratelimit:user:1:1700000000") instead of a single long-lived hash
+	// true:  All of a window's buckets share a key suffix, so they can be
+	//        scanned together and expire together when the window ends; every
+	//        window starts from a fresh bucket (see InitialTokens)
+	// false: One hash per key that refills continuously across windows
+	// Requires Window to be a whole number of seconds
+	// Default: false
+	// Applies to: TokenBucket
+	WindowedState bool
+
+	// StateRetention is how long a bucket's state is kept after its last
+	// request, e.g. shorter than the default for daily quotas with many idle keys
+	// Any bucket refills to capacity within Window, so it must be at least Window
+	// Default: 2 * Window
+	// Applies to: TokenBucket (without WindowedState)
+	StateRetention time.Duration
+
+	// ExpireFullBuckets shortens the TTL of a bucket found refilled to
+	// capacity to Window, so state of keys that only make the odd request
+	// expires promptly instead of lingering for StateRetention
+	// Applies to: TokenBucket (Allow/AllowN, without WindowedState)
+	ExpireFullBuckets bool
+
+	// Denylist holds keys that are always denied, permanently and without
+	// touching Redis (see Result.Permanent)
+	// It matches the key passed to Allow/AllowN exactly
+	// Optional: nil denies nothing
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	Denylist []string
+
+	// ActiveSchedule restricts the limit to recurring time-of-day windows,
+	// e.g. business hours; outside every window requests are allowed without
+	// touching Redis or using quota, with Result.Reason ReasonOutsideSchedule
+	// Denylisted keys are denied at all times
+	// Example: []ScheduleWindow{{Days: []time.Weekday{time.Monday, ...,
+	// time.Friday}, Start: 9 * time.Hour, End: 17 * time.Hour}}
+	// Optional: nil enforces the limit at all times
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	ActiveSchedule []ScheduleWindow
+
+	// ScheduleLocation is the time zone ActiveSchedule windows are in
+	// Optional: nil means UTC
+	ScheduleLocation *time.Location
+
+	// TrackTopDenied records denied keys in process so that the most denied
+	// ones can be listed with TopDenied (see DeniedTracker), without scanning Redis
+	// Memory stays bounded by this many keys per TopDeniedWindow however many
+	// keys are denied
+	// Optional: 0 disables tracking
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	TrackTopDenied int
+
+	// ReserveForCritical holds back this much of each key's quota for
+	// critical requests, those made with a context from WithCritical
+	// Other requests are denied once Remaining drops to the reserve, so
+	// critical traffic still gets through when the key is busy
+	// Example: Limit 100 and ReserveForCritical 10 lets 90 requests of any
+	// kind through, then only critical ones for the last 10
+	// Optional: 0 reserves nothing
+	// Must be less than Limit
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	ReserveForCritical int64
+
+	// ObserveFirst runs the limiter in observe-only mode for the first
+	// ObserveFirst requests of each key, e.g. to measure the impact of a new
+	// limit before enforcing it: those requests are always allowed, and the
+	// ones the limit would have denied are marked Result.WouldDeny
+	// Requests are counted in Redis in a key per rate-limited key, at one
+	// extra call per decision until the key's warmup is over and then one per
+	// key per Window; a count expires after two windows without requests, so
+	// a key idle that long starts its warmup again
+	// Denylisted keys and requests larger than Limit are denied regardless
+	// Optional: 0 enforces from the first request
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	ObserveFirst int64
+
+	// LocalCacheTTL is how long a cached limiter (see NewCached) trusts a local
+	// "denied for the rest of this window" verdict before asking Redis again
+	// Shorter: more accurate, since quota freed by refills or resets is seen
+	//          sooner, but more Redis load from keys that stay over the limit
+	// Longer:  less Redis load, but denials may outlive the quota shortage
+	// Only denials are cached, never allows, so caching can't admit extra requests
+	// Default: DefaultLocalCacheTTL
+	LocalCacheTTL time.Duration
+
+	// MinKeyTTL is the shortest TTL set on any Redis key the limiter writes
+	// TTLs otherwise follow Window (or what is left of it), so keys for very
+	// short windows could expire before they are read again, e.g. when
+	// clocks are skewed or a test fast-forwards Redis time
+	// Default: DefaultMinKeyTTL
+	MinKeyTTL time.Duration
+
+	// ClassLimits sets a separate limit per operation class (e.g. "read", "write")
+	// for checks made with AllowClass (see ClassLimiter)
+	// Each class is tracked in its own sub-bucket under the key, so classes
+	// never consume each other's quota; the limits are not changed by SetLimit
+	// Optional: nil disables AllowClass
+	// Example: map[string]int64{"read": 1000, "write": 50}
+	ClassLimits map[string]int64
+
+	// Observer receives every Allow/AllowN decision for metrics or logging
+	// An Observer that implements FirstDenialObserver is also told when a key
+	// first goes over its limit in a window, and one that implements
+	// ResetObserver is told when a key is reset
+	// Optional: nil disables observation (no overhead)
+	Observer Observer
+
+	// MetricKeyLabel maps a raw key to a bounded label reported to the Observer
+	// It is used only for observability, never for Redis keys
+	// Optional: defaults to a constant label (DefaultMetricKeyLabel) so that
+	// high-cardinality keys (user IDs, IPs) don't explode metric series
+	// Example: func(key string) string { return strings.SplitN(key, ":", 2)[0] }
+	MetricKeyLabel func(key string) string
+
+	// LimitChangeWindow is how long after a SetLimit call decisions report
+	// Result.LimitChangedRecently
+	// Optional: 0 disables the flag
+	LimitChangeWindow time.Duration
+
+	// LimitResolver returns the limit for a specific key, e.g. a paid tier's
+	// larger quota, overriding Limit (and SetLimit) for that key; the decision's
+	// Result.Limit reports the limit actually applied
+	// It is called synchronously on the request path and must return quickly
+	// Token buckets refill at the key's limit per Window
+	// Optional: nil (or a result below 1) applies Limit to every key
+	// Applies to: TokenBucket (except AllowWithOverflow), SlidingWindow, FixedWindow
+	LimitResolver func(key string) int64
+
+	// WindowResolver returns the window for a specific key, e.g. a daily quota
+	// for one tier and an hourly one for another, overriding Window for that
+	// key; combined with LimitResolver each key gets its own limit and period
+	// The key's counters, TTLs and ResetAt follow the resolved window
+	// It is called synchronously on the request path and must return quickly,
+	// and must return the same window for a key for as long as it has state
+	// Optional: nil (or a result that isn't a positive whole number of
+	// seconds) applies Window to every key
+	// Applies to: FixedWindow (AlignedToEpoch, without KeyTimeResolution or PostResetGrace)
+	WindowResolver func(key string) time.Duration
+
+	// RequestCost returns the typical cost (n) of one request for the key,
+	// used to estimate Result.RequestsUntilDenied for clients pacing themselves
+	// It is called synchronously on the request path and must return quickly
+	// Optional: nil (or a result below 1) means every request costs 1
+	// Example: func(key string) int64 { if strings.HasPrefix(key, "upload:") { return 5 }; return 1 }
+	RequestCost func(key string) int64
+
+	// OnKeyCreated is called with the key whenever a decision creates the
+	// key's state in Redis (see Result.FirstSeen), e.g. for provisioning or metrics
+	// It is called synchronously on the request path and must return quickly
+	// Optional: nil disables the callback
+	OnKeyCreated func(key string)
+
+	// OnExhausted is called with the key and Result.ResetAt when a request is
+	// denied for being over the limit (ReasonLimitExceeded), e.g. to trigger
+	// backend scaling when quota runs out
+	// It fires at most once per key per Window, counted in Window-long
+	// periods, so sustained over-limit traffic doesn't flood it, and never
+	// for errors from an unavailable Redis
+	// It is called synchronously on the request path and must return quickly
+	// Optional: nil disables the callback
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	OnExhausted func(key string, resetAt time.Time)
+
+	// OnPanic is called with the recovered value and its stack trace when a
+	// callback above (or the limiter itself) panics during a decision, e.g.
+	// to log it; the panic doesn't reach the caller
+	// A panic in Observer, OnKeyCreated or OnExhausted leaves the decision as
+	// made; any other panic fails the decision as an unavailable Redis would:
+	// allowed with FailOpen, an error wrapping ErrPanic otherwise
+	// Optional: nil recovers panics silently
+	// Applies to: TokenBucket, SlidingWindow, FixedWindow
+	OnPanic func(recovered any, stack []byte)
+
+	// denied is the set form of Denylist, built by WithDefaults
+	denied map[string]struct{}
+
+	// scheduleNow is the clock ActiveSchedule is checked against; nil means
+	// time.Now. Tests set it to pin the time of day
+	scheduleNow func() time.Time
 }
 
 // RateLimiter is the core interface that all rate limiting algorithms implement
@@ -143,3 +579,396 @@ type RateLimiter interface {
 	//   defer limiter.Close()
 	Close() error
 }
+
+// OverflowLimiter is implemented by limiters that can consume from an ordered
+// list of buckets, spilling to the next bucket when the previous one is empty
+//
+// This models "primary then overflow" quota schemes such as an included quota
+// followed by pay-as-you-go usage.
+type OverflowLimiter interface {
+	// AllowWithOverflow tries each key in order and consumes n from the first
+	// bucket that has enough quota
+	//
+	// The check is atomic across all keys. When running against Redis Cluster
+	// the keys must hash to the same slot, e.g. by sharing a hash tag:
+	//   "{user:123}:included", "{user:123}:payg"
+	//
+	// Result.Source is set to the key that served the request. When every
+	// bucket is exhausted, Result.DeniedBy is set to the last key in the chain.
+	// Config.Denylist and Config.ActiveSchedule apply to the first key, which
+	// the decision is also counted and observed under, and
+	// Config.ReserveForCritical is held back in every bucket.
+	// Returns ErrTooManyKeys if more than MaxScriptKeys keys are given.
+	//
+	// Example:
+	//   result, err := limiter.AllowWithOverflow(ctx, []string{"{org:1}:included", "{org:1}:payg"}, 1)
+	//   if result.Allowed && result.Source == "{org:1}:payg" {
+	//       billing.RecordOverage(ctx, "org:1")
+	//   }
+	AllowWithOverflow(ctx context.Context, keys []string, n int64) (*Result, error)
+}
+
+// BurstReporter is implemented by limiters that can report the worst-case
+// burst their configuration permits, for capacity planning
+type BurstReporter interface {
+	// MaxBurst returns the theoretical maximum number of requests that could
+	// be admitted for a single key in an instant, assuming the key has been
+	// idle long enough to have its full quota available
+	MaxBurst() int64
+}
+
+// FullResetReporter is implemented by limiters that can report when a key
+// will have its full quota again, as opposed to Result.ResetAt, which is only
+// the next window boundary or refill estimate
+type FullResetReporter interface {
+	// FullResetAt returns when the key will have its full quota available if
+	// no further requests are made for it: for a token bucket when it has
+	// refilled to capacity, for a fixed window the end of the current
+	// window, and for a sliding window when the weighted count has decayed
+	// to zero. Returns the current time for a key with its full quota.
+	FullResetAt(ctx context.Context, key string) (time.Time, error)
+}
+
+// HintedLimiter is implemented by limiters that can skip the Redis round-trip
+// when the caller's own estimate shows the key is far below its limit
+//
+// This is intended for ultra-hot keys where callers already keep an
+// approximate local count (e.g. per-process counters).
+type HintedLimiter interface {
+	// AllowHinted checks n requests using localHint as the caller's estimate
+	// of how much of the key's quota is already used
+	//
+	// When localHint + n is comfortably below the limit (at most half of it),
+	// the request is allowed locally without contacting Redis and nothing is
+	// recorded in Redis; the decision is still counted and reported to the
+	// Observer. Otherwise, or when Config.ReserveForCritical holds quota back
+	// from the request or Config.ObserveFirst is set, it behaves exactly
+	// like AllowN.
+	//
+	// Accuracy tradeoff: locally served requests are invisible to other
+	// instances, so the limiter may over-admit by up to the staleness of the
+	// hint. Only use this when the hint is kept reasonably fresh.
+	AllowHinted(ctx context.Context, key string, n int64, localHint int64) (*Result, error)
+}
+
+// SlidingInspector is implemented by sliding window limiters that can expose
+// the per-bucket counts behind their weighted count, for debugging
+type SlidingInspector interface {
+	// Inspect returns the current state of the key without consuming quota
+	//
+	// This answers "why was the weighted count X": each bucket's count and
+	// the weight applied to it are returned alongside the weighted sum.
+	Inspect(ctx context.Context, key string) (*SlidingState, error)
+}
+
+// Peeker is implemented by limiters that can report a key's status without
+// consuming quota, e.g. for debugging or to show users their remaining quota
+type Peeker interface {
+	// Peek returns the key's current status as of now
+	//
+	// Allowed reports whether one more request would be allowed, and
+	// Remaining how many requests are left; no state is created or changed.
+	// A concurrent request may consume the quota between Peek and Allow.
+	Peek(ctx context.Context, key string) (*Result, error)
+}
+
+// TimelineReader is implemented by sliding window limiters that can report
+// the raw count of each recent sub-window, e.g. for dashboard sparklines
+type TimelineReader interface {
+	// Timeline returns the counts of the last buckets sub-windows for the key,
+	// oldest first and ending with the current sub-window
+	//
+	// Sub-windows are those of Config.SubWindows. Only the sub-windows that
+	// cover the window are kept in Redis, so buckets may be at most
+	// Config.SubWindows+1. The counters are read without being modified.
+	Timeline(ctx context.Context, key string, buckets int) ([]int64, error)
+}
+
+// MemoryReporter is implemented by limiters that can report how much Redis
+// memory a key's state takes, e.g. to find which algorithm or config is
+// expensive per key
+type MemoryReporter interface {
+	// MemoryUsage returns the bytes Redis reports with MEMORY USAGE for the
+	// keys holding key's current state, summed; 0 if key has no state
+	//
+	// The figure is Redis's estimate, including its per-key overhead. Returns
+	// an error wrapping ErrMemoryUsageUnavailable on Redis older than 4.0.
+	MemoryUsage(ctx context.Context, key string) (int64, error)
+}
+
+// HistoricalCounter is implemented by sliding window limiters that can report
+// how many requests a key made recently from the sub-windows they keep, e.g.
+// for "requests in the last hour" analytics without a time-series database
+type HistoricalCounter interface {
+	// HistoricalCount returns the requests counted for the key in the last
+	// since, which must be positive and at most Window
+	//
+	// The resolution is one sub-window (Window / Config.SubWindows): every
+	// sub-window that overlaps the range counts in full, so the sum may
+	// include up to one sub-window's worth of requests made before the range.
+	// The counters are read without being modified.
+	HistoricalCount(ctx context.Context, key string, since time.Duration) (int64, error)
+}
+
+// ValueLimiter is implemented by limiters that can return decisions by value
+//
+// On hot paths handling millions of requests per second, the *Result
+// allocated by Allow adds GC pressure. AllowValue returns the same decision
+// without allocating a Result on the heap.
+type ValueLimiter interface {
+	// AllowValue checks if a single request is allowed, like Allow, but
+	// returns the Result by value
+	//
+	// On error the zero Result is returned (unless the limiter fails open).
+	AllowValue(ctx context.Context, key string) (Result, error)
+
+	// AllowValueN checks if N requests are allowed, like AllowN, but returns
+	// the Result by value with every field AllowN would set, including
+	// ResetAt for this call
+	AllowValueN(ctx context.Context, key string, n int64) (Result, error)
+}
+
+// AggregateReader is implemented by limiters that can report combined quota
+// across a set of related keys, e.g. the members of a team
+type AggregateReader interface {
+	// SumRemaining returns the sum of the keys' limits (Limit, or
+	// Config.LimitResolver's override) minus the usage recorded across keys in
+	// the current window, each key's usage clamped at its limit
+	//
+	// It is read-only: no quota is consumed and no keys are created.
+	SumRemaining(ctx context.Context, keys []string) (int64, error)
+}
+
+// UsageReader is implemented by limiters that can report the quota used by
+// many keys at once, e.g. for dashboards
+type UsageReader interface {
+	// UsedMany returns the quota each key has used as of now, keyed by key
+	//
+	// Used is what Peek would report as Limit minus Remaining. Keys with no
+	// state report 0. The lookups are pipelined and read-only: no quota is
+	// consumed and no keys are created.
+	UsedMany(ctx context.Context, keys []string) (map[string]int64, error)
+}
+
+// QuotaInfo describes the static quota a limiter enforces
+type QuotaInfo struct {
+	// Algorithm is the rate limiting algorithm in use
+	Algorithm Algorithm
+
+	// Limit is the current limit, reflecting any SetLimit calls
+	Limit int64
+
+	// Window is the time window the limit applies to
+	Window time.Duration
+
+	// Prefix is the Redis key prefix
+	Prefix string
+
+	// LimitChangedAt is when SetLimit last changed the limit
+	// Zero if the limit has never changed
+	LimitChangedAt time.Time
+}
+
+// LimitSetter is implemented by limiters whose limit can be changed while
+// they are in use, e.g. by an admin API or a schedule
+type LimitSetter interface {
+	// SetLimit changes the limit applied to subsequent decisions
+	//
+	// State already stored in Redis is kept: a window counter or token bucket
+	// is judged against the new limit from the next decision on.
+	// Returns an error wrapping ErrInvalidConfig if limit <= 0.
+	SetLimit(limit int64) error
+
+	// Describe returns the quota the limiter currently enforces
+	Describe() QuotaInfo
+}
+
+// DescribingLimiter is implemented by limiters that can return a decision
+// together with the quota metadata callers need to render it, e.g. as
+// headers or JSON
+type DescribingLimiter interface {
+	// AllowDescribe checks if a single request is allowed, like Allow, and
+	// returns the quota it was checked against, like Describe
+	//
+	// QuotaInfo.Limit equals Result.Limit: the limit applied to this
+	// decision, including any Config.LimitResolver override. On error the
+	// Result is nil (unless the limiter fails open) and the QuotaInfo is
+	// still set.
+	AllowDescribe(ctx context.Context, key string) (*Result, QuotaInfo, error)
+}
+
+// Decision is a complete record of one AllowN call, its inputs and outcome,
+// for audit logs that want a single value per decision
+type Decision struct {
+	// Key is the key the request was checked for
+	Key string
+
+	// N is the number of requests that were checked
+	N int64
+
+	// Algorithm is the rate limiting algorithm that made the decision
+	Algorithm Algorithm
+
+	// Limit is the limit applied to this decision, including any
+	// Config.LimitResolver override
+	Limit int64
+
+	// Window is the time window the limit applies to
+	Window time.Duration
+
+	// Result is the decision, nil when the call failed (unless the limiter
+	// fails open)
+	Result *Result
+}
+
+// DecisionLimiter is implemented by limiters that can return a decision
+// together with its inputs as one Decision, e.g. for audit logging
+type DecisionLimiter interface {
+	// AllowNDecision checks if N requests are allowed, like AllowN, and
+	// records the call as a Decision
+	//
+	// On error the Decision is still returned, with a nil Result (unless the
+	// limiter fails open).
+	AllowNDecision(ctx context.Context, key string, n int64) (*Decision, error)
+}
+
+// GranularLimiter is implemented by sliding window limiters that let callers
+// choose how finely the window is subdivided on each call
+//
+// This allows one limiter to use coarse accounting for cheap key classes and
+// fine accounting where accuracy near the limit matters.
+type GranularLimiter interface {
+	// AllowGranular checks if N requests are allowed, dividing the window into
+	// granularity sub-windows
+	//
+	// A granularity of 1 is the classic previous/current window approximation.
+	// Counters are tracked separately per granularity, so a key should be
+	// checked with the same granularity on every call.
+	// Returns ErrInvalidGranularity if the window cannot be divided into
+	// granularity whole-second sub-windows.
+	AllowGranular(ctx context.Context, key string, n int64, granularity int) (*Result, error)
+}
+
+// ClassLimiter is implemented by limiters that can enforce separate limits per
+// operation class on one key, e.g. many reads but few writes per user
+type ClassLimiter interface {
+	// AllowClass checks if N requests of the given class are allowed for the key
+	//
+	// The limit comes from Config.ClassLimits. Each class has its own
+	// sub-bucket under the key, independent of other classes and of
+	// Allow/AllowN on the same key.
+	// Returns ErrUnknownClass if the class has no entry in Config.ClassLimits.
+	AllowClass(ctx context.Context, key string, class string, n int64) (*Result, error)
+}
+
+// ConditionalLimiter is implemented by limiters that can consume quota only
+// when the key's counter holds an expected value, for optimistic concurrency
+// with external coordination (read the count, decide, then consume only if
+// nobody else consumed in between)
+type ConditionalLimiter interface {
+	// AllowIfCount consumes n for the key only if its counter in the current
+	// window equals expectedCount and n more requests fit within the limit
+	//
+	// The returned bool reports whether n was consumed. When the counter
+	// doesn't match, nothing is consumed, Result.Allowed is false and
+	// Result.Remaining reflects the actual counter so the caller can retry.
+	// Outside Config.ActiveSchedule nothing is compared or consumed, and the
+	// call reports success.
+	AllowIfCount(ctx context.Context, key string, expectedCount, n int64) (*Result, bool, error)
+}
+
+// ResettingLimiter is implemented by limiters that can allow a request and
+// reset the key in one atomic step, e.g. for single-use actions that must
+// not be counted twice
+type ResettingLimiter interface {
+	// AllowThenReset checks a single request and, when it is allowed, resets
+	// the key so the next call starts from a fresh window
+	//
+	// When denied, nothing changes. The check and the reset are atomic, so a
+	// request counted concurrently is never lost between them.
+	AllowThenReset(ctx context.Context, key string) (*Result, error)
+}
+
+// FractionalLimiter is implemented by limiters that can charge fractional
+// costs, e.g. half a unit for a response served from cache
+type FractionalLimiter interface {
+	// AllowCostF checks if a request costing cost units is allowed for the key
+	//
+	// Fractions accumulate exactly, so two requests of 0.5 use one unit.
+	// Result.Remaining is rounded down to whole units.
+	// Returns ErrInvalidCost if cost is not a finite number greater than 0.
+	AllowCostF(ctx context.Context, key string, cost float64) (*Result, error)
+}
+
+// DebugInfoProvider is implemented by limiters that can report their live
+// parameters for a debug endpoint (see DebugHandler)
+type DebugInfoProvider interface {
+	// DebugInfo returns a snapshot of the effective configuration, derived
+	// parameters (refill rate, key TTLs, Lua script SHA1) and in-process
+	// decision counts, keyed by snake_case names
+	//
+	// It never includes Redis credentials or other secrets.
+	DebugInfo() map[string]any
+}
+
+// DeniedTracker is implemented by limiters that can report which keys are
+// denied most, e.g. to identify abusers (see Config.TrackTopDenied)
+type DeniedTracker interface {
+	// TopDenied returns up to Config.TrackTopDenied keys with the most
+	// denials recorded in process over the last one to two TopDeniedWindows,
+	// most denied first
+	//
+	// Memory is bounded by TrackTopDenied whatever the number of keys, at the
+	// cost of approximate counts: a key may be reported with more denials
+	// than it had, but a key with a large share of the denials is never
+	// missed. Returns nil when TrackTopDenied is 0.
+	TopDenied() []KeyCount
+}
+
+// Flusher is implemented by limiters that can delete all of their state,
+// e.g. for teardown in test suites sharing one Redis
+type Flusher interface {
+	// FlushAll deletes every key under the limiter's prefix, resetting every
+	// key the limiter (and any other limiter sharing the prefix) has seen
+	//
+	// DANGER: this is meant for tests. It deletes data that may not be the
+	// limiter's own if other code writes keys under the same prefix, and
+	// resets every client's quota in production. Keys are found with SCAN
+	// and deleted in batches, never with FLUSHDB, so keys outside the prefix
+	// are kept; it is not atomic, so keys written meanwhile may survive.
+	// Returns ErrInvalidConfig for an empty prefix, which would match every
+	// key in the database.
+	FlushAll(ctx context.Context) error
+}
+
+// Reconciler is implemented by limiters that can settle the cost of a
+// request after it was allowed, for APIs whose true cost is only known once
+// the request has been served (e.g. the number of rows returned)
+type Reconciler interface {
+	// Reconcile settles a request allowed with a plain Allow, i.e. at
+	// ProvisionalCost, at its actual cost: the key is charged
+	// actualCost-ProvisionalCost more, or refunded the difference when
+	// actualCost is lower. The adjustment is atomic but applies to the
+	// key's current window or bucket, so a request reconciled after its
+	// window ended is charged to the next one.
+	//
+	// Usage never goes below zero, and a token bucket never holds more
+	// than its capacity or less than zero tokens, so a cost beyond what
+	// the key has left is charged only in part. Returns ErrInvalidCost for
+	// a negative actualCost.
+	Reconcile(ctx context.Context, key string, actualCost int64) error
+}
+
+// KeyCounter is implemented by limiters that can report how many Redis keys
+// they hold, e.g. for capacity planning
+type KeyCounter interface {
+	// StartKeyCounter counts the keys under the limiter's prefix every
+	// interval and sends each count on the returned channel
+	//
+	// Keys are counted with SCAN in bounded batches, so counting never blocks
+	// Redis. The channel holds only the latest count; scans that fail are
+	// skipped. The returned func stops the counter and closes the channel;
+	// cancelling ctx stops it too. Every key under the prefix is counted, so
+	// limiters sharing a prefix are counted together.
+	StartKeyCounter(ctx context.Context, interval time.Duration) (func(), <-chan int)
+}
@@ -0,0 +1,98 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *KeyBuilder
+		expected string
+	}{
+		{
+			name:     "empty",
+			builder:  Key(),
+			expected: "",
+		},
+		{
+			name:     "single dimension",
+			builder:  Key().Dim("user", "123"),
+			expected: "user:123",
+		},
+		{
+			name:     "multiple dimensions",
+			builder:  Key().Dim("region", "eu").Dim("user", "123"),
+			expected: "region:eu:user:123",
+		},
+		{
+			name:     "dimension and part",
+			builder:  Key().Dim("user", "123").Part("upload"),
+			expected: "user:123:upload",
+		},
+		{
+			name:     "separator in value is escaped",
+			builder:  Key().Dim("user", "a:b"),
+			expected: `user:a\:b`,
+		},
+		{
+			name:     "escape character is escaped",
+			builder:  Key().Dim("user", `a\`),
+			expected: `user:a\\`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.builder.Build())
+			assert.Equal(t, tt.expected, tt.builder.String())
+		})
+	}
+}
+
+func TestKeyBuilder_NoCollisions(t *testing.T) {
+	// A value containing the separator must not look like two segments
+	assert.NotEqual(t, Key().Part("a:b").Build(), Key().Part("a").Part("b").Build())
+	assert.NotEqual(t,
+		Key().Dim("region", "eu:user").Dim("x", "1").Build(),
+		Key().Dim("region", "eu").Dim("user:x", "1").Build(),
+	)
+
+	// Escaping the escape character keeps "a\" + "b" apart from "a\:b"
+	assert.NotEqual(t, Key().Part(`a\`).Part("b").Build(), Key().Part(`a:b`).Build())
+}
+
+func TestAllowKey(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := AllowKey(ctx, limiter, Key().Dim("region", "eu").Dim("user", "123"))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Same dimensions share a counter with the equivalent string key
+	result, err = limiter.Allow(ctx, "region:eu:user:123")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	_, err = AllowKey(ctx, limiter, Key())
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = AllowKeyN(ctx, limiter, nil, 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
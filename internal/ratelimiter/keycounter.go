@@ -0,0 +1,89 @@
+package ratelimiter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyCounterBatch is the COUNT hint passed to each SCAN call made by
+// StartKeyCounter, bounding the work Redis does per call.
+const keyCounterBatch = 100
+
+// keyCounterMinInterval is the shortest interval between key counts, so a
+// tiny or non-positive interval cannot keep Redis busy with SCANs.
+const keyCounterMinInterval = 10 * time.Millisecond
+
+// startKeyCounter counts the keys matching pattern every interval with SCAN
+// and sends each count on the returned channel, which holds only the latest
+// count so a slow reader never blocks the scan. The returned stop func ends
+// the counter, waits for it to exit and closes the channel; it may be called
+// more than once. The counter also stops when ctx is cancelled.
+func startKeyCounter(ctx context.Context, client *redis.Client, pattern string, interval time.Duration) (func(), <-chan int) {
+	interval = max(interval, keyCounterMinInterval)
+	ctx, cancel := context.WithCancel(ctx)
+	counts := make(chan int, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(counts)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if count, err := scanCount(ctx, client, pattern); err == nil {
+				// Replace a count the reader hasn't taken yet with the newer one
+				select {
+				case <-counts:
+				default:
+				}
+				counts <- count
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	return stop, counts
+}
+
+// scanCount counts the keys matching pattern with SCAN, which iterates in
+// batches instead of blocking Redis like KEYS would.
+func scanCount(ctx context.Context, client *redis.Client, pattern string) (int, error) {
+	count := 0
+	iter := client.Scan(ctx, 0, pattern, keyCounterBatch).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	return count, iter.Err()
+}
+
+// keyPattern returns the SCAN pattern matching every key a limiter with
+// config creates. With an empty prefix it matches every key in the database.
+// Glob characters in the prefix are escaped, so they match only themselves.
+func (c *Config) keyPattern() string {
+	prefix := c.KeyPrefix()
+	if prefix == "" {
+		return "*"
+	}
+	return globEscaper.Replace(prefix) + ":*"
+}
+
+// globEscaper escapes the characters Redis treats specially in SCAN patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
@@ -0,0 +1,83 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartKeyCounter(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := range 3 {
+		_, err := limiter.Allow(ctx, "user:"+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	// Keys of other prefixes are not counted
+	mr.Set("other:key", "1")
+
+	stop, counts := limiter.(KeyCounter).StartKeyCounter(ctx, 10*time.Millisecond)
+
+	select {
+	case count := <-counts:
+		assert.Equal(t, 3, count)
+	case <-time.After(time.Second):
+		t.Fatal("no count received")
+	}
+
+	for i := 3; i < 250; i++ {
+		_, err := limiter.Allow(ctx, "user:"+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+
+	// Counting spans several SCAN batches
+	assert.Eventually(t, func() bool {
+		return <-counts == 250
+	}, time.Second, time.Millisecond)
+
+	stop()
+	stop() // Stopping twice is safe
+
+	// The channel is closed once the counter stopped
+	for range counts {
+	}
+}
+
+func TestStartKeyCounter_StopsWithContext(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop, counts := limiter.(KeyCounter).StartKeyCounter(ctx, time.Hour)
+	defer stop()
+
+	assert.Equal(t, 0, <-counts)
+	cancel()
+
+	select {
+	case _, ok := <-counts:
+		assert.False(t, ok, "the channel should be closed after cancellation")
+	case <-time.After(time.Second):
+		t.Fatal("counter did not stop when the context was cancelled")
+	}
+}
@@ -0,0 +1,30 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keyTypeError turns a Redis WRONGTYPE error into one wrapping
+// ErrKeyTypeMismatch that explains the likely cause. Other errors are
+// returned unchanged.
+//
+// The algorithms store state in different Redis types (counters are strings,
+// token buckets are hashes), so a WRONGTYPE error means a key written by one
+// algorithm was read by another: two limiters sharing a prefix and key.
+func keyTypeError(err error, algorithm Algorithm) error {
+	// Inside a script the error may be wrapped, e.g. "ERR Error running script ...: WRONGTYPE ..."
+	if err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		return err
+	}
+	return fmt.Errorf("%w: %s limiter found state of another type (%v); limiters with different algorithms must use distinct prefixes, or set Config.TagKeysWithAlgorithm", ErrKeyTypeMismatch, algorithm, err)
+}
+
+// misconfigured reports whether err points at a misconfiguration rather
+// than an outage: a CROSSSLOT error (keys in different cluster slots) or a
+// WRONGTYPE one (state written by another algorithm). Failing open would
+// hide these for good, so they are surfaced even with Config.FailOpen.
+func misconfigured(err error) bool {
+	return errors.Is(err, ErrCrossSlot) || errors.Is(err, ErrKeyTypeMismatch)
+}
@@ -0,0 +1,182 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyTypeError(t *testing.T) {
+	assert.Nil(t, keyTypeError(nil, FixedWindow))
+
+	other := errors.New("connection refused")
+	assert.Equal(t, other, keyTypeError(other, FixedWindow))
+
+	err := keyTypeError(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"), TokenBucket)
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "token_bucket limiter")
+	assert.ErrorContains(t, err, "TagKeysWithAlgorithm")
+	assert.True(t, misconfigured(err))
+}
+
+func TestKeyTypeMismatch_CrossAlgorithmCollision(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// Windowed token buckets and fixed windows both key state by window start
+	fixed, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	bucket, err := NewTokenBucket(client, &Config{
+		Algorithm:     TokenBucket,
+		Limit:         10,
+		Window:        time.Hour,
+		WindowedState: true,
+		FailOpen:      true,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = fixed.Allow(ctx, "user:1")
+	require.NoError(t, err)
+
+	// The bucket finds the fixed window's counter, and says so even though it
+	// fails open, since retrying can't fix a misconfiguration
+	result, err := bucket.Allow(ctx, "user:1")
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.NotErrorIs(t, err, ErrStorageUnavailable)
+
+	var backendErr *BackendError
+	assert.False(t, errors.As(err, &backendErr), "a misconfiguration is not a backend failure")
+
+	// And the other way around
+	_, err = bucket.Allow(ctx, "user:2")
+	require.NoError(t, err)
+	_, err = fixed.Allow(ctx, "user:2")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "fixed_window limiter")
+}
+
+func TestTagKeysWithAlgorithm(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	fixed, err := NewFixedWindow(client, &Config{
+		Algorithm:            FixedWindow,
+		Limit:                10,
+		Window:               time.Hour,
+		TagKeysWithAlgorithm: true,
+	})
+	require.NoError(t, err)
+	bucket, err := NewTokenBucket(client, &Config{
+		Algorithm:            TokenBucket,
+		Limit:                10,
+		Window:               time.Hour,
+		WindowedState:        true,
+		TagKeysWithAlgorithm: true,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	// Each limiter keeps its own state for the same key
+	for i := range int64(2) {
+		for _, limiter := range []RateLimiter{fixed, bucket} {
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, 9-i, result.Remaining)
+		}
+	}
+
+	hour := time.Now().Truncate(time.Hour).Unix()
+	assert.True(t, mr.Exists(fmt.Sprintf("ratelimit:fixed_window:user:1:%d", hour)))
+	assert.True(t, mr.Exists(fmt.Sprintf("ratelimit:token_bucket:user:1:%d", hour)))
+}
+
+func TestTagKeysWithAlgorithm_WindowAlgorithms(t *testing.T) {
+	// Both window algorithms keep plain counters under the window start, so
+	// untagged they share them without any error
+	for _, tagged := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tagged=%v", tagged), func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+
+			config := func(algorithm Algorithm) *Config {
+				return &Config{Algorithm: algorithm, Limit: 10, Window: time.Hour, TagKeysWithAlgorithm: tagged}
+			}
+			fixed, err := NewFixedWindow(client, config(FixedWindow))
+			require.NoError(t, err)
+			sliding, err := NewSlidingWindow(client, config(SlidingWindow))
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			_, err = fixed.AllowN(ctx, "user:1", 4)
+			require.NoError(t, err)
+			result, err := sliding.Allow(ctx, "user:1")
+			require.NoError(t, err)
+
+			if tagged {
+				assert.Equal(t, int64(9), result.Remaining)
+			} else {
+				assert.Equal(t, int64(5), result.Remaining, "the sliding window counts the fixed window's requests")
+			}
+		})
+	}
+}
+
+func TestKeyTypeMismatch_TokenBucketOnStringKey(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	bucket, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	// A counter left under the bucket's key, e.g. by a fixed window sharing the prefix
+	require.NoError(t, mr.Set(bucket.(*tokenBucketLimiter).config.FormatKey("user:1"), "3"))
+
+	ctx := context.Background()
+	_, err = bucket.Allow(ctx, "user:1")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "token_bucket limiter found state of another type")
+	assert.ErrorContains(t, err, "distinct prefixes")
+
+	// Reading and reconciling the bucket explain the clash the same way
+	_, err = bucket.(Peeker).Peek(ctx, "user:1")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	err = bucket.(Reconciler).Reconcile(ctx, "user:1", 2)
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+}
+
+func TestKeyTypeMismatch_Hierarchical(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 10, Window: time.Hour, Prefix: "user", FailOpen: true},
+		{Algorithm: FixedWindow, Limit: 100, Window: time.Hour, Prefix: "org", FailOpen: true},
+	})
+	require.NoError(t, err)
+
+	// A bucket left under the org level's key, e.g. by a token bucket sharing the prefix
+	h := limiter.(*hierarchicalLimiter)
+	orgKey := h.formatKey(h.levels[1], "org:1", time.Now().Truncate(time.Hour).Unix())
+	mr.HSet(orgKey, "tokens", "5")
+
+	// Surfaced even though the levels fail open
+	_, err = limiter.Allow(context.Background(), []string{"user:1", "org:1"})
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "fixed_window limiter found state of another type")
+}
@@ -0,0 +1,44 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryUsage sums what Redis reports with MEMORY USAGE for keys, counting
+// keys that don't exist as 0 bytes.
+func memoryUsage(ctx context.Context, client *redis.Client, keys []string) (int64, error) {
+	pipe := client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.MemoryUsage(ctx, key)
+	}
+	// Exec reports the first failed command; each one is checked below
+	_, _ = pipe.Exec(ctx)
+
+	var total int64
+	for _, cmd := range cmds {
+		bytes, err := cmd.Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			continue
+		case err != nil && memoryUsageUnsupported(err):
+			return 0, fmt.Errorf("%w: %v", ErrMemoryUsageUnavailable, err)
+		case err != nil:
+			return 0, fmt.Errorf("failed to read memory usage: %w", err)
+		}
+		total += bytes
+	}
+	return total, nil
+}
+
+// memoryUsageUnsupported reports whether err is Redis rejecting MEMORY USAGE
+// itself, as servers older than Redis 4.0 do.
+func memoryUsageUnsupported(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unknown command") || strings.Contains(msg, "unknown subcommand")
+}
@@ -0,0 +1,49 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     10,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		reporter := limiter.(MemoryReporter)
+
+		_, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+
+		bytes, err := reporter.MemoryUsage(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Positive(t, bytes)
+
+		bytes, err = reporter.MemoryUsage(ctx, "user:2")
+		require.NoError(t, err)
+		assert.Zero(t, bytes)
+
+		_, err = reporter.MemoryUsage(ctx, "")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+}
+
+func TestMemoryUsageUnsupported(t *testing.T) {
+	assert.True(t, memoryUsageUnsupported(errors.New("ERR unknown command 'MEMORY'")))
+	assert.True(t, memoryUsageUnsupported(errors.New("ERR Unknown subcommand or wrong number of arguments for 'USAGE'")))
+	assert.False(t, memoryUsageUnsupported(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")))
+}
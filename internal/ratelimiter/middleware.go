@@ -0,0 +1,91 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// middlewareOptions holds the settings applied by MiddlewareOptions.
+type middlewareOptions struct {
+	// redirectURL is where denied HTML requests are sent, "" to never redirect
+	redirectURL string
+}
+
+// MiddlewareOption configures the handler returned by Middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+// WithRedirectOnDeny makes denied requests from browsers, those whose Accept
+// header prefers HTML, get a 303 See Other redirect to url (e.g. a "slow
+// down" page) instead of a 429. API clients still get the 429 JSON response.
+func WithRedirectOnDeny(url string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.redirectURL = url
+	}
+}
+
+// deniedBody is the JSON body of a 429 response.
+type deniedBody struct {
+	Error             string `json:"error"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+}
+
+// Middleware returns HTTP middleware that rate limits requests with limiter,
+// keyed by keyFn, using CheckHTTP. Every response carries the rate limit
+// headers. Denied requests get a 429 with a JSON body, and requests that
+// can't be checked get the status CheckHTTP reports.
+func Middleware(limiter RateLimiter, keyFn func(*http.Request) string, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	var options middlewareOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, headers, status, err := CheckHTTP(r.Context(), limiter, r, keyFn)
+			for name, values := range headers {
+				w.Header()[name] = values
+			}
+			switch {
+			case err != nil:
+				http.Error(w, http.StatusText(status), status)
+			case allowed:
+				next.ServeHTTP(w, r)
+			case options.redirectURL != "" && prefersHTML(r):
+				http.Redirect(w, r, options.redirectURL, http.StatusSeeOther)
+			default:
+				body := deniedBody{Error: "rate limit exceeded"}
+				body.RetryAfterSeconds, _ = strconv.ParseInt(headers.Get(HeaderRetryAfter), 10, 64)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_ = json.NewEncoder(w).Encode(body)
+			}
+		})
+	}
+}
+
+// prefersHTML reports whether the request's Accept header asks for HTML
+// explicitly and ranks it at least as high as JSON, as browser navigations
+// do. A missing Accept header or */* alone counts as an API client.
+func prefersHTML(r *http.Request) bool {
+	var htmlQuality, jsonQuality float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			htmlQuality = max(htmlQuality, quality)
+		case "application/json":
+			jsonQuality = max(jsonQuality, quality)
+		}
+	}
+	return htmlQuality > 0 && htmlQuality >= jsonQuality
+}
@@ -0,0 +1,73 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestMiddleware_RedirectOnDeny(t *testing.T) {
+	limiter := &scriptedLimiter{decisions: []bool{false}}
+	served := false
+	handler := Middleware(limiter, userKey, WithRedirectOnDeny("/slow-down"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served = true }))
+
+	tests := []struct {
+		name   string
+		accept string
+		status int
+	}{
+		{"browser", browserAccept, http.StatusSeeOther},
+		{"api", "application/json", http.StatusTooManyRequests},
+		{"api preferring json", "application/json, text/html;q=0.5", http.StatusTooManyRequests},
+		{"any type", "*/*", http.StatusTooManyRequests},
+		{"no accept header", "", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/page", nil)
+			r.Header.Set("X-User-ID", "user:1")
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "10", w.Header().Get(HeaderLimit), "rate limit headers are always set")
+			if tt.status == http.StatusSeeOther {
+				assert.Equal(t, "/slow-down", w.Header().Get("Location"))
+				return
+			}
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			var body deniedBody
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "rate limit exceeded", body.Error)
+		})
+	}
+	assert.False(t, served, "denied requests must not reach the handler")
+}
+
+func TestMiddleware_DeniedWithoutRedirect(t *testing.T) {
+	handler := Middleware(&scriptedLimiter{decisions: []bool{true, false}}, userKey)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-ID", "user:1")
+	r.Header.Set("Accept", browserAccept)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "browsers get a 429 unless a redirect is configured")
+}
@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+)
+
+// noopLimiter is a RateLimiter that allows every request.
+// It never touches Redis and holds no resources.
+type noopLimiter struct{}
+
+// NewNoop creates a RateLimiter that always allows requests.
+//
+// Use it when rate limiting is disabled (e.g. behind a feature flag) so that
+// call sites don't need to branch on a nil limiter. Results report
+// math.MaxInt64 for Limit and Remaining.
+func NewNoop() RateLimiter {
+	return noopLimiter{}
+}
+
+// Allow always allows the request.
+func (noopLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return noopResult(), nil
+}
+
+// AllowN always allows the requests, but still rejects invalid n.
+func (noopLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	return noopResult(), nil
+}
+
+// Reset is a no-op.
+func (noopLimiter) Reset(ctx context.Context, key string) error {
+	return nil
+}
+
+// MaxBurst returns math.MaxInt64 since no request is ever denied.
+func (noopLimiter) MaxBurst() int64 {
+	return math.MaxInt64
+}
+
+// Close is a no-op.
+func (noopLimiter) Close() error {
+	return nil
+}
+
+// noopResult returns an allowed Result with unlimited quota.
+func noopResult() *Result {
+	return &Result{
+		Allowed:   true,
+		Limit:     math.MaxInt64,
+		Remaining: math.MaxInt64,
+		Atomic:    true,
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoop_AlwaysAllows(t *testing.T) {
+	limiter := NewNoop()
+	ctx := context.Background()
+
+	for i := 0; i < 1000; i++ {
+		result, err := limiter.Allow(ctx, "user:123")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(math.MaxInt64), result.Remaining)
+		assert.Zero(t, result.RetryAfter)
+	}
+
+	result, err := limiter.AllowN(ctx, "user:123", math.MaxInt64)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestNoop_AllowN_InvalidTokens(t *testing.T) {
+	limiter := NewNoop()
+	ctx := context.Background()
+
+	for _, n := range []int64{0, -1, -100} {
+		result, err := limiter.AllowN(ctx, "user:123", n)
+		assert.ErrorIs(t, err, ErrInvalidN)
+		assert.Nil(t, result)
+	}
+}
+
+func TestNoop_ResetAndClose(t *testing.T) {
+	limiter := NewNoop()
+
+	assert.NoError(t, limiter.Reset(context.Background(), "user:123"))
+	assert.NoError(t, limiter.Close())
+
+	// Still usable after Close since it holds no resources
+	result, err := limiter.Allow(context.Background(), "user:123")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestNoop_InterfaceContract(t *testing.T) {
+	// Verify that noopLimiter implements RateLimiter interface
+	var _ RateLimiter = noopLimiter{}
+}
@@ -0,0 +1,137 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultMetricKeyLabel is the label reported to observers when
+	// Config.MetricKeyLabel is not set. A constant keeps metric cardinality
+	// bounded no matter how many distinct keys are rate limited.
+	DefaultMetricKeyLabel = "all"
+)
+
+// Observer receives rate limit decisions for metrics, logging, or tracing.
+//
+// Implementations must be safe for concurrent use and should return quickly,
+// since they are called synchronously on the request path.
+type Observer interface {
+	// ObserveDecision is called once for every Allow/AllowN call, including
+	// calls that return an error.
+	ObserveDecision(ctx context.Context, obs Observation)
+}
+
+// FirstDenialObserver is implemented by an Observer that also wants to know
+// the moment a key is first blocked in a window, e.g. for an abuse dashboard,
+// rather than every denial that follows.
+type FirstDenialObserver interface {
+	// OnFirstDenial is called with the key and the decision when a request
+	// takes the key over its limit for the first time in a window; further
+	// over-limit requests in the same window don't call it again.
+	// FixedWindow limiters report first denials whatever their Alignment,
+	// as do hierarchical limiters for each level. SlidingWindow and
+	// TokenBucket limiters have no window for a denial to be first in and
+	// never call it.
+	OnFirstDenial(key string, result *Result)
+}
+
+// ResetObserver is implemented by an Observer that also wants to know when a
+// key's quota is reset, e.g. to audit manual resets.
+type ResetObserver interface {
+	// ObserveReset is called with the key after Reset has cleared its state.
+	// Failed resets are not reported.
+	ObserveReset(ctx context.Context, key string)
+}
+
+// Observation describes a single rate limit decision.
+type Observation struct {
+	// Algorithm is the algorithm of the limiter that made the decision.
+	Algorithm Algorithm
+
+	// KeyLabel is the bounded label derived from the request key via
+	// Config.MetricKeyLabel. The raw key is intentionally not exposed so
+	// that metric labels cannot explode in cardinality.
+	KeyLabel string
+
+	// N is the number of requests that were checked.
+	N int64
+
+	// Result is the decision, nil when Err is non-nil and the limiter fails closed.
+	Result *Result
+
+	// Err is the error returned to the caller, if any.
+	Err error
+
+	// Duration is the wall time spent making the decision.
+	Duration time.Duration
+
+	// TraceID is the trace ID carried by the request's context (see
+	// WithTraceID), empty if none. Metrics observers can attach it as an
+	// exemplar to link a slow or denied decision to its trace.
+	TraceID string
+}
+
+// traceIDKey is the context key holding a request's trace ID
+type traceIDKey struct{}
+
+// WithTraceID returns a copy of ctx carrying traceID, which is reported to
+// the Observer as Observation.TraceID.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID set with WithTraceID, empty if none.
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// MetricLabel maps a raw key to the label used in metrics.
+// Falls back to DefaultMetricKeyLabel when no mapping is configured.
+func (c *Config) MetricLabel(key string) string {
+	if c == nil || c.MetricKeyLabel == nil {
+		return DefaultMetricKeyLabel
+	}
+	return c.MetricKeyLabel(key)
+}
+
+// observeDecision reports a decision to the configured Observer.
+func (c *Config) observeDecision(ctx context.Context, key string, n int64, start time.Time, result *Result, err error) {
+	defer c.recoverCallback()
+	c.Observer.ObserveDecision(ctx, Observation{
+		Algorithm: c.Algorithm,
+		KeyLabel:  c.MetricLabel(key),
+		N:         n,
+		Result:    result,
+		Err:       err,
+		Duration:  time.Since(start),
+		TraceID:   TraceIDFromContext(ctx),
+	})
+}
+
+// notifyFirstDenial calls Config.Observer's OnFirstDenial, if it implements
+// FirstDenialObserver, for the request that first took key over its limit.
+func (c *Config) notifyFirstDenial(key string, result *Result) {
+	if observer, ok := c.Observer.(FirstDenialObserver); ok {
+		defer c.recoverCallback()
+		observer.OnFirstDenial(key, result)
+	}
+}
+
+// observeReset calls Config.Observer's ObserveReset, if it implements
+// ResetObserver, for a key whose state was just reset.
+func (c *Config) observeReset(ctx context.Context, key string) {
+	if observer, ok := c.Observer.(ResetObserver); ok {
+		defer c.recoverCallback()
+		observer.ObserveReset(ctx, key)
+	}
+}
+
+// notifyKeyCreated calls Config.OnKeyCreated, if set, for a key whose state was just created.
+func (c *Config) notifyKeyCreated(key string) {
+	if c.OnKeyCreated != nil {
+		defer c.recoverCallback()
+		c.OnKeyCreated(key)
+	}
+}
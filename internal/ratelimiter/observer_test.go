@@ -0,0 +1,287 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records every observation it receives
+type recordingObserver struct {
+	mu           sync.Mutex
+	observations []Observation
+}
+
+func (r *recordingObserver) ObserveDecision(_ context.Context, obs Observation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, obs)
+}
+
+func (r *recordingObserver) all() []Observation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Observation(nil), r.observations...)
+}
+
+func TestObserver_LabelsByMappedKey(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	config := &Config{
+		Algorithm: FixedWindow,
+		Limit:     1,
+		Window:    time.Minute,
+		Observer:  observer,
+		MetricKeyLabel: func(key string) string {
+			return strings.SplitN(key, ":", 2)[0]
+		},
+	}
+
+	limiter, err := NewFixedWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	_, err = limiter.Allow(ctx, "user:123")
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:123")
+	require.NoError(t, err)
+	_, err = limiter.AllowN(ctx, "ip:10.0.0.1", 1)
+	require.NoError(t, err)
+
+	observations := observer.all()
+	require.Len(t, observations, 3)
+
+	assert.Equal(t, "user", observations[0].KeyLabel)
+	assert.Equal(t, FixedWindow, observations[0].Algorithm)
+	assert.Equal(t, int64(1), observations[0].N)
+	assert.True(t, observations[0].Result.Allowed)
+
+	assert.Equal(t, "user", observations[1].KeyLabel)
+	assert.False(t, observations[1].Result.Allowed)
+
+	assert.Equal(t, "ip", observations[2].KeyLabel)
+
+	for _, obs := range observations {
+		assert.NotContains(t, obs.KeyLabel, "123")
+		assert.NoError(t, obs.Err)
+	}
+}
+
+func TestObserver_DefaultLabelIsConstant(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Minute,
+		Observer:  observer,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"user:1", "user:2", "ip:10.0.0.1"} {
+		_, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+	}
+
+	observations := observer.all()
+	require.Len(t, observations, 3)
+	for _, obs := range observations {
+		assert.Equal(t, DefaultMetricKeyLabel, obs.KeyLabel)
+		assert.Equal(t, TokenBucket, obs.Algorithm)
+	}
+}
+
+func TestObserver_ObservesErrors(t *testing.T) {
+	client, mr := setupMiniredis(t)
+
+	observer := &recordingObserver{}
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Minute,
+		Observer:  observer,
+	}
+
+	limiter, err := NewSlidingWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// Close Redis to simulate failure (fail-closed)
+	mr.Close()
+
+	result, err := limiter.Allow(context.Background(), "user:down")
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	observations := observer.all()
+	require.Len(t, observations, 1)
+	assert.Error(t, observations[0].Err)
+	assert.Nil(t, observations[0].Result)
+}
+
+func TestObserver_ReportsTraceID(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Observer:  observer,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.Allow(WithTraceID(ctx, "4bf92f3577b34da6a3ce929d0e0e4736"), "user:1")
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+
+	observations := observer.all()
+	require.Len(t, observations, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", observations[0].TraceID)
+	assert.Empty(t, observations[1].TraceID, "no trace ID in the context")
+}
+
+// firstDenialObserver records the keys reported to OnFirstDenial
+type firstDenialObserver struct {
+	recordingObserver
+	firstDenials []string
+}
+
+func (f *firstDenialObserver) OnFirstDenial(key string, result *Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.firstDenials = append(f.firstDenials, key)
+}
+
+func TestObserver_OnFirstDenial(t *testing.T) {
+	for _, tt := range []struct {
+		alignment  WindowAlignment
+		capCounter bool
+	}{
+		{AlignedToEpoch, false},
+		{AlignedToEpoch, true},
+		{AlignedToFirstRequest, false},
+		{AlignedToFirstRequest, true},
+	} {
+		t.Run(fmt.Sprintf("%s cap counter %v", tt.alignment, tt.capCounter), func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+
+			observer := &firstDenialObserver{}
+			limiter, err := NewFixedWindow(client, &Config{
+				Algorithm:         FixedWindow,
+				Limit:             3,
+				Window:            time.Hour,
+				Alignment:         tt.alignment,
+				CapCounterAtLimit: tt.capCounter,
+				Observer:          observer,
+			})
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			ctx := context.Background()
+			for i := range 6 {
+				result, err := limiter.Allow(ctx, "user:1")
+				require.NoError(t, err)
+				assert.Equal(t, i < 3, result.Allowed)
+
+				// Only the request crossing the limit is reported
+				want := 0
+				if i >= 3 {
+					want = 1
+				}
+				assert.Len(t, observer.firstDenials, want, "after request %d", i+1)
+			}
+			assert.Equal(t, []string{"user:1"}, observer.firstDenials)
+			assert.Len(t, observer.all(), 6)
+		})
+	}
+}
+
+// resetObserver records the keys reported to ObserveReset
+type resetObserver struct {
+	recordingObserver
+	resets []string
+}
+
+func (r *resetObserver) ObserveReset(_ context.Context, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resets = append(r.resets, key)
+}
+
+func TestObserver_ObserveReset(t *testing.T) {
+	type resetter interface {
+		Reset(ctx context.Context, key string) error
+		Close() error
+	}
+	algorithms := []struct {
+		algorithm Algorithm
+		create    func(*redis.Client, *Config) (resetter, error)
+	}{
+		{FixedWindow, func(c *redis.Client, cfg *Config) (resetter, error) { return NewFixedWindow(c, cfg) }},
+		{SlidingWindow, func(c *redis.Client, cfg *Config) (resetter, error) { return NewSlidingWindow(c, cfg) }},
+		{TokenBucket, func(c *redis.Client, cfg *Config) (resetter, error) { return NewTokenBucket(c, cfg) }},
+		{Concurrency, func(c *redis.Client, cfg *Config) (resetter, error) { return NewConcurrency(c, cfg) }},
+	}
+
+	for _, tt := range algorithms {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			client, mr := setupMiniredis(t)
+			defer mr.Close()
+
+			observer := &resetObserver{}
+			limiter, err := tt.create(client, &Config{
+				Algorithm: tt.algorithm,
+				Limit:     5,
+				Window:    time.Minute,
+				Observer:  observer,
+			})
+			require.NoError(t, err)
+			defer limiter.Close()
+
+			ctx := context.Background()
+			require.NoError(t, limiter.Reset(ctx, "user:1"))
+			require.NoError(t, limiter.Reset(ctx, "user:2"))
+			assert.Equal(t, []string{"user:1", "user:2"}, observer.resets)
+			assert.Empty(t, observer.all(), "a reset is not a decision")
+		})
+	}
+
+	t.Run("failed reset", func(t *testing.T) {
+		client, _ := unreachableClient(t)
+		observer := &resetObserver{}
+		limiter, err := NewFixedWindow(client, &Config{
+			Algorithm: FixedWindow,
+			Limit:     5,
+			Window:    time.Minute,
+			Observer:  observer,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		require.Error(t, limiter.Reset(context.Background(), "user:1"))
+		assert.Empty(t, observer.resets)
+	})
+}
@@ -0,0 +1,305 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ParentKey is the key a parent/child limiter (see NewHierarchicalLimiter)
+// charges the parent under, so that every child shares one parent quota.
+const ParentKey = "global"
+
+// maxChildLimiters is the number of child limiters a parent/child limiter
+// keeps; the least recently used one is dropped to make room for a new key.
+const maxChildLimiters = 10000
+
+// childEntry is a cached child limiter and the key it was created for.
+type childEntry struct {
+	key     string
+	limiter RateLimiter
+}
+
+// parentChildLimiter charges every request to a per-key child limiter and to
+// a shared parent limiter.
+type parentChildLimiter struct {
+	parent       RateLimiter
+	childFactory func(key string) RateLimiter
+
+	mu       sync.Mutex
+	children map[string]*list.Element // Of *childEntry, in recency order
+	recent   *list.List
+}
+
+// NewHierarchicalLimiter returns a RateLimiter that allows a request only if
+// both the key's child limiter and the parent limiter have room for it, e.g.
+// per-user limits under a global limit. The parent is charged under ParentKey,
+// the child under the request's key.
+//
+// childFactory is called for a key on first use. The limiters of the most
+// recently used keys (up to 10000) are kept and closed by Close, along with
+// the parent; beyond that the least recently used child is dropped without
+// Close, and the factory is called again if its key comes back. Its state
+// lives in Redis, so dropping it loses nothing, but the factory should build
+// children on a shared Redis client rather than one per key.
+//
+// When the parent and child are fixed window limiters on the same Redis
+// client (with windows aligned to the epoch, and without MinInterval,
+// KeyTimeResolution, PostResetGrace, WindowResolver, ReserveForCritical or
+// ObserveFirst), both are checked and charged by one Lua script, so a denial
+// consumes nothing. Each level's LimitResolver, Denylist and ActiveSchedule
+// still apply, the decision is reported to both levels' Observers (first
+// denials included), and new counters to their OnKeyCreated. On Redis
+// Cluster their keys must then hash to the same slot. Other limiters
+// from this package are charged one after the other, and the first charge is
+// refunded when the second level denies the request (or fails), so a denial
+// consumes nothing once the refund lands. When neither level can be refunded
+// (limiters from other packages), both are checked with Peek first when they
+// implement Peeker and then charged child first; a request racing for the
+// last unit of the parent can then leave the child charged although it was
+// denied.
+//
+// For fixed multi-level quotas where every level is known up front, see
+// NewHierarchical.
+func NewHierarchicalLimiter(parent RateLimiter, childFactory func(key string) RateLimiter) RateLimiter {
+	return &parentChildLimiter{
+		parent:       parent,
+		childFactory: childFactory,
+		children:     make(map[string]*list.Element),
+		recent:       list.New(),
+	}
+}
+
+// Allow checks a single request against the child and the parent.
+func (p *parentChildLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return p.AllowN(ctx, key, 1)
+}
+
+// AllowN checks N requests against the child and the parent, charging both
+// or, where possible, neither.
+func (p *parentChildLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	child := p.child(key)
+	if levels, ok := sharedFixedWindows(child, p.parent); ok {
+		return allowSharedFixedWindows(ctx, levels, [2]string{key, ParentKey}, n)
+	}
+
+	// Charge a level that can be refunded first, so that a denial by the
+	// other one can be undone
+	first, second := chargedLevel{child, key}, chargedLevel{p.parent, ParentKey}
+	refunder, refundable := child.(adjuster)
+	if !refundable {
+		if refunder, refundable = p.parent.(adjuster); refundable {
+			first, second = second, first
+		}
+	}
+	if !refundable {
+		// Check both before charging either, so that a denial by the parent
+		// usually leaves the child untouched
+		for _, level := range []chargedLevel{first, second} {
+			peeker, ok := level.limiter.(Peeker)
+			if !ok {
+				continue
+			}
+			result, err := peeker.Peek(ctx, level.key)
+			if err != nil {
+				return nil, err
+			}
+			if result.Remaining < n {
+				// Nothing was charged
+				result.Allowed = false
+				result.DeniedBy = level.key
+				result.Atomic = true
+				return result, nil
+			}
+		}
+	}
+
+	firstResult, err := first.limiter.AllowN(ctx, first.key, n)
+	if err != nil || !firstResult.Allowed {
+		if firstResult != nil {
+			firstResult.DeniedBy = first.key
+		}
+		return firstResult, err
+	}
+
+	secondResult, err := second.limiter.AllowN(ctx, second.key, n)
+	if err != nil || !secondResult.Allowed {
+		if refundable {
+			if refundErr := refunder.adjust(ctx, first.key, -n); refundErr != nil {
+				return nil, errors.Join(err, fmt.Errorf("failed to refund %q: %w", first.key, refundErr))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		secondResult.DeniedBy = second.key
+		return secondResult, nil
+	}
+
+	// Report the level with the least quota left
+	if secondResult.Remaining < firstResult.Remaining {
+		return secondResult, nil
+	}
+	return firstResult, nil
+}
+
+// chargedLevel is one of the two limiters a parent/child request is charged
+// to, with the key it is charged under.
+type chargedLevel struct {
+	limiter RateLimiter
+	key     string
+}
+
+// Reset clears the key's child limiter. The shared parent is left untouched;
+// reset it directly with ParentKey.
+func (p *parentChildLimiter) Reset(ctx context.Context, key string) error {
+	return p.child(key).Reset(ctx, key)
+}
+
+// Close closes every child limiter created so far and the parent.
+func (p *parentChildLimiter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	closed := make(map[RateLimiter]bool)
+	for element := p.recent.Front(); element != nil; element = element.Next() {
+		child := element.Value.(*childEntry).limiter
+		// A factory may hand out one limiter for many keys
+		if closed[child] {
+			continue
+		}
+		closed[child] = true
+		errs = append(errs, child.Close())
+	}
+	p.children = make(map[string]*list.Element)
+	p.recent.Init()
+	if !closed[p.parent] {
+		errs = append(errs, p.parent.Close())
+	}
+
+	// Limiters sharing a Redis client each close it; only the first succeeds
+	for i, err := range errs {
+		if errors.Is(err, redis.ErrClosed) {
+			errs[i] = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// child returns the key's child limiter, creating it on first use and
+// dropping the least recently used one when maxChildLimiters are kept.
+func (p *parentChildLimiter) child(key string) RateLimiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if element, ok := p.children[key]; ok {
+		p.recent.MoveToFront(element)
+		return element.Value.(*childEntry).limiter
+	}
+
+	if p.recent.Len() >= maxChildLimiters {
+		oldest := p.recent.Back()
+		p.recent.Remove(oldest)
+		delete(p.children, oldest.Value.(*childEntry).key)
+	}
+	child := p.childFactory(key)
+	p.children[key] = p.recent.PushFront(&childEntry{key: key, limiter: child})
+	return child
+}
+
+// sharedFixedWindows returns the child and parent when both are fixed window
+// limiters that one script can check atomically.
+func sharedFixedWindows(child, parent RateLimiter) ([2]*fixedWindowLimiter, bool) {
+	c, ok := child.(*fixedWindowLimiter)
+	if !ok {
+		return [2]*fixedWindowLimiter{}, false
+	}
+	pa, ok := parent.(*fixedWindowLimiter)
+	if !ok || c.client != pa.client {
+		return [2]*fixedWindowLimiter{}, false
+	}
+
+	levels := [2]*fixedWindowLimiter{c, pa}
+	for _, level := range levels {
+		cfg := level.config
+		if level.alignedToFirstRequest() || cfg.MinInterval > 0 || cfg.KeyTimeResolution > 0 || cfg.PostResetGrace > 0 ||
+			cfg.WindowResolver != nil || cfg.ReserveForCritical > 0 || cfg.ObserveFirst > 0 {
+			return levels, false
+		}
+	}
+	return levels, true
+}
+
+// allowSharedFixedWindows checks n requests against both levels (keys holds
+// the key of each) and charges both or neither. The decision is counted in
+// both levels' stats and reported to both Observers.
+func allowSharedFixedWindows(ctx context.Context, levels [2]*fixedWindowLimiter, keys [2]string, n int64) (result *Result, err error) {
+	defer levels[0].config.recoverDecision(&result, &err)
+
+	start := time.Now()
+	result, err = decideSharedFixedWindows(ctx, levels, keys, n)
+	for i, level := range levels {
+		level.stats.forKey(keys[i]).record(result, err)
+		if level.config.Observer != nil {
+			level.config.observeDecision(ctx, keys[i], n, start, result, err)
+		}
+	}
+	return result, err
+}
+
+// decideSharedFixedWindows makes the decision for allowSharedFixedWindows,
+// applying each level's Config as its own AllowN would: the key's limit,
+// Denylist and ActiveSchedule. Levels that enforce their limit right now are
+// checked with one script.
+func decideSharedFixedWindows(ctx context.Context, levels [2]*fixedWindowLimiter, keys [2]string, n int64) (*Result, error) {
+	var (
+		active     []int
+		configs    []*Config
+		activeKeys []string
+		unlimited  Result
+	)
+	for i, level := range levels {
+		limit := level.config.keyLimit(keys[i], level.limit.load())
+		if result, outside := level.config.outsideSchedule(keys[i], limit); outside {
+			unlimited = result
+			continue
+		}
+		if result, denied := level.config.permanentDenial(ctx, keys[i], float64(n), limit); denied {
+			result.DeniedBy = keys[i]
+			return resultPtr(level.config.stampDecision(result, nil))
+		}
+		cfg := *level.config
+		cfg.Limit = limit
+		active = append(active, i)
+		configs = append(configs, &cfg)
+		activeKeys = append(activeKeys, keys[i])
+	}
+
+	switch len(active) {
+	case 0:
+		return resultPtr(levels[0].config.stampDecision(unlimited, nil))
+	case 1:
+		level := levels[active[0]]
+		result, err := level.allowN(ctx, activeKeys[0], n, configs[0].Limit)
+		if err == nil && !result.Allowed {
+			result.DeniedBy = activeKeys[0]
+		}
+		return resultPtr(level.config.stampDecision(result, err))
+	}
+
+	h := &hierarchicalLimiter{client: levels[0].client, levels: configs}
+	return resultPtr(levels[0].config.stampDecision(h.decide(ctx, activeKeys, n)))
+}
@@ -0,0 +1,333 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParentChild_ParentBlocksAtomically(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	parent, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     3,
+		Window:    time.Minute,
+		Prefix:    "global",
+	})
+	require.NoError(t, err)
+
+	limiter := NewHierarchicalLimiter(parent, func(key string) RateLimiter {
+		child, err := NewFixedWindow(client, &Config{
+			Algorithm: FixedWindow,
+			Limit:     2,
+			Window:    time.Minute,
+			Prefix:    "user",
+		})
+		require.NoError(t, err)
+		return child
+	})
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	for range 2 {
+		result, err := limiter.Allow(ctx, "alice")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	// Alice's own limit is exhausted first
+	result, err := limiter.Allow(ctx, "alice")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "alice", result.DeniedBy)
+
+	result, err = limiter.Allow(ctx, "bob")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Bob has room, but the global limit of 3 is used up
+	result, err = limiter.Allow(ctx, "bob")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ParentKey, result.DeniedBy)
+	assert.Equal(t, int64(3), result.Limit)
+
+	// The denied request charged neither level
+	windowStart := time.Now().Truncate(time.Minute).Unix()
+	bobCount, err := client.Get(ctx, "user:bob:"+strconv.FormatInt(windowStart, 10)).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), bobCount)
+	parentCount, err := client.Get(ctx, "global:"+ParentKey+":"+strconv.FormatInt(windowStart, 10)).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), parentCount)
+}
+
+func TestParentChild_ParentBlocksOtherLimiters(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	parent, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     2,
+		Window:    time.Hour,
+		Prefix:    "global",
+	})
+	require.NoError(t, err)
+
+	child, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+		Prefix:    "user",
+	})
+	require.NoError(t, err)
+
+	limiter := NewHierarchicalLimiter(parent, func(key string) RateLimiter { return child })
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.AllowN(ctx, "alice", 2)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining, "the parent has the least quota left")
+
+	result, err = limiter.Allow(ctx, "alice")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ParentKey, result.DeniedBy)
+
+	// The parent's denial was seen before charging the child
+	peeked, err := child.(Peeker).Peek(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), peeked.Remaining)
+}
+
+func TestParentChild_InvalidInput(t *testing.T) {
+	limiter := NewHierarchicalLimiter(&scriptedLimiter{decisions: []bool{true}}, func(key string) RateLimiter {
+		return &scriptedLimiter{decisions: []bool{true}}
+	})
+
+	_, err := limiter.AllowN(context.Background(), "alice", 0)
+	assert.ErrorIs(t, err, ErrInvalidN)
+
+	_, err = limiter.Allow(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestParentChild_ParentDenialRefundsChild(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	parent, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     3,
+		Window:    time.Minute,
+		Prefix:    "global",
+	})
+	require.NoError(t, err)
+
+	child, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+		Prefix:    "user",
+	})
+	require.NoError(t, err)
+
+	limiter := NewHierarchicalLimiter(parent, func(key string) RateLimiter { return child })
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.AllowN(ctx, "alice", 2)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// The child is charged first and refunded when the parent denies
+	result, err = limiter.AllowN(ctx, "alice", 2)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ParentKey, result.DeniedBy)
+
+	peeked, err := child.(Peeker).Peek(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), peeked.Remaining)
+
+	// A level that can't be refunded is charged after one that can
+	limiter = NewHierarchicalLimiter(child, func(key string) RateLimiter {
+		return &scriptedLimiter{decisions: []bool{false}}
+	})
+	result, err = limiter.Allow(ctx, "bob")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "bob", result.DeniedBy)
+
+	peeked, err = child.(Peeker).Peek(ctx, ParentKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), peeked.Remaining)
+}
+
+func TestParentChild_BoundsChildLimiters(t *testing.T) {
+	created := 0
+	limiter := NewHierarchicalLimiter(&scriptedLimiter{decisions: []bool{true}}, func(key string) RateLimiter {
+		created++
+		return &scriptedLimiter{decisions: []bool{true}}
+	})
+	defer limiter.Close()
+	pc := limiter.(*parentChildLimiter)
+
+	ctx := context.Background()
+	for i := range maxChildLimiters + 10 {
+		_, err := limiter.Allow(ctx, "user:"+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	assert.Len(t, pc.children, maxChildLimiters)
+	assert.Equal(t, maxChildLimiters, pc.recent.Len())
+
+	// The most recent keys are kept, the oldest are created again
+	_, err := limiter.Allow(ctx, "user:"+strconv.Itoa(maxChildLimiters+9))
+	require.NoError(t, err)
+	assert.Equal(t, maxChildLimiters+10, created)
+	_, err = limiter.Allow(ctx, "user:0")
+	require.NoError(t, err)
+	assert.Equal(t, maxChildLimiters+11, created)
+}
+
+func TestParentChild_SharedScriptAppliesEachConfig(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	parentObserver, childObserver := &recordingObserver{}, &recordingObserver{}
+	parent, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     100,
+		Window:    time.Minute,
+		Prefix:    "global",
+		Observer:  parentObserver,
+	})
+	require.NoError(t, err)
+
+	child, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     1,
+		Window:    time.Minute,
+		Prefix:    "user",
+		Denylist:  []string{"mallory"},
+		Observer:  childObserver,
+		LimitResolver: func(key string) int64 {
+			if key == "premium" {
+				return 3
+			}
+			return 0
+		},
+	})
+	require.NoError(t, err)
+
+	limiter := NewHierarchicalLimiter(parent, func(key string) RateLimiter { return child })
+	defer limiter.Close()
+	_, shared := sharedFixedWindows(child, parent)
+	require.True(t, shared)
+
+	ctx := context.Background()
+
+	// The child's LimitResolver applies
+	for range 3 {
+		result, err := limiter.Allow(ctx, "premium")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "premium")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Limit)
+	assert.Equal(t, "premium", result.DeniedBy)
+
+	// A denylisted key is denied without charging the parent
+	result, err = limiter.Allow(ctx, "mallory")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Permanent)
+	assert.Equal(t, ReasonDenylisted, result.Reason)
+	assert.Equal(t, FixedWindow, result.Algorithm)
+
+	peeked, err := parent.(Peeker).Peek(ctx, ParentKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(97), peeked.Remaining)
+
+	// Both levels observe every decision
+	assert.Len(t, childObserver.all(), 5)
+	assert.Len(t, parentObserver.all(), 5)
+}
+
+func TestParentChild_SharedScriptFillsResult(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	var created []string
+	observer := &firstDenialObserver{}
+	parent, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     100,
+		Window:    time.Hour,
+		Prefix:    "global",
+	})
+	require.NoError(t, err)
+	child, err := NewFixedWindow(client, &Config{
+		Algorithm:    FixedWindow,
+		Limit:        2,
+		Window:       time.Hour,
+		Prefix:       "user",
+		Observer:     observer,
+		OnKeyCreated: func(key string) { created = append(created, key) },
+		RequestCost:  func(string) int64 { return 2 },
+	})
+	require.NoError(t, err)
+
+	limiter := NewHierarchicalLimiter(parent, func(key string) RateLimiter { return child })
+	defer limiter.Close()
+	_, shared := sharedFixedWindows(child, parent)
+	require.True(t, shared)
+
+	ctx := context.Background()
+	result, err := limiter.Allow(ctx, "bob")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.FirstSeen)
+	assert.Equal(t, int64(1), result.Remaining)
+	assert.Zero(t, result.RequestsUntilDenied, "the next request costs 2")
+	assert.Positive(t, result.CheckDuration)
+	assert.Equal(t, []string{"bob"}, created)
+
+	result, err = limiter.Allow(ctx, "bob")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.False(t, result.FirstSeen)
+	assert.Equal(t, []string{"bob"}, created)
+
+	// Only the first denial of the window is reported; denials charge
+	// nothing, so there is no overage
+	for range 2 {
+		result, err = limiter.AllowN(ctx, "bob", 2)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Zero(t, result.Overage)
+		assert.Zero(t, result.Remaining)
+	}
+	assert.Equal(t, []string{"bob"}, observer.firstDenials)
+
+	// Reset clears the mark along with the counter, so the next denial is reported again
+	require.NoError(t, limiter.Reset(ctx, "bob"))
+	for range 3 {
+		_, err = limiter.Allow(ctx, "bob")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"bob", "bob"}, observer.firstDenials)
+}
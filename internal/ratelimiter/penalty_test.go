@@ -0,0 +1,92 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPenaltyBox(t *testing.T) {
+	_, err := NewPenaltyBox(nil, "")
+	assert.Error(t, err)
+
+	box, err := NewPenaltyBox(redis.NewClient(&redis.Options{}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "ratelimit:user:123:ban", box.formatKey("user:123"))
+}
+
+func TestPenaltyBox_IsBanned(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	box, err := NewPenaltyBox(client, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := "ip:203.0.113.7"
+
+	banned, remaining, err := box.IsBanned(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, banned)
+	assert.Equal(t, time.Duration(0), remaining)
+
+	require.NoError(t, box.Ban(ctx, key, 10*time.Second))
+
+	banned, remaining, err = box.IsBanned(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, banned)
+	assert.Equal(t, 10*time.Second, remaining)
+
+	// The remaining duration shrinks as time passes
+	mr.FastForward(4 * time.Second)
+
+	banned, remaining, err = box.IsBanned(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, banned)
+	assert.Equal(t, 6*time.Second, remaining)
+
+	// And the ban lifts itself once it expires
+	mr.FastForward(7 * time.Second)
+
+	banned, remaining, err = box.IsBanned(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, banned)
+	assert.Equal(t, time.Duration(0), remaining)
+}
+
+func TestPenaltyBox_Unban(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	box, err := NewPenaltyBox(client, "api")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, box.Ban(ctx, "user:1", time.Minute))
+	require.NoError(t, box.Unban(ctx, "user:1"))
+
+	banned, _, err := box.IsBanned(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, banned)
+}
+
+func TestPenaltyBox_InvalidInput(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	box, err := NewPenaltyBox(client, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	assert.ErrorIs(t, box.Ban(ctx, "", time.Minute), ErrInvalidKey)
+	assert.Error(t, box.Ban(ctx, "user:1", 0))
+
+	_, _, err = box.IsBanned(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
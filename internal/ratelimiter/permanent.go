@@ -0,0 +1,34 @@
+package ratelimiter
+
+import "context"
+
+// permanentDenial returns a permanent denial when the request can never be
+// allowed: the key is in Config.Denylist, or n exceeds the most a window can
+// admit (the limit plus any Config.PostResetGrace, less the
+// Config.ReserveForCritical a request that isn't critical must leave). Such
+// requests are decided without touching Redis, so they use no quota.
+func (c *Config) permanentDenial(ctx context.Context, key string, n float64, limit int64) (Result, bool) {
+	reason := DenyReason("")
+	if c.denylisted(key) {
+		reason = ReasonDenylisted
+	} else if n > float64(limit)+float64(c.PostResetGrace)-float64(c.reserveFor(ctx)) {
+		reason = ReasonExceedsLimit
+	}
+	if reason == "" {
+		return Result{}, false
+	}
+
+	return Result{
+		Allowed:   false,
+		Limit:     limit,
+		Remaining: 0,
+		Reason:    reason,
+		Permanent: true,
+	}, true
+}
+
+// denylisted reports whether the key is in Config.Denylist.
+func (c *Config) denylisted(key string) bool {
+	_, ok := c.denied[key]
+	return ok
+}
@@ -0,0 +1,175 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermanentDenial_Denylist(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Denylist:  []string{"user:banned"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "user:banned")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Permanent)
+	assert.Equal(t, ReasonDenylisted, result.Reason)
+	assert.Equal(t, time.Duration(0), result.RetryAfter)
+	assert.True(t, result.ResetAt.IsZero())
+	assert.Empty(t, mr.Keys(), "a denylisted key should not touch Redis")
+
+	// A hint cannot serve a denylisted key locally
+	result, err = limiter.(HintedLimiter).AllowHinted(ctx, "user:banned", 1, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Permanent)
+
+	result, err = limiter.Allow(ctx, "user:ok")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.False(t, result.Permanent)
+	assert.Empty(t, result.Reason)
+}
+
+func TestPermanentDenial_ImpossibleN(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	config := Config{Limit: 5, Window: time.Minute}
+
+	fixed := config
+	fixed.Algorithm = FixedWindow
+	fixedLimiter, err := NewFixedWindow(client, &fixed)
+	require.NoError(t, err)
+
+	sliding := config
+	sliding.Algorithm = SlidingWindow
+	slidingLimiter, err := NewSlidingWindow(client, &sliding)
+	require.NoError(t, err)
+
+	token := config
+	token.Algorithm = TokenBucket
+	tokenLimiter, err := NewTokenBucket(client, &token)
+	require.NoError(t, err)
+
+	for name, limiter := range map[string]RateLimiter{
+		"fixed window":   fixedLimiter,
+		"sliding window": slidingLimiter,
+		"token bucket":   tokenLimiter,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := limiter.AllowN(ctx, "user:big", 6)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.True(t, result.Permanent)
+			assert.Equal(t, ReasonExceedsLimit, result.Reason)
+			assert.Equal(t, time.Duration(0), result.RetryAfter)
+			assert.True(t, result.ResetAt.IsZero())
+
+			// A request that fits is denied only temporarily once the quota is used
+			_, err = limiter.AllowN(ctx, "user:big", 5)
+			require.NoError(t, err)
+			result, err = limiter.Allow(ctx, "user:big")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.False(t, result.Permanent)
+			assert.Equal(t, ReasonLimitExceeded, result.Reason)
+			assert.Greater(t, result.RetryAfter, time.Duration(0))
+		})
+	}
+
+	result, err := tokenLimiter.(FractionalLimiter).AllowCostF(ctx, "user:frac", 5.5)
+	require.NoError(t, err)
+	assert.True(t, result.Permanent)
+}
+
+func TestWaitN_PermanentDenial(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// Waiting cannot make room for more than the limit, so WaitN gives up at once
+	result, err := WaitN(context.Background(), limiter, "user:big", 6)
+	assert.ErrorIs(t, err, ErrPermanentDenial)
+	require.NotNil(t, result)
+	assert.True(t, result.Permanent)
+}
+
+func TestWaitN_PermanentDenialBeyondReserve(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm:          tt.algorithm,
+			Limit:              5,
+			Window:             time.Minute,
+			ReserveForCritical: 2,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		// Only 3 of the 5 are ever available to requests that aren't critical
+		result, err := WaitN(ctx, limiter, "user:big", 4)
+		assert.ErrorIs(t, err, ErrPermanentDenial)
+		require.NotNil(t, result)
+		assert.True(t, result.Permanent)
+		assert.Equal(t, ReasonExceedsLimit, result.Reason)
+
+		result, err = WaitN(WithCritical(ctx), limiter, "user:big", 4)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+}
+
+func TestPermanentDenial_WithinPostResetGrace(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:            FixedWindow,
+		Limit:                5,
+		Window:               time.Hour,
+		PostResetGrace:       3,
+		PostResetGracePeriod: time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	// More than the limit, but within what the grace can admit
+	result, err := limiter.AllowN(ctx, "user:1", 8)
+	require.NoError(t, err)
+	assert.False(t, result.Permanent)
+
+	result, err = limiter.AllowN(ctx, "user:2", 9)
+	require.NoError(t, err)
+	assert.True(t, result.Permanent)
+	assert.Equal(t, ReasonExceedsLimit, result.Reason)
+}
@@ -0,0 +1,143 @@
+// Package ratelimitertest provides test doubles for code that uses a ratelimiter.RateLimiter.
+package ratelimitertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zahra-abedi/distributed-rate-limiter/internal/ratelimiter"
+)
+
+// Call records a single Allow or AllowN call made against a FakeLimiter.
+// Allow is recorded with N set to 1.
+type Call struct {
+	Key string
+	N   int64
+}
+
+// response is a queued decision returned by a FakeLimiter.
+type response struct {
+	result *ratelimiter.Result
+	err    error
+}
+
+// FakeLimiter is a ratelimiter.RateLimiter with programmable decisions.
+//
+// Decisions queued with Queue or QueueError are returned in order, one per
+// Allow/AllowN call. Once the queue is empty, every request is allowed. All
+// calls are recorded so tests can assert how the limiter was used. It never
+// touches Redis and is safe for concurrent use.
+//
+// Example:
+//
+//	fake := ratelimitertest.NewFakeLimiter()
+//	fake.Queue(ratelimiter.NewDeniedResult(10, time.Second, time.Now().Add(time.Second)))
+//	handler := NewHandler(fake)
+//	// ... exercise handler ...
+//	if calls := fake.Calls(); calls[0].Key != "user:123" { ... }
+type FakeLimiter struct {
+	mu        sync.Mutex
+	responses []response
+	calls     []Call
+	resets    []string
+	closed    bool
+}
+
+// NewFakeLimiter creates a FakeLimiter with an empty decision queue.
+func NewFakeLimiter() *FakeLimiter {
+	return &FakeLimiter{}
+}
+
+// Queue appends results to be returned by subsequent Allow/AllowN calls.
+func (f *FakeLimiter) Queue(results ...*ratelimiter.Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, result := range results {
+		f.responses = append(f.responses, response{result: result})
+	}
+}
+
+// QueueError appends an error to be returned by the next unanswered Allow/AllowN call.
+func (f *FakeLimiter) QueueError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, response{err: err})
+}
+
+// Allow records the call and returns the next queued decision.
+func (f *FakeLimiter) Allow(ctx context.Context, key string) (*ratelimiter.Result, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+// AllowN records the call and returns the next queued decision.
+// Invalid n is rejected like the real limiters, without consuming a queued decision.
+func (f *FakeLimiter) AllowN(ctx context.Context, key string, n int64) (*ratelimiter.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Key: key, N: n})
+
+	if n <= 0 {
+		return nil, ratelimiter.ErrInvalidN
+	}
+
+	if len(f.responses) == 0 {
+		return ratelimiter.NewNoop().AllowN(ctx, key, n)
+	}
+
+	next := f.responses[0]
+	f.responses = f.responses[1:]
+	return next.result, next.err
+}
+
+// Reset records the key that was reset.
+func (f *FakeLimiter) Reset(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.resets = append(f.resets, key)
+	return nil
+}
+
+// Close marks the limiter as closed.
+func (f *FakeLimiter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	return nil
+}
+
+// Calls returns a copy of every Allow/AllowN call made so far, in order.
+func (f *FakeLimiter) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Resets returns a copy of every key passed to Reset so far, in order.
+func (f *FakeLimiter) Resets() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.resets...)
+}
+
+// Closed reports whether Close has been called.
+func (f *FakeLimiter) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.closed
+}
+
+// Pending returns the number of queued decisions not yet returned.
+func (f *FakeLimiter) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.responses)
+}
@@ -0,0 +1,77 @@
+package ratelimitertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zahra-abedi/distributed-rate-limiter/internal/ratelimiter"
+)
+
+func TestFakeLimiter_InterfaceContract(t *testing.T) {
+	var _ ratelimiter.RateLimiter = (*FakeLimiter)(nil)
+}
+
+func TestFakeLimiter_QueuedResultsInOrder(t *testing.T) {
+	fake := NewFakeLimiter()
+	ctx := context.Background()
+	resetAt := time.Now().Add(time.Minute)
+
+	allowed := ratelimiter.NewAllowedResult(10, 9, resetAt)
+	denied := ratelimiter.NewDeniedResult(10, time.Second, resetAt)
+	errBackend := errors.New("backend down")
+
+	fake.Queue(allowed, denied)
+	fake.QueueError(errBackend)
+	assert.Equal(t, 3, fake.Pending())
+
+	result, err := fake.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Same(t, allowed, result)
+
+	result, err = fake.AllowN(ctx, "user:2", 5)
+	require.NoError(t, err)
+	assert.Same(t, denied, result)
+
+	result, err = fake.Allow(ctx, "user:1")
+	assert.ErrorIs(t, err, errBackend)
+	assert.Nil(t, result)
+
+	// Empty queue allows everything
+	result, err = fake.Allow(ctx, "user:3")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, fake.Pending())
+}
+
+func TestFakeLimiter_RecordsCalls(t *testing.T) {
+	fake := NewFakeLimiter()
+	ctx := context.Background()
+
+	_, _ = fake.Allow(ctx, "user:1")
+	_, _ = fake.AllowN(ctx, "user:2", 3)
+	require.NoError(t, fake.Reset(ctx, "user:1"))
+	require.NoError(t, fake.Close())
+
+	assert.Equal(t, []Call{
+		{Key: "user:1", N: 1},
+		{Key: "user:2", N: 3},
+	}, fake.Calls())
+	assert.Equal(t, []string{"user:1"}, fake.Resets())
+	assert.True(t, fake.Closed())
+}
+
+func TestFakeLimiter_InvalidN(t *testing.T) {
+	fake := NewFakeLimiter()
+	fake.Queue(ratelimiter.NewDeniedResult(10, time.Second, time.Now()))
+
+	_, err := fake.AllowN(context.Background(), "user:1", 0)
+	assert.ErrorIs(t, err, ratelimiter.ErrInvalidN)
+
+	// The queued decision is kept for the next valid call
+	assert.Equal(t, 1, fake.Pending())
+	assert.Len(t, fake.Calls(), 1)
+}
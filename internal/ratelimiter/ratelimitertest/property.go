@@ -0,0 +1,225 @@
+package ratelimitertest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/zahra-abedi/distributed-rate-limiter/internal/ratelimiter"
+)
+
+const (
+	// propertyLimit and propertyWindow configure every limiter under test.
+	// The window is long enough that wall-clock refill during a run is
+	// negligible and a sequence almost never straddles a window boundary.
+	propertyLimit  = 10
+	propertyWindow = 24 * time.Hour
+
+	propertySequences = 20
+	propertySteps     = 50
+)
+
+// propertyOptions holds the settings applied by PropertyOptions.
+type propertyOptions struct {
+	// seed is the random seed, 0 to pick one at random
+	seed uint64
+
+	// denialsCounted skips the check that a denial consumes nothing
+	denialsCounted bool
+}
+
+// PropertyOption configures a PropertyTest run.
+type PropertyOption func(*propertyOptions)
+
+// WithSeed runs the sequences of the given seed, e.g. the one logged by a
+// failed run, to replay that failure.
+func WithSeed(seed uint64) PropertyOption {
+	return func(o *propertyOptions) {
+		o.seed = seed
+	}
+}
+
+// WithDenialsCounted is for limiters that count denied requests towards the
+// window, as FixedWindow and SlidingWindow do. A denial then uses quota by
+// design, so the invariant that it consumes nothing is not checked.
+func WithDenialsCounted() PropertyOption {
+	return func(o *propertyOptions) {
+		o.denialsCounted = true
+	}
+}
+
+// PropertyTest checks a RateLimiter implementation against invariants every
+// algorithm in this module must hold, using randomized sequences of Allow,
+// AllowN and Reset calls, each on a fresh key:
+//
+//   - Allow/AllowN never fail and report the configured Limit
+//   - Remaining is never negative and never above Limit
+//   - no more than Limit is admitted per window, plus whatever the elapsed
+//     wall time refills
+//   - a denied request consumes nothing: the key's state, read with Peek
+//     when the limiter is a ratelimiter.Peeker, has no less Remaining after
+//     the denial than before it, and asking again for at most the Remaining
+//     the denial reported is allowed (skipped with WithDenialsCounted)
+//   - after Reset the key has its full Limit back
+//
+// newLimiter must return a limiter that admits limit units per window. The
+// limiters read the wall clock, so a run lasts real time and uses a long
+// window rather than advancing a clock. The random seed is logged and is part
+// of every key, so a failure can be traced back to its sequence and replayed
+// with WithSeed.
+//
+// Example:
+//
+//	ratelimitertest.PropertyTest(t, func(limit int64, window time.Duration) (ratelimiter.RateLimiter, error) {
+//		return ratelimiter.NewFixedWindow(client, &ratelimiter.Config{
+//			Algorithm: ratelimiter.FixedWindow,
+//			Limit:     limit,
+//			Window:    window,
+//		})
+//	})
+func PropertyTest(t *testing.T, newLimiter func(limit int64, window time.Duration) (ratelimiter.RateLimiter, error), opts ...PropertyOption) {
+	t.Helper()
+
+	var options propertyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	limiter, err := newLimiter(propertyLimit, propertyWindow)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	seed := options.seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	t.Logf("property test seed: %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for i := range propertySequences {
+		key := fmt.Sprintf("property:%d:%d", seed, i)
+		// A replayed seed's keys may still hold the failed run's state
+		if err := limiter.Reset(context.Background(), key); err != nil {
+			t.Fatalf("failed to reset %s: %v", key, err)
+		}
+		runPropertySequence(t, limiter, key, rng, options)
+	}
+}
+
+// runPropertySequence runs one random sequence against key, tracking how much
+// it has admitted since the last Reset.
+func runPropertySequence(t *testing.T, limiter ratelimiter.RateLimiter, key string, rng *rand.Rand, options propertyOptions) {
+	t.Helper()
+	ctx := context.Background()
+	peeker, _ := limiter.(ratelimiter.Peeker)
+
+	var admitted int64
+	since := time.Now()
+	window := since.Truncate(propertyWindow)
+
+	check := func(step int, n int64, result *ratelimiter.Result, err error) bool {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s step %d: AllowN(%d) failed: %v", key, step, n, err)
+			return false
+		}
+		if result.Limit != propertyLimit {
+			t.Errorf("%s step %d: Limit = %d, want %d", key, step, result.Limit, propertyLimit)
+		}
+		if result.Remaining < 0 || result.Remaining > propertyLimit {
+			t.Errorf("%s step %d: Remaining = %d, want 0..%d", key, step, result.Remaining, propertyLimit)
+		}
+		if !result.Allowed {
+			return true
+		}
+
+		admitted += n
+		// A fixed window starts over at the boundary, so the bound only holds within one window
+		if time.Now().Truncate(propertyWindow).Equal(window) {
+			refill := int64(math.Ceil(propertyLimit * time.Since(since).Seconds() / propertyWindow.Seconds()))
+			if admitted > propertyLimit+refill {
+				t.Errorf("%s step %d: admitted %d in one window, want at most %d", key, step, admitted, propertyLimit+refill)
+			}
+		}
+		return true
+	}
+
+	for step := range propertySteps {
+		switch op := rng.IntN(10); {
+		case op == 0:
+			if err := limiter.Reset(ctx, key); err != nil {
+				t.Errorf("%s step %d: Reset failed: %v", key, step, err)
+				return
+			}
+			admitted, since = 0, time.Now()
+			window = since.Truncate(propertyWindow)
+
+			result, err := limiter.Allow(ctx, key)
+			if !check(step, 1, result, err) {
+				return
+			}
+			if !result.Allowed || result.Remaining != propertyLimit-1 {
+				t.Errorf("%s step %d: after Reset got Allowed=%v Remaining=%d, want true and %d",
+					key, step, result.Allowed, result.Remaining, propertyLimit-1)
+			}
+
+		case op < 4:
+			result, err := limiter.Allow(ctx, key)
+			if !check(step, 1, result, err) {
+				return
+			}
+
+		default:
+			var before *ratelimiter.Result
+			if peeker != nil && !options.denialsCounted {
+				var err error
+				if before, err = peeker.Peek(ctx, key); err != nil {
+					t.Errorf("%s step %d: Peek failed: %v", key, step, err)
+					return
+				}
+			}
+
+			// Occasionally ask for more than the limit, which is never allowed
+			n := rng.Int64N(propertyLimit+1) + 1
+			result, err := limiter.AllowN(ctx, key, n)
+			if !check(step, n, result, err) {
+				return
+			}
+			if result.Allowed || options.denialsCounted {
+				continue
+			}
+
+			// The denial didn't consume anything, as the key's state shows
+			if before != nil {
+				after, err := peeker.Peek(ctx, key)
+				if err != nil {
+					t.Errorf("%s step %d: Peek failed: %v", key, step, err)
+					return
+				}
+				if after.Remaining < before.Remaining {
+					t.Errorf("%s step %d: denied AllowN(%d) took Remaining from %d to %d",
+						key, step, n, before.Remaining, after.Remaining)
+				}
+			}
+			if result.Remaining == 0 {
+				continue
+			}
+
+			// The denial didn't consume anything, so what it reported as remaining is still there
+			retry := rng.Int64N(result.Remaining) + 1
+			result, err = limiter.AllowN(ctx, key, retry)
+			if !check(step, retry, result, err) {
+				return
+			}
+			if !result.Allowed {
+				t.Errorf("%s step %d: AllowN(%d) denied after a denial reported Remaining >= %d",
+					key, step, retry, retry)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimitertest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zahra-abedi/distributed-rate-limiter/internal/ratelimiter"
+)
+
+func TestPropertyTest(t *testing.T) {
+	algorithms := []struct {
+		algorithm ratelimiter.Algorithm
+		create    func(*redis.Client, *ratelimiter.Config) (ratelimiter.RateLimiter, error)
+		opts      []PropertyOption
+	}{
+		{ratelimiter.FixedWindow, ratelimiter.NewFixedWindow, []PropertyOption{WithDenialsCounted()}},
+		{ratelimiter.SlidingWindow, ratelimiter.NewSlidingWindow, []PropertyOption{WithDenialsCounted()}},
+		{ratelimiter.TokenBucket, ratelimiter.NewTokenBucket, nil},
+	}
+
+	for _, tt := range algorithms {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+			PropertyTest(t, func(limit int64, window time.Duration) (ratelimiter.RateLimiter, error) {
+				return tt.create(client, &ratelimiter.Config{
+					Algorithm: tt.algorithm,
+					Limit:     limit,
+					Window:    window,
+				})
+			}, tt.opts...)
+		})
+	}
+}
+
+func TestPropertyTest_WithSeed(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	newLimiter := func(limit int64, window time.Duration) (ratelimiter.RateLimiter, error) {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		return ratelimiter.NewTokenBucket(client, &ratelimiter.Config{
+			Algorithm: ratelimiter.TokenBucket,
+			Limit:     limit,
+			Window:    window,
+		})
+	}
+
+	// The seed is part of every key, so a replay touches the same keys
+	PropertyTest(t, newLimiter, WithSeed(42))
+	keys := mr.Keys()
+	PropertyTest(t, newLimiter, WithSeed(42))
+	if got := mr.Keys(); !slices.Equal(got, keys) {
+		t.Errorf("replayed run used keys %v, want %v", got, keys)
+	}
+}
@@ -0,0 +1,43 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+// RecommendConfig returns a Config for algo that sustains ratePerSec requests
+// per second per key and admits bursts of about burst requests.
+//
+// For TokenBucket the bucket holds burst tokens (Limit) and refills at
+// ratePerSec, i.e. Window is burst/ratePerSec. For the window algorithms
+// Window is burst/ratePerSec rounded to whole seconds, at least one, and
+// Limit is ratePerSec times Window, so the burst is at least one second's
+// worth of requests; a fixed window may also admit up to twice Limit across
+// a window boundary.
+//
+// Returns nil if ratePerSec or burst is not positive, algo is Concurrency or
+// unknown, or the resulting Config fails Validate.
+func RecommendConfig(ratePerSec float64, burst int64, algo Algorithm) *Config {
+	if !(ratePerSec > 0) || math.IsInf(ratePerSec, 1) || burst <= 0 {
+		return nil
+	}
+
+	seconds := float64(burst) / ratePerSec
+	config := &Config{Algorithm: algo}
+	switch algo {
+	case TokenBucket:
+		config.Limit = burst
+		config.Window = time.Duration(seconds * float64(time.Second))
+	case FixedWindow, SlidingWindow:
+		seconds = max(math.Round(seconds), 1)
+		config.Window = time.Duration(seconds) * time.Second
+		config.Limit = max(int64(math.Round(ratePerSec*seconds)), 1)
+	default:
+		return nil
+	}
+
+	if config.Validate() != nil {
+		return nil
+	}
+	return config
+}
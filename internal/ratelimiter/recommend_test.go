@@ -0,0 +1,111 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommendConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		rate       float64
+		burst      int64
+		algo       Algorithm
+		wantLimit  int64
+		wantWindow time.Duration
+	}{
+		{"token bucket", 10, 20, TokenBucket, 20, 2 * time.Second},
+		{"token bucket fractional window", 4, 3, TokenBucket, 3, 750 * time.Millisecond},
+		{"fixed window", 10, 20, FixedWindow, 20, 2 * time.Second},
+		{"sliding window rounds window", 2, 7, SlidingWindow, 8, 4 * time.Second},
+		{"window algorithms use at least a second", 100, 10, FixedWindow, 100, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := RecommendConfig(tt.rate, tt.burst, tt.algo)
+			require.NotNil(t, config)
+			assert.Equal(t, tt.algo, config.Algorithm)
+			assert.Equal(t, tt.wantLimit, config.Limit)
+			assert.Equal(t, tt.wantWindow, config.Window)
+			assert.NoError(t, config.Validate())
+		})
+	}
+}
+
+func TestRecommendConfig_Invalid(t *testing.T) {
+	assert.Nil(t, RecommendConfig(0, 10, TokenBucket))
+	assert.Nil(t, RecommendConfig(-1, 10, FixedWindow))
+	assert.Nil(t, RecommendConfig(math.NaN(), 10, SlidingWindow))
+	assert.Nil(t, RecommendConfig(math.Inf(1), 10, TokenBucket))
+	assert.Nil(t, RecommendConfig(10, 0, TokenBucket))
+	assert.Nil(t, RecommendConfig(10, 5, Concurrency))
+	// A 100µs window is below the 1ms minimum
+	assert.Nil(t, RecommendConfig(10000, 1, TokenBucket))
+}
+
+func TestRecommendConfig_AdmitsRateAndBurst(t *testing.T) {
+	const (
+		rate  = 50.0
+		burst = 10
+	)
+
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		config := RecommendConfig(rate, burst, tt.algorithm)
+		require.NotNil(t, config)
+		assert.InDelta(t, rate, float64(config.Limit)/config.Window.Seconds(), rate*0.1, "sustained rate")
+
+		limiter, err := tt.create(client, config)
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		if tt.algorithm != TokenBucket {
+			// Start at a window boundary so the burst doesn't straddle two windows
+			time.Sleep(time.Until(time.Now().Truncate(config.Window).Add(config.Window)))
+		}
+
+		// A fresh key admits the whole burst at once, and no more
+		admitted := 0
+		for range 2 * config.Limit {
+			result, err := limiter.Allow(context.Background(), "user:1")
+			require.NoError(t, err)
+			if result.Allowed {
+				admitted++
+			}
+		}
+		assert.InDelta(t, float64(config.Limit), float64(admitted), 1, "burst")
+	})
+
+	t.Run("token bucket refills at the rate", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewTokenBucket(client, RecommendConfig(rate, burst, TokenBucket))
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		result, err := limiter.AllowN(ctx, "user:1", burst)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+
+		// 100ms at 50/s refills about 5 tokens
+		time.Sleep(100 * time.Millisecond)
+		admitted := 0
+		for range burst {
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			if result.Allowed {
+				admitted++
+			}
+		}
+		assert.InDelta(t, 5, admitted, 2)
+	})
+}
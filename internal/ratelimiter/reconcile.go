@@ -0,0 +1,77 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ProvisionalCost is the cost Reconcile assumes a request was allowed with:
+// a plain Allow.
+const ProvisionalCost int64 = 1
+
+// adjustCounterScript adds a signed amount to a window counter, never taking
+// it below zero, and gives a counter it creates a TTL.
+//
+// KEYS[1]: The Redis key for the counter
+// ARGV[1]: The amount to add (negative to refund)
+// ARGV[2]: The TTL in milliseconds for a counter this call creates
+// ARGV[3]: Hash field holding the counter ("" when KEYS[1] is the counter itself)
+//
+// Returns: the counter after the adjustment.
+const adjustCounterScript = `
+local field = ARGV[3]
+local function incr(n)
+    if field == '' then
+        return redis.call('INCRBY', KEYS[1], n)
+    end
+    return redis.call('HINCRBY', KEYS[1], field, n)
+end
+
+local current = incr(ARGV[1])
+if current < 0 then
+    -- Refunds can't take the counter below zero; INCRBY keeps the TTL
+    current = incr(-current)
+end
+if redis.call('PTTL', KEYS[1]) == -1 then
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return current
+`
+
+// adjuster is implemented by the limiters whose usage can be changed by a
+// signed amount, for Reconcile and to refund a charge that must be undone.
+type adjuster interface {
+	// adjust charges the key delta more, or refunds -delta when negative,
+	// within the bounds described on Reconciler.
+	adjust(ctx context.Context, key string, delta int64) error
+}
+
+// reconcileDelta validates a Reconcile call and returns the consumption to
+// add to the key: actualCost less ProvisionalCost.
+func reconcileDelta(key string, actualCost int64) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	if actualCost < 0 {
+		return 0, fmt.Errorf("%w: actual cost must not be negative, got: %d", ErrInvalidCost, actualCost)
+	}
+	return actualCost - ProvisionalCost, nil
+}
+
+// adjustCounter adds delta to the window counter at redisKey (and field),
+// creating it with ttl if needed.
+func adjustCounter(ctx context.Context, client *redis.Client, config *Config, redisKey, field string, delta int64, ttl time.Duration) error {
+	if delta == 0 {
+		return nil
+	}
+	if err := config.checkCallBudget(ctx); err != nil {
+		return err
+	}
+	if err := client.Eval(ctx, adjustCounterScript, []string{redisKey}, delta, ttl.Milliseconds(), field).Err(); err != nil {
+		return fmt.Errorf("failed to reconcile rate limit: %w", keyTypeError(err, config.Algorithm))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     10,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+		reconciler := limiter.(Reconciler)
+
+		ctx := context.Background()
+
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		assert.Equal(t, int64(9), result.Remaining)
+
+		// The request turned out to cost 5: 4 more units are consumed
+		require.NoError(t, reconciler.Reconcile(ctx, "user:1", 5))
+		result, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), result.Remaining)
+
+		// A request that cost nothing is refunded
+		require.NoError(t, reconciler.Reconcile(ctx, "user:1", 0))
+		result, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), result.Remaining)
+
+		// Charging more than is left uses up the key's quota without error
+		require.NoError(t, reconciler.Reconcile(ctx, "user:1", 100))
+		result, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+
+		// A key never seen before is charged from a fresh window or bucket
+		require.NoError(t, reconciler.Reconcile(ctx, "user:2", 3))
+		result, err = limiter.Allow(ctx, "user:2")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), result.Remaining)
+
+		assert.ErrorIs(t, reconciler.Reconcile(ctx, "", 5), ErrInvalidKey)
+		assert.ErrorIs(t, reconciler.Reconcile(ctx, "user:1", -1), ErrInvalidCost)
+	})
+}
+
+func TestReconcile_SetsCounterTTL(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	require.NoError(t, limiter.(Reconciler).Reconcile(context.Background(), "user:1", 3))
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	assert.Greater(t, mr.TTL(keys[0]), time.Duration(0), "a counter created by Reconcile must expire")
+}
+
+func TestReconcile_AlignedToFirstRequestUnsupported(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm: FixedWindow,
+		Limit:     10,
+		Window:    time.Minute,
+		Alignment: AlignedToFirstRequest,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	err = limiter.(Reconciler).Reconcile(context.Background(), "user:1", 3)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestReconcile_TokenBucketAfterRefill(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// 10 tokens per second
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, int64(9), result.Remaining)
+
+	// The bucket refills to capacity before the charge is known; the refill
+	// is applied first, so the next consume can't refill over the charge
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, limiter.(Reconciler).Reconcile(ctx, "user:1", 5))
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Remaining)
+}
@@ -0,0 +1,63 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// recoverDecision turns a panic during a decision, e.g. in a LimitResolver,
+// RequestCost or MetricKeyLabel callback, into the decision an unavailable
+// Redis would get: a fail-open Result with Config.FailOpen, an error wrapping
+// ErrPanic otherwise. The panic is reported to Config.OnPanic. Deferred by
+// every entry point that makes a decision, with named results; entry points
+// that read or adjust a key's state defer recoverError instead.
+func (c *Config) recoverDecision(result **Result, err *error) {
+	if recovered := recover(); recovered != nil {
+		*result, *err = c.panicDecision(recovered, debug.Stack())
+	}
+}
+
+// recoverError turns a panic in an entry point that makes no decision (Peek,
+// UsedMany, Reconcile and the like) into an error wrapping ErrPanic, even
+// with Config.FailOpen. The panic is reported to Config.OnPanic.
+func (c *Config) recoverError(err *error) {
+	if recovered := recover(); recovered != nil {
+		c.reportPanic(recovered, debug.Stack())
+		*err = fmt.Errorf("%w: %v", ErrPanic, recovered)
+	}
+}
+
+// recoverDecisionValue is recoverDecision for entry points returning a Result by value.
+func (c *Config) recoverDecisionValue(result *Result, err *error) {
+	if recovered := recover(); recovered != nil {
+		*result, *err = resultValue(c.panicDecision(recovered, debug.Stack()))
+	}
+}
+
+// panicDecision reports a recovered panic and returns the decision it falls back to.
+func (c *Config) panicDecision(recovered any, stack []byte) (*Result, error) {
+	c.reportPanic(recovered, stack)
+	if c.FailOpen {
+		return resultPtr(c.stampDecision(*NewFailOpenResult(), nil))
+	}
+	return nil, fmt.Errorf("%w: %v", ErrPanic, recovered)
+}
+
+// recoverCallback recovers a panic in a callback that is only told about a
+// decision already made (Observer, OnKeyCreated, OnExhausted), so the
+// decision stands. The panic is reported to Config.OnPanic. Must be deferred.
+func (c *Config) recoverCallback() {
+	if recovered := recover(); recovered != nil {
+		c.reportPanic(recovered, debug.Stack())
+	}
+}
+
+// reportPanic calls Config.OnPanic, if set. A panic in OnPanic itself is
+// dropped, since there is nothing left to report it to.
+func (c *Config) reportPanic(recovered any, stack []byte) {
+	if c.OnPanic == nil {
+		return
+	}
+	defer func() { _ = recover() }()
+	c.OnPanic(recovered, stack)
+}
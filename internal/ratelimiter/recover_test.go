@@ -0,0 +1,201 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingObserver is an Observer with a bug.
+type panickingObserver struct{}
+
+func (panickingObserver) ObserveDecision(context.Context, Observation) {
+	panic("observer bug")
+}
+
+// panicRecorder captures what Config.OnPanic is called with.
+type panicRecorder struct {
+	values []any
+	stacks [][]byte
+}
+
+func (p *panicRecorder) record(recovered any, stack []byte) {
+	p.values = append(p.values, recovered)
+	p.stacks = append(p.stacks, stack)
+}
+
+func TestRecover_PanickingObserver(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		var panics panicRecorder
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     5,
+			Window:    time.Hour,
+			Observer:  panickingObserver{},
+			OnPanic:   panics.record,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		// The decision stands despite the Observer panicking
+		result, err := limiter.Allow(context.Background(), "user:1")
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(4), result.Remaining)
+
+		require.Len(t, panics.values, 1)
+		assert.Equal(t, "observer bug", panics.values[0])
+		assert.Contains(t, string(panics.stacks[0]), "ObserveDecision")
+	})
+}
+
+func TestRecover_PanickingLimitResolver(t *testing.T) {
+	resolver := func(string) int64 { panic("resolver bug") }
+
+	t.Run("fail closed", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		var panics panicRecorder
+		limiter, err := NewFixedWindow(client, &Config{
+			Algorithm:     FixedWindow,
+			Limit:         5,
+			Window:        time.Hour,
+			LimitResolver: resolver,
+			OnPanic:       panics.record,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, err := limiter.Allow(context.Background(), "user:1")
+		assert.ErrorIs(t, err, ErrPanic)
+		assert.Nil(t, result)
+		assert.Equal(t, []any{"resolver bug"}, panics.values)
+
+		value, err := limiter.(ValueLimiter).AllowValue(context.Background(), "user:1")
+		assert.ErrorIs(t, err, ErrPanic)
+		assert.Equal(t, Result{}, value)
+	})
+
+	t.Run("fail open", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewTokenBucket(client, &Config{
+			Algorithm:     TokenBucket,
+			Limit:         5,
+			Window:        time.Hour,
+			FailOpen:      true,
+			LimitResolver: resolver,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		// Without OnPanic the panic is recovered silently
+		result, err := limiter.Allow(context.Background(), "user:1")
+		require.NoError(t, err)
+		want := NewFailOpenResult()
+		want.Algorithm = TokenBucket
+		want.Atomic = true
+		assert.Equal(t, want, result)
+	})
+}
+
+func TestRecover_PanickingLimitResolverOutsideAllow(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	var panics panicRecorder
+	broken := false
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+		LimitResolver: func(string) int64 {
+			if broken {
+				panic("resolver bug")
+			}
+			return 5
+		},
+		OnPanic: panics.record,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// UsedMany only resolves the limit of keys that have state
+	ctx := context.Background()
+	_, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	broken = true
+
+	result, err := limiter.(HintedLimiter).AllowHinted(ctx, "user:1", 1, 0)
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Nil(t, result)
+
+	result, err = limiter.(Peeker).Peek(ctx, "user:1")
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Nil(t, result)
+
+	_, err = limiter.(UsageReader).UsedMany(ctx, []string{"user:1"})
+	assert.ErrorIs(t, err, ErrPanic)
+
+	assert.Len(t, panics.values, 3)
+}
+
+func TestRecover_PanickingWindowResolverInReconcile(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	var panics panicRecorder
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:      FixedWindow,
+		Limit:          5,
+		Window:         time.Hour,
+		WindowResolver: func(string) time.Duration { panic("resolver bug") },
+		OnPanic:        panics.record,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	err = limiter.(Reconciler).Reconcile(context.Background(), "user:1", 3)
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Equal(t, []any{"resolver bug"}, panics.values)
+}
+
+func TestRecover_PanickingNotifications(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	var panics panicRecorder
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:    SlidingWindow,
+		Limit:        1,
+		Window:       time.Hour,
+		OnKeyCreated: func(string) { panic("created bug") },
+		OnExhausted:  func(string, time.Time) { panic("exhausted bug") },
+		OnPanic: func(recovered any, stack []byte) {
+			panics.record(recovered, stack)
+			panic("OnPanic bug")
+		},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	assert.Equal(t, []any{"created bug", "exhausted bug"}, panics.values)
+}
@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry holds named limiters, e.g. one per API tier or endpoint, so they
+// can be looked up and inspected by name (see DebugHandler).
+//
+// A Registry is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	mu       sync.RWMutex
+	limiters map[string]RateLimiter
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		limiters: make(map[string]RateLimiter),
+	}
+}
+
+// Register adds limiter under name.
+// Returns an error if name is empty, limiter is nil, or name is already registered.
+func (r *Registry) Register(name string, limiter RateLimiter) error {
+	if name == "" {
+		return fmt.Errorf("limiter name cannot be empty")
+	}
+	if limiter == nil {
+		return fmt.Errorf("limiter cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.limiters[name]; exists {
+		return fmt.Errorf("limiter %q is already registered", name)
+	}
+	r.limiters[name] = limiter
+	return nil
+}
+
+// Get returns the limiter registered under name.
+func (r *Registry) Get(name string) (RateLimiter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limiter, ok := r.limiters[name]
+	return limiter, ok
+}
+
+// Names returns the names of all registered limiters, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.limiters))
+	for name := range r.limiters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,29 @@
+package ratelimiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	require.NoError(t, registry.Register("search", NewNoop()))
+	require.NoError(t, registry.Register("api", NewNoop()))
+
+	err := registry.Register("api", NewNoop())
+	assert.ErrorContains(t, err, `limiter "api" is already registered`)
+	assert.Error(t, registry.Register("", NewNoop()))
+	assert.Error(t, registry.Register("empty", nil))
+
+	limiter, ok := registry.Get("api")
+	assert.True(t, ok)
+	assert.NotNil(t, limiter)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"api", "search"}, registry.Names())
+}
@@ -0,0 +1,27 @@
+package ratelimiter
+
+import "context"
+
+// criticalKey is the context key marking a request as critical
+type criticalKey struct{}
+
+// WithCritical returns a copy of ctx marking requests made with it as
+// critical, so they may use the quota held back by Config.ReserveForCritical.
+func WithCritical(ctx context.Context) context.Context {
+	return context.WithValue(ctx, criticalKey{}, true)
+}
+
+// isCritical reports whether ctx was marked with WithCritical.
+func isCritical(ctx context.Context) bool {
+	critical, _ := ctx.Value(criticalKey{}).(bool)
+	return critical
+}
+
+// reserveFor returns the quota the request made with ctx must leave
+// untouched: Config.ReserveForCritical, or 0 for critical requests.
+func (c *Config) reserveFor(ctx context.Context) int64 {
+	if c.ReserveForCritical == 0 || isCritical(ctx) {
+		return 0
+	}
+	return c.ReserveForCritical
+}
@@ -0,0 +1,109 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveForCritical(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm:          tt.algorithm,
+			Limit:              5,
+			Window:             time.Hour,
+			ReserveForCritical: 2,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		assertReserveHeldBack(t, limiter)
+	})
+}
+
+func TestReserveForCritical_AlignedToFirstRequest(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:          FixedWindow,
+		Limit:              5,
+		Window:             time.Hour,
+		Alignment:          AlignedToFirstRequest,
+		ReserveForCritical: 2,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	assertReserveHeldBack(t, limiter)
+}
+
+// assertReserveHeldBack checks that a limiter with Limit 5 and
+// ReserveForCritical 2 keeps the last 2 requests for critical ones.
+func assertReserveHeldBack(t *testing.T, limiter RateLimiter) {
+	t.Helper()
+
+	ctx := context.Background()
+	critical := WithCritical(ctx)
+
+	for i := range 3 {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d", i+1)
+	}
+
+	// Remaining has reached the reserve: only critical requests get through,
+	// and the denials report the quota that is really left
+	for range 3 {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, ReasonLimitExceeded, result.Reason)
+		assert.Equal(t, int64(2), result.Remaining)
+		assert.Zero(t, result.Overage)
+	}
+
+	// Denied requests didn't eat into the reserve
+	for i := range 2 {
+		result, err := limiter.Allow(critical, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "critical request %d", i+1)
+		assert.Equal(t, int64(1-i), result.Remaining)
+	}
+
+	result, err := limiter.Allow(critical, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "the reserve is used up")
+}
+
+func TestReserveForCritical_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"below limit", Config{Algorithm: FixedWindow, Limit: 5, Window: time.Minute, ReserveForCritical: 4}, false},
+		{"negative", Config{Algorithm: FixedWindow, Limit: 5, Window: time.Minute, ReserveForCritical: -1}, true},
+		{"equal to limit", Config{Algorithm: TokenBucket, Limit: 5, Window: time.Minute, ReserveForCritical: 5}, true},
+		{"concurrency", Config{Algorithm: Concurrency, Limit: 5, Window: time.Minute, ReserveForCritical: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				var validationErr *ValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, "ReserveForCritical", validationErr.Field)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
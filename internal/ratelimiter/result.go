@@ -1,6 +1,10 @@
 package ratelimiter
 
-import "time"
+import (
+	"math"
+	"math/rand"
+	"time"
+)
 
 // NewAllowedResult creates a Result for an allowed request
 func NewAllowedResult(limit, remaining int64, resetAt time.Time) *Result {
@@ -47,3 +51,153 @@ func NewFailClosedResult() *Result {
 		ResetAt:    time.Time{},
 	}
 }
+
+// requestsUntilDenied estimates how many requests of the key's typical cost
+// (Config.RequestCost) fit in remaining.
+func (c *Config) requestsUntilDenied(key string, remaining int64) int64 {
+	if c.RequestCost == nil {
+		return remaining
+	}
+	cost := c.RequestCost(key)
+	if cost < 1 {
+		return remaining
+	}
+	return remaining / cost
+}
+
+// peekResult creates the Result reported by Peek for a window algorithm
+// where used of limit requests have been counted. Allowed reports whether one
+// more request would fit.
+func (c *Config) peekResult(limit int64, used float64, resetAt, now time.Time) *Result {
+	remaining := limit - int64(used)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	result := &Result{
+		Allowed:   used+1 <= float64(limit),
+		Limit:     limit,
+		Remaining: remaining,
+		Overage:   overage(used, limit),
+		ResetAt:   resetAt,
+	}
+	if !result.Allowed {
+		result.RetryAfter = resetAt.Sub(now)
+		if result.RetryAfter < 0 {
+			result.RetryAfter = 0
+		}
+		result.RetryAfter = c.roundRetryAfter(result.RetryAfter)
+	}
+	return result
+}
+
+// overage returns how far the recorded usage is over the limit, in whole
+// requests, for Result.Overage.
+func overage(used float64, limit int64) int64 {
+	return max(0, int64(used)-limit)
+}
+
+// resultPtr converts a decision made by value into the pointer form returned
+// by AllowN. Failed decisions return a nil Result.
+func resultPtr(result Result, err error) (*Result, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// stampDecision records on a decision made by AllowN (or another
+// all-or-nothing entry point) which algorithm made it and that it was
+// all-or-nothing.
+func (c *Config) stampDecision(result Result, err error) (Result, error) {
+	if err == nil {
+		result.Algorithm = c.Algorithm
+		result.Atomic = true
+	}
+	return result, err
+}
+
+// resultValue converts a decision returned by AllowN into value form.
+// A nil Result (failed decision) becomes the zero Result.
+func resultValue(result *Result, err error) (Result, error) {
+	if result == nil {
+		return Result{}, err
+	}
+	return *result, err
+}
+
+// Clone returns a copy of the Result that can be modified without affecting r
+// Cached results are returned as clones so that callers can't mutate shared state
+// Returns nil if r is nil
+func (r *Result) Clone() *Result {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	return &clone
+}
+
+// RoundRetryAfter rounds d up to the next whole second, e.g. 4.1s to 5s
+// Whole seconds are unchanged and durations <= 0 become 0
+func RoundRetryAfter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return (d + time.Second - 1).Truncate(time.Second)
+}
+
+// RetryAfterSeconds returns RetryAfter in whole seconds, rounded up, as sent
+// in an HTTP Retry-After header
+func (r *Result) RetryAfterSeconds() int64 {
+	return int64(RoundRetryAfter(r.RetryAfter) / time.Second)
+}
+
+// PercentRemaining returns Remaining as a whole percentage of Limit, rounded
+// down and clamped to [0, 100], e.g. for a quota bar in a UI
+// Returns 100 when there is no limit to report (Limit <= 0), as for fail-open results
+func (r *Result) PercentRemaining() int {
+	switch {
+	case r.Limit <= 0 || r.Remaining >= r.Limit:
+		return 100
+	case r.Remaining <= 0:
+		return 0
+	}
+	// In floating point, since 100*Remaining could overflow for huge limits
+	return int(100 * float64(r.Remaining) / float64(r.Limit))
+}
+
+// roundRetryAfter applies Config.RoundRetryAfter to a RetryAfter duration
+func (c *Config) roundRetryAfter(d time.Duration) time.Duration {
+	if c.RoundRetryAfter {
+		return RoundRetryAfter(d)
+	}
+	return d
+}
+
+// JitteredRetryAfter returns RetryAfter plus a random jitter in [0, maxJitter]
+// Spreading retries out prevents denied clients from retrying in lockstep
+// If rng is nil, the shared math/rand source is used
+// The sum saturates at the largest time.Duration rather than overflowing
+func (r *Result) JitteredRetryAfter(maxJitter time.Duration, rng *rand.Rand) time.Duration {
+	if maxJitter <= 0 {
+		return r.RetryAfter
+	}
+
+	// Int63n takes the exclusive bound, which maxJitter+1 would overflow at the maximum
+	bound := int64(maxJitter)
+	if bound < math.MaxInt64 {
+		bound++
+	}
+
+	var jitter int64
+	if rng != nil {
+		jitter = rng.Int63n(bound)
+	} else {
+		jitter = rand.Int63n(bound)
+	}
+
+	if r.RetryAfter > time.Duration(math.MaxInt64-jitter) {
+		return time.Duration(math.MaxInt64)
+	}
+	return r.RetryAfter + time.Duration(jitter)
+}
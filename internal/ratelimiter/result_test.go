@@ -1,8 +1,14 @@
 package ratelimiter
 
 import (
+	"context"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewAllowedResult(t *testing.T) {
@@ -92,3 +98,309 @@ func TestNewFailClosedResult(t *testing.T) {
 		t.Errorf("ResetAt = %v, want zero time", result.ResetAt)
 	}
 }
+
+func TestResult_JitteredRetryAfter(t *testing.T) {
+	result := NewDeniedResult(100, 5*time.Second, time.Now().Add(time.Minute))
+	maxJitter := 2 * time.Second
+	rng := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 1000; i++ {
+		got := result.JitteredRetryAfter(maxJitter, rng)
+		if got < result.RetryAfter || got > result.RetryAfter+maxJitter {
+			t.Fatalf("JitteredRetryAfter() = %v, want within [%v, %v]", got, result.RetryAfter, result.RetryAfter+maxJitter)
+		}
+	}
+
+	// Nil source falls back to the shared generator
+	got := result.JitteredRetryAfter(maxJitter, nil)
+	if got < result.RetryAfter || got > result.RetryAfter+maxJitter {
+		t.Errorf("JitteredRetryAfter(nil rng) = %v, want within [%v, %v]", got, result.RetryAfter, result.RetryAfter+maxJitter)
+	}
+
+	// No jitter requested
+	if got := result.JitteredRetryAfter(0, rng); got != result.RetryAfter {
+		t.Errorf("JitteredRetryAfter(0) = %v, want %v", got, result.RetryAfter)
+	}
+	if got := result.JitteredRetryAfter(-time.Second, rng); got != result.RetryAfter {
+		t.Errorf("JitteredRetryAfter(-1s) = %v, want %v", got, result.RetryAfter)
+	}
+
+	// The largest jitter neither panics nor wraps around to a negative duration
+	maxDuration := time.Duration(math.MaxInt64)
+	for i := 0; i < 100; i++ {
+		if got := result.JitteredRetryAfter(maxDuration, rng); got < result.RetryAfter {
+			t.Fatalf("JitteredRetryAfter(MaxInt64) = %v, want >= %v", got, result.RetryAfter)
+		}
+	}
+}
+
+func TestResult_Clone(t *testing.T) {
+	original := &Result{
+		Allowed:    false,
+		Limit:      10,
+		Remaining:  0,
+		RetryAfter: time.Second,
+		ResetAt:    time.Unix(1700000000, 0),
+		DeniedBy:   "user:1",
+	}
+
+	clone := original.Clone()
+	if clone == original {
+		t.Fatal("Clone() returned the same pointer")
+	}
+	if *clone != *original {
+		t.Errorf("Clone() = %+v, want %+v", *clone, *original)
+	}
+
+	// Mutating the clone leaves the original untouched
+	clone.Allowed = true
+	clone.Remaining = 5
+	clone.DeniedBy = ""
+	if original.Allowed || original.Remaining != 0 || original.DeniedBy != "user:1" {
+		t.Errorf("original mutated through clone: %+v", *original)
+	}
+
+	var nilResult *Result
+	if nilResult.Clone() != nil {
+		t.Error("Clone() of nil Result should be nil")
+	}
+}
+
+func TestRoundRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Duration
+		want time.Duration
+	}{
+		{"fraction rounds up", 4100 * time.Millisecond, 5 * time.Second},
+		{"just over a second", time.Second + time.Nanosecond, 2 * time.Second},
+		{"whole seconds unchanged", 4 * time.Second, 4 * time.Second},
+		{"sub-second rounds to one", time.Millisecond, time.Second},
+		{"zero", 0, 0},
+		{"negative", -time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RoundRetryAfter(tt.in); got != tt.want {
+				t.Errorf("RoundRetryAfter(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_RetryAfterSeconds(t *testing.T) {
+	result := &Result{RetryAfter: 4100 * time.Millisecond}
+	if got := result.RetryAfterSeconds(); got != 5 {
+		t.Errorf("RetryAfterSeconds() = %d, want 5", got)
+	}
+
+	result = &Result{Allowed: true}
+	if got := result.RetryAfterSeconds(); got != 0 {
+		t.Errorf("RetryAfterSeconds() = %d, want 0", got)
+	}
+}
+
+func TestResult_PercentRemaining(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		remaining int64
+		want      int
+	}{
+		{"quarter used", 100, 75, 75},
+		{"rounded down", 3, 2, 66},
+		{"exhausted", 100, 0, 0},
+		{"full", 100, 100, 100},
+		{"remaining above limit after a limit change", 10, 20, 100},
+		{"negative remaining", 10, -1, 0},
+		{"zero limit", 0, 0, 100},
+		{"huge limit", math.MaxInt64, math.MaxInt64 / 2, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &Result{Limit: tt.limit, Remaining: tt.remaining}
+			if got := result.PercentRemaining(); got != tt.want {
+				t.Errorf("PercentRemaining() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_CheckDuration(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     1,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		for _, allowed := range []bool{true, false} {
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, allowed, result.Allowed)
+			assert.Positive(t, result.CheckDuration)
+			assert.Less(t, result.CheckDuration, time.Second)
+		}
+	})
+}
+
+func TestResult_Atomic(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     5,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		// Both an admitted and a denied AllowN are all-or-nothing
+		for _, n := range []int64{3, 3} {
+			result, err := limiter.AllowN(ctx, "user:1", n)
+			require.NoError(t, err)
+			assert.True(t, result.Atomic)
+		}
+
+		value, err := limiter.(ValueLimiter).AllowValueN(ctx, "user:1", 1)
+		require.NoError(t, err)
+		assert.True(t, value.Atomic)
+
+		// So is a hinted request served without Redis
+		result, err := limiter.(HintedLimiter).AllowHinted(ctx, "user:2", 1, 0)
+		require.NoError(t, err)
+		assert.True(t, result.Atomic)
+		assert.Equal(t, tt.algorithm, result.Algorithm)
+	})
+
+	assert.False(t, NewFailOpenResult().Atomic)
+}
+
+func TestResult_Atomic_Overflow(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	overflow := limiter.(OverflowLimiter)
+	for _, n := range []int64{5, 5, 1} {
+		result, err := overflow.AllowWithOverflow(context.Background(), []string{"user:1", "user:2"}, n)
+		require.NoError(t, err)
+		assert.True(t, result.Atomic)
+		assert.Equal(t, TokenBucket, result.Algorithm)
+	}
+}
+
+func TestResult_Atomic_Hierarchical(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewHierarchical(client, []Config{
+		{Algorithm: FixedWindow, Limit: 5, Window: time.Hour, Prefix: "user"},
+		{Algorithm: FixedWindow, Limit: 3, Window: time.Hour, Prefix: "org"},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	// Every level is charged or none is, whether the request is admitted or not
+	for _, allowed := range []bool{true, false} {
+		result, err := limiter.AllowN(context.Background(), []string{"{acme}:alice", "{acme}"}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed)
+		assert.True(t, result.Atomic)
+		assert.Equal(t, FixedWindow, result.Algorithm)
+	}
+}
+
+func TestResult_Atomic_Concurrency(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewConcurrency(client, &Config{
+		Algorithm: Concurrency,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	// A slot is claimed or not
+	for _, allowed := range []bool{true, false} {
+		result, _, err := limiter.Acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed)
+		assert.True(t, result.Atomic)
+		assert.Equal(t, Concurrency, result.Algorithm)
+	}
+}
+
+func TestResult_Algorithm(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     1,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		for _, allowed := range []bool{true, false} {
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, allowed, result.Allowed)
+			assert.Equal(t, tt.algorithm, result.Algorithm)
+		}
+	})
+
+	t.Run(string(Concurrency), func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewConcurrency(client, &Config{Algorithm: Concurrency, Limit: 1, Window: time.Minute})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, release, err := limiter.Acquire(context.Background(), "job:1")
+		require.NoError(t, err)
+		defer release()
+		assert.Equal(t, Concurrency, result.Algorithm)
+	})
+
+	t.Run("hierarchical", func(t *testing.T) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := NewHierarchical(client, []Config{
+			{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, Prefix: "user"},
+			{Algorithm: FixedWindow, Limit: 100, Window: time.Minute, Prefix: "org"},
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		result, err := limiter.Allow(context.Background(), []string{"u1", "o1"})
+		require.NoError(t, err)
+		assert.Equal(t, FixedWindow, result.Algorithm)
+	})
+
+	assert.Empty(t, NewFailOpenResult().Algorithm)
+}
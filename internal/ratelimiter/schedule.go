@@ -0,0 +1,85 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ScheduleWindow is a recurring time-of-day window on some days of the week
+// during which a limiter enforces its limit (see Config.ActiveSchedule).
+type ScheduleWindow struct {
+	// Days are the weekdays the window applies to
+	// Optional: empty means every day
+	Days []time.Weekday
+
+	// Start is when the window opens, as an offset from midnight (inclusive)
+	Start time.Duration
+
+	// End is when the window closes, as an offset from midnight (exclusive)
+	// Must be after Start and at most 24h; a window crossing midnight is
+	// written as two windows, one on each day
+	End time.Duration
+}
+
+// contains reports whether t, in the schedule's location, falls in the window.
+func (w ScheduleWindow) contains(t time.Time) bool {
+	if len(w.Days) > 0 && !slices.Contains(w.Days, t.Weekday()) {
+		return false
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	return offset >= w.Start && offset < w.End
+}
+
+// validate checks that the window's days and times are in range.
+func (w ScheduleWindow) validate() error {
+	for _, day := range w.Days {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("unknown weekday: %d", day)
+		}
+	}
+	if w.Start < 0 || w.End > 24*time.Hour || w.Start >= w.End {
+		return fmt.Errorf("window must satisfy 0 <= Start < End <= 24h, got: %v to %v", w.Start, w.End)
+	}
+	return nil
+}
+
+// scheduleActive reports whether the limit is enforced at t: always without
+// an ActiveSchedule, otherwise only within one of its windows.
+func (c *Config) scheduleActive(t time.Time) bool {
+	if len(c.ActiveSchedule) == 0 {
+		return true
+	}
+	location := c.ScheduleLocation
+	if location == nil {
+		location = time.UTC
+	}
+	t = t.In(location)
+	for _, window := range c.ActiveSchedule {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// outsideSchedule returns an allowed result when the limit is not enforced
+// right now (see Config.ActiveSchedule). Such requests are decided without
+// touching Redis, so they use no quota. Denylisted keys stay denied.
+func (c *Config) outsideSchedule(key string, limit int64) (Result, bool) {
+	now := time.Now
+	if c.scheduleNow != nil {
+		now = c.scheduleNow
+	}
+	if c.denylisted(key) || c.scheduleActive(now()) {
+		return Result{}, false
+	}
+
+	return Result{
+		Allowed:   true,
+		Limit:     limit,
+		Remaining: limit,
+		Reason:    ReasonOutsideSchedule,
+	}, true
+}
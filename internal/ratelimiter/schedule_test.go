@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+func businessHours(location *time.Location) *Config {
+	return &Config{
+		Algorithm:        FixedWindow,
+		Limit:            1,
+		Window:           time.Minute,
+		ActiveSchedule:   []ScheduleWindow{{Days: weekdays, Start: 9 * time.Hour, End: 17 * time.Hour}},
+		ScheduleLocation: location,
+	}
+}
+
+func TestScheduleActive_FixedTimes(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	cfg := businessHours(newYork)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		active bool
+	}{
+		{"weekday noon", time.Date(2024, 3, 13, 12, 0, 0, 0, newYork), true},
+		{"opening time", time.Date(2024, 3, 13, 9, 0, 0, 0, newYork), true},
+		{"closing time", time.Date(2024, 3, 13, 17, 0, 0, 0, newYork), false},
+		{"weekday night", time.Date(2024, 3, 13, 22, 0, 0, 0, newYork), false},
+		{"weekend noon", time.Date(2024, 3, 16, 12, 0, 0, 0, newYork), false},
+		// 15:00 UTC is 11:00 in New York
+		{"other time zone", time.Date(2024, 3, 13, 15, 0, 0, 0, time.UTC), true},
+		// 21:30 UTC is 17:30 in New York
+		{"after hours in schedule zone", time.Date(2024, 3, 13, 21, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.active, cfg.scheduleActive(tt.at))
+		})
+	}
+
+	assert.True(t, (&Config{}).scheduleActive(time.Date(2024, 3, 16, 3, 0, 0, 0, time.UTC)), "no schedule is always active")
+}
+
+func TestActiveSchedule_OutsideAllowsWithoutConsuming(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// Saturday noon is outside business hours
+	cfg := businessHours(nil)
+	cfg.scheduleNow = func() time.Time { return time.Date(2024, 3, 16, 12, 0, 0, 0, time.UTC) }
+	limiter, err := NewFixedWindow(client, cfg)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, ReasonOutsideSchedule, result.Reason)
+		assert.Equal(t, int64(1), result.Remaining)
+	}
+	assert.Empty(t, mr.Keys(), "requests outside the schedule should not touch Redis")
+}
+
+func TestActiveSchedule_InsideEnforcesLimit(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	// Wednesday noon is within business hours
+	cfg := businessHours(nil)
+	cfg.scheduleNow = func() time.Time { return time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC) }
+	limiter, err := NewFixedWindow(client, cfg)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Empty(t, result.Reason)
+
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonLimitExceeded, result.Reason)
+}
+
+func TestActiveSchedule_Validate(t *testing.T) {
+	for _, window := range []ScheduleWindow{
+		{Start: 17 * time.Hour, End: 9 * time.Hour},
+		{Start: -time.Hour, End: time.Hour},
+		{Start: 0, End: 25 * time.Hour},
+		{Days: []time.Weekday{7}, Start: 0, End: time.Hour},
+	} {
+		cfg := businessHours(nil)
+		cfg.ActiveSchedule = []ScheduleWindow{window}
+		err := cfg.Validate()
+		require.Error(t, err, "%+v", window)
+		assert.Equal(t, "ActiveSchedule", ValidationErrors(err)[0].Field)
+	}
+
+	cfg := businessHours(nil)
+	cfg.Algorithm = Concurrency
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,101 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableClient returns a client whose every command fails, so a test
+// can assert that a code path returned before talking to Redis.
+func unreachableClient(t *testing.T) (*redis.Client, *int) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:               "127.0.0.1:0",
+		MaxRetries:         -1,
+		DialerRetries:      1,
+		DialerRetryTimeout: time.Millisecond,
+	})
+	calls := 0
+	client.AddHook(countingHook{calls: &calls})
+	t.Cleanup(func() { client.Close() })
+	return client, &calls
+}
+
+// countingHook counts commands sent through a client.
+type countingHook struct {
+	calls *int
+}
+
+func (h countingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h countingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		*h.calls++
+		return next(ctx, cmd)
+	}
+}
+
+func (h countingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		*h.calls += len(cmds)
+		return next(ctx, cmds)
+	}
+}
+
+func TestScriptGuard_RejectsExcessiveSubWindows(t *testing.T) {
+	client, calls := unreachableClient(t)
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:  SlidingWindow,
+		Limit:      100,
+		Window:     10000 * time.Second,
+		SubWindows: 10000,
+	})
+	require.Error(t, err)
+	assert.Nil(t, limiter)
+	assert.Contains(t, err.Error(), "sub-windows must be between 1 and 60")
+	assert.Equal(t, 0, *calls, "config must be rejected before any Redis interaction")
+}
+
+func TestScriptGuard_RejectsTooManyHierarchicalLevels(t *testing.T) {
+	client, calls := unreachableClient(t)
+
+	levels := make([]Config, MaxScriptKeys+1)
+	for i := range levels {
+		levels[i] = Config{Algorithm: FixedWindow, Limit: 10, Window: time.Minute, Prefix: fmt.Sprintf("level%d", i)}
+	}
+
+	limiter, err := NewHierarchical(client, levels)
+	assert.ErrorIs(t, err, ErrTooManyKeys)
+	assert.Nil(t, limiter)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestScriptGuard_RejectsTooManyOverflowKeys(t *testing.T) {
+	client, calls := unreachableClient(t)
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	keys := make([]string, MaxScriptKeys+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bucket:%d", i)
+	}
+
+	_, err = limiter.(OverflowLimiter).AllowWithOverflow(context.Background(), keys, 1)
+	assert.ErrorIs(t, err, ErrTooManyKeys)
+	assert.Equal(t, 0, *calls)
+}
@@ -0,0 +1,43 @@
+package ratelimiter
+
+// LuaScript is the source of a Lua script a limiter runs on Redis, with the
+// SHA1 Redis identifies it by.
+type LuaScript struct {
+	// Source is the exact script body sent to Redis
+	Source string
+
+	// SHA1 is the hex digest used by EVALSHA and returned by SCRIPT LOAD
+	SHA1 string
+}
+
+// LuaScripts returns every Lua script the limiters run, keyed by algorithm,
+// with a suffix for algorithms that use more than one script
+// (e.g. "fixed_window/aligned"). It is meant for auditing what runs on Redis
+// and for preloading the scripts with SCRIPT LOAD.
+//
+// The returned map is a new copy on every call.
+func LuaScripts() map[string]LuaScript {
+	sources := map[string]string{
+		string(FixedWindow):                            fixedWindowScript,
+		string(FixedWindow) + "/aligned":               alignedWindowScript,
+		string(FixedWindow) + "/compare_and_increment": compareAndIncrementScript,
+		string(FixedWindow) + "/allow_then_reset":      allowThenResetScript,
+		string(TokenBucket):                            tokenBucketScript,
+		string(TokenBucket) + "/overflow":              tokenBucketOverflowScript,
+		string(TokenBucket) + "/adjust":                tokenBucketAdjustScript,
+		string(SlidingWindow):                          slidingWindowScript,
+		string(Concurrency) + "/acquire":               concurrencyAcquireScript,
+		string(Concurrency) + "/release":               concurrencyReleaseScript,
+		"hierarchical":                                 hierarchicalScript,
+		"adjust_counter":                               adjustCounterScript,
+		"warmup":                                       warmupScript,
+		"adaptive_outcome":                             adaptiveOutcomeScript,
+		"composite_key_mapping":                        compositeKeyMappingScript,
+	}
+
+	scripts := make(map[string]LuaScript, len(sources))
+	for name, source := range sources {
+		scripts[name] = LuaScript{Source: source, SHA1: scriptSHA(source)}
+	}
+	return scripts
+}
@@ -0,0 +1,122 @@
+package ratelimiter
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLuaScripts_SHAMatchesRedis(t *testing.T) {
+	scripts := LuaScripts()
+	for _, name := range []string{
+		string(FixedWindow),
+		string(TokenBucket),
+		string(SlidingWindow),
+		string(Concurrency) + "/acquire",
+		"hierarchical",
+	} {
+		assert.Contains(t, scripts, name)
+	}
+
+	for name, script := range scripts {
+		assert.NotEmpty(t, script.Source, name)
+		assert.Equal(t, redis.NewScript(script.Source).Hash(), script.SHA1, name)
+	}
+}
+
+// TestLuaScripts_Complete fails when a script constant (any string constant
+// named *Script in the package) is missing from LuaScripts.
+func TestLuaScripts_Complete(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	sources := make(map[string]bool)
+	for _, script := range LuaScripts() {
+		sources[script.Source] = true
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range parsed.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for i, name := range spec.(*ast.ValueSpec).Names {
+					if !strings.HasSuffix(name.Name, "Script") {
+						continue
+					}
+					literal, ok := spec.(*ast.ValueSpec).Values[i].(*ast.BasicLit)
+					require.True(t, ok, "%s must be a string literal", name.Name)
+					source, err := strconv.Unquote(literal.Value)
+					require.NoError(t, err, name.Name)
+					assert.True(t, sources[source], "%s is missing from LuaScripts", name.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestLuaScripts_Preload(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	for name, script := range LuaScripts() {
+		sha, err := client.ScriptLoad(ctx, script.Source).Result()
+		require.NoError(t, err, name)
+		assert.Equal(t, script.SHA1, sha, name)
+	}
+}
+
+func TestLuaScripts_ReturnsCopy(t *testing.T) {
+	scripts := LuaScripts()
+	delete(scripts, string(FixedWindow))
+
+	assert.Contains(t, LuaScripts(), string(FixedWindow))
+}
+
+// TestLuaScripts_SurviveScriptFlush simulates a failover to a replica with an
+// empty script cache: decisions must keep working without NOSCRIPT errors.
+func TestLuaScripts_SurviveScriptFlush(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm: tt.algorithm,
+			Limit:     10,
+			Window:    time.Hour,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		_, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+
+		require.NoError(t, client.ScriptFlush(ctx).Err())
+
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(8), result.Remaining)
+	})
+}
@@ -3,38 +3,122 @@ package ratelimiter
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 const (
-	// slidingWindowScript atomically retrieves previous and current window counts,
-	// increments current count, and sets appropriate TTLs.
+	// slidingWindowScript atomically retrieves the counts of every sub-window
+	// covering the sliding window, increments the current one, and sets appropriate TTLs.
 	//
-	// KEYS[1]: Current window key
-	// KEYS[2]: Previous window key
+	// KEYS[1..#KEYS-1]: Older sub-window keys, oldest first
+	// KEYS[#KEYS]: Current sub-window key
 	// ARGV[1]: Increment amount (n)
-	// ARGV[2]: Current window TTL in seconds
-	// ARGV[3]: Previous window TTL in seconds
+	// ARGV[2]: Current sub-window TTL in milliseconds
+	// ARGV[3]: Previous sub-window TTL in milliseconds, refreshed on every call (0 to skip)
+	// ARGV[4]: Refresh the previous TTL only when below this fraction of ARGV[3] (0 = always)
+	// ARGV[5]: Weight of the oldest sub-window in the weighted count
+	// ARGV[6]: The limit
+	// ARGV[7]: Quota the request must leave for critical requests (0 = none)
 	//
-	// Returns: {previous_count, current_count}
+	// Returns: {count for each key oldest first..., created (0/1), held (0/1)}
+	// With a granularity of 1 this is {previous_count, current_count, created, held}.
+	// created is 1 when this call created the current sub-window counter.
+	// A request that would dip into the reserve is denied without counting, so
+	// such denials can't use up the reserve; held is then 1 and the counts
+	// are the stored ones.
 	slidingWindowScript = `
-local prev = tonumber(redis.call('GET', KEYS[2]) or 0)
-local curr = redis.call('INCRBY', KEYS[1], ARGV[1])
+local counts = {}
+for i = 1, #KEYS - 1 do
+    counts[i] = tonumber(redis.call('GET', KEYS[i]) or 0)
+end
+
+local n = tonumber(ARGV[1])
+local reserve = tonumber(ARGV[7])
+if reserve > 0 then
+    local curr = tonumber(redis.call('GET', KEYS[#KEYS]) or 0)
+    local weighted = counts[1] * tonumber(ARGV[5]) + curr + n
+    for i = 2, #KEYS - 1 do
+        weighted = weighted + counts[i]
+    end
+    if weighted > tonumber(ARGV[6]) - reserve then
+        counts[#KEYS] = curr
+        counts[#KEYS + 1] = 0
+        counts[#KEYS + 2] = 1
+        return counts
+    end
+end
+
+local created = 0
+local curr = redis.call('INCRBY', KEYS[#KEYS], ARGV[1])
 if curr == tonumber(ARGV[1]) then
-    redis.call('EXPIRE', KEYS[1], ARGV[2])
+    redis.call('PEXPIRE', KEYS[#KEYS], ARGV[2])
+    created = 1
+end
+local prev_ttl = tonumber(ARGV[3])
+local refresh_below = tonumber(ARGV[4])
+if prev_ttl > 0 and #KEYS > 1 then
+    if refresh_below <= 0 or redis.call('PTTL', KEYS[#KEYS - 1]) < prev_ttl * refresh_below then
+        redis.call('PEXPIRE', KEYS[#KEYS - 1], prev_ttl)
+    end
 end
-redis.call('EXPIRE', KEYS[2], ARGV[3])
-return {prev, curr}
+counts[#KEYS] = curr
+counts[#KEYS + 1] = created
+counts[#KEYS + 2] = 0
+return counts
 `
 )
 
+// SlidingBucket is the count recorded for one bucket of a sliding window.
+type SlidingBucket struct {
+	// Start is when the bucket begins.
+	Start time.Time
+
+	// Count is the number of requests recorded in the bucket.
+	Count int64
+
+	// Weight is the factor applied to Count in the weighted count (0.0-1.0).
+	Weight float64
+}
+
+// SlidingState describes the counters behind a sliding window decision.
+type SlidingState struct {
+	// Buckets holds every bucket contributing to the weighted count, oldest first.
+	Buckets []SlidingBucket
+
+	// WeightedCount is the sum of each bucket's Count multiplied by its Weight.
+	WeightedCount float64
+
+	// Limit is the maximum weighted count allowed.
+	Limit int64
+
+	// LimitChangedAt is when SetLimit last changed the limit, zero if never.
+	LimitChangedAt time.Time
+}
+
 // slidingWindowLimiter implements the Sliding Window Counter algorithm.
 // It uses a weighted count from current and previous windows for smoother rate limiting.
+//
+// The window can be subdivided into sub-windows (see Config.SubWindows and
+// AllowGranular). With a granularity of g, the window is covered by the g most
+// recent sub-windows plus the one before them, weighted by how much of it still
+// overlaps the window. A granularity of 1 is the classic previous/current
+// window approximation; higher granularities track the true sliding count
+// more closely at the cost of more Redis keys per decision.
 type slidingWindowLimiter struct {
-	client *redis.Client
-	config *Config
+	client      *redis.Client
+	config      *Config
+	limit       *dynamicLimit
+	granularity int
+	stats       decisionStats
+
+	// estimate is nil unless Config.FailOpenEstimate is set
+	estimate *failOpenEstimate
+
+	// warmup is nil unless Config.ObserveFirst is set
+	warmup *warmupTracker
 }
 
 // NewSlidingWindow creates a new Sliding Window rate limiter.
@@ -52,9 +136,22 @@ func NewSlidingWindow(client *redis.Client, config *Config) (RateLimiter, error)
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	granularity := cfg.SubWindows
+	if granularity < 1 {
+		granularity = 1
+	}
+
 	return &slidingWindowLimiter{
-		client: client,
-		config: cfg,
+		client:      client,
+		config:      cfg,
+		limit:       newDynamicLimit(cfg.Limit),
+		granularity: granularity,
+		stats: decisionStats{
+			deniedKeys: newDeniedTracker(cfg.TrackTopDenied),
+			exhausted:  newExhaustionNotifier(cfg),
+		},
+		estimate: newFailOpenEstimate(cfg),
+		warmup:   newWarmupTracker(cfg),
 	}, nil
 }
 
@@ -64,80 +161,488 @@ func (s *slidingWindowLimiter) Allow(ctx context.Context, key string) (*Result,
 }
 
 // AllowN checks if N requests are allowed for the given key.
-// Uses sliding window algorithm with weighted count from previous and current windows.
-func (s *slidingWindowLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+// Reports the decision to Config.Observer when one is configured.
+func (s *slidingWindowLimiter) AllowN(ctx context.Context, key string, n int64) (result *Result, err error) {
+	defer s.config.recoverDecision(&result, &err)
+	return s.observeAllowN(ctx, key, n, s.config.keyLimit(key, s.limit.load()), s.granularity)
+}
+
+// AllowValue checks if a single request is allowed for the given key and
+// returns the Result by value, avoiding a heap allocation per call.
+func (s *slidingWindowLimiter) AllowValue(ctx context.Context, key string) (Result, error) {
+	return s.AllowValueN(ctx, key, 1)
+}
+
+// AllowValueN checks if N requests are allowed for the given key and returns
+// the Result by value, avoiding a heap allocation per call.
+func (s *slidingWindowLimiter) AllowValueN(ctx context.Context, key string, n int64) (result Result, err error) {
+	defer s.config.recoverDecisionValue(&result, &err)
+	if s.config.Observer != nil {
+		// Observers receive a *Result, so observed calls go through AllowN
+		return resultValue(s.AllowN(ctx, key, n))
+	}
+	return s.stats.forKey(key).recordValue(s.config.stampDecision(s.allowN(ctx, key, n, s.config.keyLimit(key, s.limit.load()), s.granularity)))
+}
+
+// AllowGranular checks if N requests are allowed for the given key, dividing
+// the window into the given number of sub-windows instead of Config.SubWindows.
+func (s *slidingWindowLimiter) AllowGranular(ctx context.Context, key string, n int64, granularity int) (result *Result, err error) {
+	defer s.config.recoverDecision(&result, &err)
+	if err := validateSubWindows(s.config.Window, granularity); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGranularity, err)
+	}
+	return s.observeAllowN(ctx, key, n, s.config.keyLimit(key, s.limit.load()), granularity)
+}
+
+// observeAllowN makes the decision and reports it to Config.Observer when one is configured.
+func (s *slidingWindowLimiter) observeAllowN(ctx context.Context, key string, n, limit int64, granularity int) (*Result, error) {
+	if s.config.Observer == nil {
+		return s.stats.forKey(key).record(resultPtr(s.config.stampDecision(s.allowN(ctx, key, n, limit, granularity))))
+	}
+
+	start := time.Now()
+	result, err := s.stats.forKey(key).record(resultPtr(s.config.stampDecision(s.allowN(ctx, key, n, limit, granularity))))
+	s.config.observeDecision(ctx, key, n, start, result, err)
+	return result, err
+}
+
+// recordDecision makes a decision other than AllowN's with decide, against
+// the key's limit, and counts and reports it as observeAllowN does.
+func (s *slidingWindowLimiter) recordDecision(ctx context.Context, key string, n int64, decide func(limit int64) (Result, error)) (*Result, error) {
+	start := time.Now()
+	result, err := s.stats.forKey(key).record(resultPtr(s.config.stampDecision(decide(s.config.keyLimit(key, s.limit.load())))))
+	if s.config.Observer != nil {
+		s.config.observeDecision(ctx, key, n, start, result, err)
+	}
+	return result, err
+}
+
+// allowN makes the rate limit decision for AllowN against the given limit.
+// Uses sliding window algorithm with weighted count from the sub-windows covering the window.
+func (s *slidingWindowLimiter) allowN(ctx context.Context, key string, n, limit int64, granularity int) (Result, error) {
 	if n <= 0 {
-		return nil, ErrInvalidN
+		return Result{}, ErrInvalidN
+	}
+	if result, unlimited := s.config.outsideSchedule(key, limit); unlimited {
+		return result, nil
+	}
+	if result, denied := s.config.permanentDenial(ctx, key, float64(n), limit); denied {
+		return result, nil
 	}
 
 	now := time.Now()
-	currWindowStart := now.Truncate(s.config.Window).Unix()
-	prevWindowStart := currWindowStart - int64(s.config.Window.Seconds())
+	currBucketStart := s.bucketStart(now, granularity)
 
-	// Format Redis keys for current and previous windows
-	currKey := s.formatKey(key, currWindowStart)
-	prevKey := s.formatKey(key, prevWindowStart)
+	// Format Redis keys for every sub-window, oldest first
+	keys := s.bucketKeys(key, currBucketStart, granularity)
 
 	// Execute Lua script to get counts atomically
-	prevCount, currCount, err := s.getCounts(ctx, currKey, prevKey, n)
+	oldestWeight := 1.0 - s.bucketProgress(now, currBucketStart, granularity)
+	counts, created, held, err := s.getCounts(ctx, keys, n, limit, oldestWeight, granularity)
 	if err != nil {
-		if s.config.FailOpen {
+		if s.config.FailOpen && !misconfigured(err) {
 			// Fail open: allow the request
-			return &Result{
-				Allowed:    true,
-				Limit:      s.config.Limit,
-				Remaining:  0,
-				RetryAfter: 0,
-				ResetAt:    s.calculateResetTime(currWindowStart),
+			return Result{
+				Allowed:       true,
+				Limit:         limit,
+				Remaining:     s.estimate.remaining(key, n, limit, now),
+				RetryAfter:    0,
+				ResetAt:       s.calculateResetTime(currBucketStart, granularity),
+				CheckDuration: time.Since(now),
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(err))
 	}
+	checkDuration := time.Since(now)
+	s.estimate.recovered()
 
-	// Calculate weighted count based on position in current window
-	weightedCount := s.calculateWeightedCount(now, currWindowStart, prevCount, currCount)
+	// Calculate weighted count based on position in current sub-window
+	weightedCount := s.calculateWeightedCount(now, currBucketStart, granularity, counts)
 
-	allowed := weightedCount <= float64(s.config.Limit)
-	remaining := s.config.Limit - int64(weightedCount)
+	// Requests that aren't critical leave the reserve for those that are
+	reserve := s.config.reserveFor(ctx)
+	allowed := weightedCount <= float64(limit-reserve) && !held
+	remaining := limit - int64(weightedCount)
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	result := &Result{
-		Allowed:    allowed,
-		Limit:      s.config.Limit,
-		Remaining:  remaining,
-		RetryAfter: 0,
-		ResetAt:    s.calculateResetTime(currWindowStart),
+	result := Result{
+		Allowed:              allowed,
+		Limit:                limit,
+		Remaining:            remaining,
+		Overage:              overage(weightedCount, limit),
+		RetryAfter:           0,
+		ResetAt:              s.calculateResetTime(currBucketStart, granularity),
+		FirstSeen:            created,
+		LimitChangedRecently: s.limit.changedWithin(s.config.LimitChangeWindow),
+		RequestsUntilDenied:  s.config.requestsUntilDenied(key, max(remaining-reserve, 0)),
+		CheckDuration:        checkDuration,
+	}
+
+	if created {
+		s.config.notifyKeyCreated(key)
 	}
 
 	if !allowed {
+		result.Reason = ReasonLimitExceeded
 		result.RetryAfter = time.Until(result.ResetAt)
 		if result.RetryAfter < 0 {
 			result.RetryAfter = 0
 		}
+		result.RetryAfter = s.config.roundRetryAfter(result.RetryAfter)
 	}
+	s.warmup.observe(ctx, s.client, s.config, key, n, &result)
 
 	return result, nil
 }
 
-// Reset resets the rate limit counter for the given key.
-func (s *slidingWindowLimiter) Reset(ctx context.Context, key string) error {
+// AllowClass checks if N requests of the given class are allowed for the key,
+// using the class's limit from Config.ClassLimits and its own sub-bucket.
+func (s *slidingWindowLimiter) AllowClass(ctx context.Context, key string, class string, n int64) (result *Result, err error) {
+	defer s.config.recoverDecision(&result, &err)
+	limit, err := s.config.classLimit(class)
+	if err != nil {
+		return nil, err
+	}
+	return s.observeAllowN(ctx, classKey(key, class), n, limit, s.granularity)
+}
+
+// AllowHinted serves the request locally when the caller's hint shows the key
+// is comfortably under its limit, and falls back to AllowN otherwise.
+func (s *slidingWindowLimiter) AllowHinted(ctx context.Context, key string, n int64, localHint int64) (result *Result, err error) {
+	defer s.config.recoverDecision(&result, &err)
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	limit := s.config.keyLimit(key, s.limit.load())
+	// A reserve or ObserveFirst needs the counts stored in Redis
+	if s.config.denylisted(key) || s.config.reserveFor(ctx) > 0 || s.warmup != nil || !hintIsSafe(limit, n, localHint) {
+		return s.AllowN(ctx, key, n)
+	}
+
+	resetAt := s.calculateResetTime(s.bucketStart(time.Now(), s.granularity), s.granularity)
+	return s.recordDecision(ctx, key, n, func(limit int64) (Result, error) {
+		return *NewAllowedResult(limit, limit-localHint-n, resetAt), nil
+	})
+}
+
+// Inspect returns the count of every sub-window covering the window for the
+// key along with the weights applied to them. It reads the counters without
+// modifying them.
+func (s *slidingWindowLimiter) Inspect(ctx context.Context, key string) (state *SlidingState, err error) {
+	defer s.config.recoverError(&err)
+	now := time.Now()
+	currBucketStart := s.bucketStart(now, s.granularity)
+	keys := s.bucketKeys(key, currBucketStart, s.granularity)
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect rate limit: %w", err)
+	}
+
+	size := s.bucketSize(s.granularity)
+	progress := s.bucketProgress(now, currBucketStart, s.granularity)
+	firstStart := currBucketStart - int64(s.granularity)*int64(size.Seconds())
+
+	state = &SlidingState{
+		Buckets:        make([]SlidingBucket, len(values)),
+		Limit:          s.config.keyLimit(key, s.limit.load()),
+		LimitChangedAt: s.limit.lastChange(),
+	}
+	for i, value := range values {
+		count, err := parseCount(value)
+		if err != nil {
+			return nil, err
+		}
+
+		weight := 1.0
+		if i == 0 {
+			weight = 1.0 - progress
+		}
+
+		state.Buckets[i] = SlidingBucket{
+			Start:  time.Unix(firstStart+int64(i)*int64(size.Seconds()), 0),
+			Count:  count,
+			Weight: weight,
+		}
+		state.WeightedCount += float64(count) * weight
+	}
+
+	return state, nil
+}
+
+// Peek returns the key's weighted count status without incrementing the
+// current sub-window.
+func (s *slidingWindowLimiter) Peek(ctx context.Context, key string) (result *Result, err error) {
+	defer s.config.recoverError(&err)
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	limit := s.config.keyLimit(key, s.limit.load())
+	now := time.Now()
+	currBucketStart := s.bucketStart(now, s.granularity)
+	keys := s.bucketKeys(key, currBucketStart, s.granularity)
+
+	counts, err := s.peekCounts(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	weightedCount := s.calculateWeightedCount(now, currBucketStart, s.granularity, counts)
+	return s.config.peekResult(limit, weightedCount, s.calculateResetTime(currBucketStart, s.granularity), now), nil
+}
+
+// FullResetAt returns when the key's weighted count will have decayed to
+// zero if no further requests arrive: when the newest sub-window holding
+// requests has slid out of the window, or now if none does.
+func (s *slidingWindowLimiter) FullResetAt(ctx context.Context, key string) (time.Time, error) {
+	if key == "" {
+		return time.Time{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+	currBucketStart := s.bucketStart(now, s.granularity)
+	keys := s.bucketKeys(key, currBucketStart, s.granularity)
+
+	counts, err := s.peekCounts(ctx, keys)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	size := s.bucketSize(s.granularity)
+	for i := len(counts) - 1; i >= 0; i-- {
+		if counts[i] > 0 {
+			// A sub-window counts fully for one window after it starts, then
+			// fades out over one more sub-window as the oldest
+			start := time.Unix(currBucketStart, 0).Add(-time.Duration(len(counts)-1-i) * size)
+			return start.Add(s.config.Window + size), nil
+		}
+	}
+	return now, nil
+}
+
+// peekCounts reads the counts of the sub-windows at keys without
+// incrementing any of them.
+func (s *slidingWindowLimiter) peekCounts(ctx context.Context, keys []string) ([]int64, error) {
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek rate limit: %w", err)
+	}
+
+	counts := make([]int64, len(values))
+	for i, value := range values {
+		counts[i], err = parseCount(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// UsedMany returns each key's weighted count, clamped at Limit. The
+// sub-window keys are read with one pipelined MGET per key.
+func (s *slidingWindowLimiter) UsedMany(ctx context.Context, keys []string) (used map[string]int64, err error) {
+	defer s.config.recoverError(&err)
+	for _, key := range keys {
+		if key == "" {
+			return nil, ErrInvalidKey
+		}
+	}
+
+	limit := s.limit.load()
+	now := time.Now()
+	currBucketStart := s.bucketStart(now, s.granularity)
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.MGet(ctx, s.bucketKeys(key, currBucketStart, s.granularity)...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	used = make(map[string]int64, len(keys))
+	for i, key := range keys {
+		counts := make([]int64, len(cmds[i].Val()))
+		for j, value := range cmds[i].Val() {
+			count, err := parseCount(value)
+			if err != nil {
+				return nil, err
+			}
+			counts[j] = count
+		}
+		weightedCount := s.calculateWeightedCount(now, currBucketStart, s.granularity, counts)
+		used[key] = min(int64(weightedCount), s.config.keyLimit(key, limit))
+	}
+	return used, nil
+}
+
+// HistoricalCount returns the requests counted for the key in the sub-windows
+// overlapping the last since.
+func (s *slidingWindowLimiter) HistoricalCount(ctx context.Context, key string, since time.Duration) (int64, error) {
+	if since <= 0 || since > s.config.Window {
+		return 0, fmt.Errorf("since must be between 0 and the window (%v), got: %v", s.config.Window, since)
+	}
+
+	// The current sub-window, and as many before it as the rest of the range reaches into
 	now := time.Now()
-	currWindowStart := now.Truncate(s.config.Window).Unix()
-	prevWindowStart := currWindowStart - int64(s.config.Window.Seconds())
+	size := s.bucketSize(s.granularity)
+	elapsed := now.Sub(time.Unix(s.bucketStart(now, s.granularity), 0))
+	buckets := 1
+	if since > elapsed {
+		buckets += int((since - elapsed + size - 1) / size)
+	}
+
+	counts, err := s.Timeline(ctx, key, min(buckets, s.granularity+1))
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
 
-	currKey := s.formatKey(key, currWindowStart)
-	prevKey := s.formatKey(key, prevWindowStart)
+// Timeline returns the counts of the last buckets sub-windows for the key,
+// oldest first. The sub-window keys are read directly with MGET, so the
+// counters are not modified and no key is created.
+func (s *slidingWindowLimiter) Timeline(ctx context.Context, key string, buckets int) ([]int64, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	if buckets < 1 || buckets > s.granularity+1 {
+		return nil, fmt.Errorf("buckets must be between 1 and %d, got: %d", s.granularity+1, buckets)
+	}
 
-	// Delete both current and previous window keys
-	if err := s.client.Del(ctx, currKey, prevKey).Err(); err != nil {
+	currBucketStart := s.bucketStart(time.Now(), s.granularity)
+	keys := s.bucketKeys(key, currBucketStart, s.granularity)
+	keys = keys[len(keys)-buckets:]
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit timeline: %w", err)
+	}
+
+	counts := make([]int64, len(values))
+	for i, value := range values {
+		counts[i], err = parseCount(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+// Reset resets the rate limit counter for the given key.
+// Only the sub-windows of the configured granularity (Config.SubWindows) are
+// cleared; counters written through AllowGranular with a different
+// granularity expire on their own.
+func (s *slidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	currBucketStart := s.bucketStart(time.Now(), s.granularity)
+	keys := s.bucketKeys(key, currBucketStart, s.granularity)
+
+	// Delete every sub-window key, and the Config.ObserveFirst count
+	if err := s.client.Del(ctx, s.config.withWarmupKey(keys, key)...).Err(); err != nil {
 		return fmt.Errorf("failed to reset rate limit: %w", err)
 	}
+	s.warmup.reset(key)
 
+	s.config.observeReset(ctx, key)
 	return nil
 }
 
+// MemoryUsage returns the Redis memory used by the key's sub-windows of the
+// configured granularity and its Config.ObserveFirst count.
+func (s *slidingWindowLimiter) MemoryUsage(ctx context.Context, key string) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	keys := s.bucketKeys(key, s.bucketStart(time.Now(), s.granularity), s.granularity)
+	return memoryUsage(ctx, s.client, s.config.withWarmupKey(keys, key))
+}
+
+// MaxBurst returns the worst-case burst for a sliding window: Limit.
+// The weighted count carries the previous window's usage across the boundary,
+// so unlike a fixed window the boundary does not double the burst. The
+// weighted count is an approximation that assumes requests in the previous
+// window were evenly spread.
+func (s *slidingWindowLimiter) MaxBurst() int64 {
+	return s.limit.load()
+}
+
+// SetLimit changes the limit applied to subsequent decisions.
+func (s *slidingWindowLimiter) SetLimit(limit int64) error {
+	return s.limit.set(limit)
+}
+
+// Describe returns the quota the limiter currently enforces.
+func (s *slidingWindowLimiter) Describe() QuotaInfo {
+	return s.limit.describe(s.config)
+}
+
+// AllowDescribe checks a single request and describes the quota it was
+// checked against.
+func (s *slidingWindowLimiter) AllowDescribe(ctx context.Context, key string) (*Result, QuotaInfo, error) {
+	return allowDescribe(ctx, s, key)
+}
+
+// AllowNDecision checks N requests and records the call as a Decision.
+func (s *slidingWindowLimiter) AllowNDecision(ctx context.Context, key string, n int64) (*Decision, error) {
+	return allowNDecision(ctx, s, key, n)
+}
+
+// StartKeyCounter periodically counts the keys under the limiter's prefix.
+func (s *slidingWindowLimiter) StartKeyCounter(ctx context.Context, interval time.Duration) (func(), <-chan int) {
+	return startKeyCounter(ctx, s.client, s.config.keyPattern(), interval)
+}
+
+// Reconcile charges the key's current sub-window the difference between the
+// request's actual cost and ProvisionalCost; see Reconciler.
+func (s *slidingWindowLimiter) Reconcile(ctx context.Context, key string, actualCost int64) (err error) {
+	defer s.config.recoverError(&err)
+	delta, err := reconcileDelta(key, actualCost)
+	if err != nil {
+		return err
+	}
+	return s.adjust(ctx, key, delta)
+}
+
+// adjust adds delta to the key's current sub-window; see adjuster.
+func (s *slidingWindowLimiter) adjust(ctx context.Context, key string, delta int64) error {
+	keys := s.bucketKeys(key, s.bucketStart(time.Now(), s.granularity), s.granularity)
+	currTTL, _ := s.keyTTLs(s.granularity)
+	return adjustCounter(ctx, s.client, s.config, keys[len(keys)-1], "", delta, currTTL)
+}
+
+// FlushAll deletes every key under the limiter's prefix. Meant for tests;
+// see Flusher.
+func (s *slidingWindowLimiter) FlushAll(ctx context.Context) error {
+	return flushKeys(ctx, s.client, s.config)
+}
+
+// DebugInfo returns a snapshot of the limiter's parameters and decision counts.
+func (s *slidingWindowLimiter) DebugInfo() map[string]any {
+	info := s.config.debugInfo(s.limit, &s.stats)
+	info["script_sha"] = scriptSHA(slidingWindowScript)
+	currTTL, _ := s.keyTTLs(s.granularity)
+	info["key_ttl"] = currTTL.String()
+	info["sub_windows"] = s.granularity
+	info["sub_window"] = s.bucketSize(s.granularity).String()
+	info["hash_tag_keys"] = s.config.HashTagKeys
+	return info
+}
+
+// TopDenied returns the most denied keys of the last minute or two, most
+// denied first.
+func (s *slidingWindowLimiter) TopDenied() []KeyCount {
+	return s.stats.topDenied()
+}
+
 // Close closes the rate limiter and releases resources.
 func (s *slidingWindowLimiter) Close() error {
 	if s.client != nil {
@@ -148,50 +653,154 @@ func (s *slidingWindowLimiter) Close() error {
 
 // formatKey formats the Redis key with prefix, user key, and window timestamp.
 func (s *slidingWindowLimiter) formatKey(key string, windowStart int64) string {
-	return fmt.Sprintf("%s:%d", s.config.FormatKey(key), windowStart)
+	return fmt.Sprintf("%s:%d", s.baseKey(key), windowStart)
+}
+
+// baseKey returns the prefixed key shared by all of key's sub-windows. With
+// Config.HashTagKeys the user key is wrapped in a hash tag so that every
+// sub-window lands in the same Redis Cluster slot.
+func (s *slidingWindowLimiter) baseKey(key string) string {
+	if s.config.HashTagKeys {
+		return s.config.FormatKey("{" + key + "}")
+	}
+	return s.config.FormatKey(key)
+}
+
+// formatBucketKey formats the Redis key of a sub-window.
+// A granularity of 1 keeps the plain window key format, so existing counters
+// stay valid; finer granularities are namespaced so that sub-windows of
+// different sizes never share a key.
+func (s *slidingWindowLimiter) formatBucketKey(key string, granularity int, bucketStart int64) string {
+	if granularity <= 1 {
+		return s.formatKey(key, bucketStart)
+	}
+	return fmt.Sprintf("%s:g%d:%d", s.baseKey(key), granularity, bucketStart)
 }
 
-// calculateResetTime calculates when the current window will reset.
-func (s *slidingWindowLimiter) calculateResetTime(windowStart int64) time.Time {
-	return time.Unix(windowStart, 0).Add(s.config.Window)
+// bucketKeys returns the keys of the granularity+1 sub-windows covering the
+// window that ends with the current sub-window, oldest first.
+func (s *slidingWindowLimiter) bucketKeys(key string, currBucketStart int64, granularity int) []string {
+	// At least a second apart, so sub-windows never share a key. Starts
+	// before the epoch are negative and still distinct.
+	step := max(1, int64(s.bucketSize(granularity).Seconds()))
+	keys := make([]string, granularity+1)
+	for i := range keys {
+		keys[i] = s.formatBucketKey(key, granularity, currBucketStart-int64(granularity-i)*step)
+	}
+	return keys
 }
 
-// getCounts retrieves previous and current window counts atomically.
-func (s *slidingWindowLimiter) getCounts(ctx context.Context, currKey, prevKey string, n int64) (int64, int64, error) {
-	currTTL := int64(s.config.Window.Seconds())
-	prevTTL := int64(s.config.Window.Seconds() * 2) // Previous window lives for 2 windows
+// bucketSize returns the duration of one sub-window.
+func (s *slidingWindowLimiter) bucketSize(granularity int) time.Duration {
+	return s.config.Window / time.Duration(granularity)
+}
 
-	result, err := s.client.Eval(ctx, slidingWindowScript, []string{currKey, prevKey}, n, currTTL, prevTTL).Result()
+// bucketStart returns the Unix start time of the sub-window containing now.
+func (s *slidingWindowLimiter) bucketStart(now time.Time, granularity int) int64 {
+	return now.Truncate(s.bucketSize(granularity)).Unix()
+}
+
+// bucketProgress returns how far now is through the current sub-window (0.0-1.0).
+func (s *slidingWindowLimiter) bucketProgress(now time.Time, bucketStart int64, granularity int) float64 {
+	elapsed := now.Sub(time.Unix(bucketStart, 0))
+	return float64(elapsed) / float64(s.bucketSize(granularity))
+}
+
+// calculateResetTime calculates when the current sub-window ends, which is
+// when the oldest sub-window stops counting towards the limit.
+// With a granularity of 1 this is the end of the current window.
+func (s *slidingWindowLimiter) calculateResetTime(bucketStart int64, granularity int) time.Time {
+	return time.Unix(bucketStart, 0).Add(s.bucketSize(granularity))
+}
+
+// keyTTLs returns the TTLs set on the current sub-window and refreshed on
+// the previous one (0 for none).
+func (s *slidingWindowLimiter) keyTTLs(granularity int) (time.Duration, time.Duration) {
+	if granularity > 1 {
+		// A sub-window is read until it is the oldest of granularity+1, so it
+		// gets its full lifetime up front instead of being refreshed. Sub-window
+		// keys are at least a second apart (see bucketKeys).
+		lifetime := max(time.Second, s.bucketSize(granularity)) * time.Duration(granularity+1)
+		return s.config.effectiveTTL(lifetime), 0
+	}
+	return s.config.effectiveTTL(s.config.Window), s.config.effectiveTTL(2 * s.config.Window) // Previous window lives for 2 windows
+}
+
+// getCounts retrieves the count of every sub-window atomically, oldest first,
+// whether the current sub-window was created by this call, and whether the
+// request was held back: requests that would dip into
+// Config.ReserveForCritical are denied and left uncounted.
+func (s *slidingWindowLimiter) getCounts(ctx context.Context, keys []string, n, limit int64, oldestWeight float64, granularity int) ([]int64, bool, bool, error) {
+	if err := s.config.checkCallBudget(ctx); err != nil {
+		return nil, false, false, err
+	}
+
+	currTTL, prevTTL := s.keyTTLs(granularity)
+
+	result, err := s.client.Eval(ctx, slidingWindowScript, keys, n, currTTL.Milliseconds(), prevTTL.Milliseconds(), s.config.TTLRefreshFraction,
+		oldestWeight, limit, s.config.reserveFor(ctx)).Result()
 	if err != nil {
-		return 0, 0, err
+		return nil, false, false, keyTypeError(crossSlotError(err), SlidingWindow)
 	}
 
-	counts, ok := result.([]interface{})
-	if !ok || len(counts) != 2 {
-		return 0, 0, fmt.Errorf("unexpected result type from Redis: %T", result)
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(keys)+2 {
+		return nil, false, false, fmt.Errorf("unexpected result type from Redis: %T", result)
 	}
 
-	prevCount, ok := counts[0].(int64)
+	counts := make([]int64, len(keys))
+	for i := range counts {
+		counts[i], ok = values[i].(int64)
+		if !ok {
+			return nil, false, false, fmt.Errorf("unexpected count type: %T", values[i])
+		}
+	}
+
+	created, ok := values[len(keys)].(int64)
 	if !ok {
-		return 0, 0, fmt.Errorf("unexpected previous count type: %T", counts[0])
+		return nil, false, false, fmt.Errorf("unexpected created type: %T", values[len(keys)])
 	}
 
-	currCount, ok := counts[1].(int64)
+	held, ok := values[len(keys)+1].(int64)
 	if !ok {
-		return 0, 0, fmt.Errorf("unexpected current count type: %T", counts[1])
+		return nil, false, false, fmt.Errorf("unexpected held type: %T", values[len(keys)+1])
 	}
 
-	return prevCount, currCount, nil
+	return counts, created == 1, held == 1, nil
 }
 
 // calculateWeightedCount calculates the weighted count using sliding window formula.
-// Formula: prev_count * (1 - progress) + curr_count
-// where progress = time_elapsed_in_current_window / window_duration
-func (s *slidingWindowLimiter) calculateWeightedCount(now time.Time, windowStart int64, prevCount, currCount int64) float64 {
-	windowStartTime := time.Unix(windowStart, 0)
-	elapsedInWindow := now.Sub(windowStartTime)
-	progress := float64(elapsedInWindow) / float64(s.config.Window)
-
-	// Weighted count = previous * (1 - progress) + current
-	return float64(prevCount)*(1.0-progress) + float64(currCount)
+// Formula: oldest_count * (1 - progress) + sum(newer_counts)
+// where progress = time_elapsed_in_current_sub_window / sub_window_duration
+// With a granularity of 1 this is prev_count * (1 - progress) + curr_count.
+func (s *slidingWindowLimiter) calculateWeightedCount(now time.Time, bucketStart int64, granularity int, counts []int64) float64 {
+	if len(counts) == 0 {
+		return 0
+	}
+
+	progress := s.bucketProgress(now, bucketStart, granularity)
+
+	// Weighted count = oldest * (1 - progress) + every newer sub-window
+	weighted := float64(counts[0]) * (1.0 - progress)
+	for _, count := range counts[1:] {
+		weighted += float64(count)
+	}
+	return weighted
+}
+
+// parseCount converts a counter value read with GET/MGET into an int64.
+// Missing keys (nil) count as 0.
+func parseCount(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		count, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected counter value %q: %w", v, err)
+		}
+		return count, nil
+	default:
+		return 0, fmt.Errorf("unexpected counter type: %T", value)
+	}
 }
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -401,3 +402,624 @@ func TestSlidingWindow_Integration_SmoothRateLimit(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, result.Allowed)
 }
+
+func TestSlidingWindow_Integration_ExactRemaining(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewSlidingWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:exact"
+
+	result, err := limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	assert.Equal(t, int64(7), result.Remaining)
+
+	// n == remaining is allowed and leaves nothing behind
+	result, err = limiter.AllowN(ctx, key, 7)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestSlidingWindow_Integration_Inspect(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     100,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewSlidingWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	inspector, ok := limiter.(SlidingInspector)
+	require.True(t, ok, "sliding window should implement SlidingInspector")
+
+	sw := limiter.(*slidingWindowLimiter)
+	ctx := context.Background()
+	key := "user:inspect"
+
+	// Seed uneven counts in the previous and current windows
+	currWindowStart := time.Now().Truncate(time.Hour).Unix()
+	prevWindowStart := currWindowStart - 3600
+	require.NoError(t, mr.Set(sw.formatKey(key, prevWindowStart), "40"))
+	require.NoError(t, mr.Set(sw.formatKey(key, currWindowStart), "7"))
+
+	state, err := inspector.Inspect(ctx, key)
+	require.NoError(t, err)
+	require.Len(t, state.Buckets, 2)
+
+	// Oldest first
+	assert.Equal(t, time.Unix(prevWindowStart, 0), state.Buckets[0].Start)
+	assert.Equal(t, int64(40), state.Buckets[0].Count)
+	assert.Greater(t, state.Buckets[0].Weight, 0.0)
+	assert.LessOrEqual(t, state.Buckets[0].Weight, 1.0)
+
+	assert.Equal(t, time.Unix(currWindowStart, 0), state.Buckets[1].Start)
+	assert.Equal(t, int64(7), state.Buckets[1].Count)
+	assert.Equal(t, 1.0, state.Buckets[1].Weight)
+
+	expected := 40*state.Buckets[0].Weight + 7
+	assert.InDelta(t, expected, state.WeightedCount, 0.0001)
+	assert.Equal(t, int64(100), state.Limit)
+
+	// Inspect is read-only
+	value, err := mr.Get(sw.formatKey(key, currWindowStart))
+	require.NoError(t, err)
+	assert.Equal(t, "7", value)
+}
+
+func TestSlidingWindow_Integration_Timeline(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:  SlidingWindow,
+		Limit:      100,
+		Window:     time.Hour,
+		SubWindows: 4,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	reader, ok := limiter.(TimelineReader)
+	require.True(t, ok, "sliding window should implement TimelineReader")
+
+	sw := limiter.(*slidingWindowLimiter)
+	ctx := context.Background()
+	key := "user:timeline"
+
+	// Seed known counts in the 5 sub-windows, oldest first; the third is empty
+	keys := sw.bucketKeys(key, sw.bucketStart(time.Now(), 4), 4)
+	require.Len(t, keys, 5)
+	require.NoError(t, mr.Set(keys[0], "3"))
+	require.NoError(t, mr.Set(keys[1], "8"))
+	require.NoError(t, mr.Set(keys[3], "5"))
+	require.NoError(t, mr.Set(keys[4], "1"))
+
+	timeline, err := reader.Timeline(ctx, key, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 8, 0, 5, 1}, timeline)
+
+	// Fewer buckets return the most recent sub-windows
+	timeline, err = reader.Timeline(ctx, key, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{5, 1}, timeline)
+
+	// Timeline is read-only
+	assert.Len(t, mr.Keys(), 4)
+
+	_, err = reader.Timeline(ctx, key, 6)
+	assert.Error(t, err)
+	_, err = reader.Timeline(ctx, key, 0)
+	assert.Error(t, err)
+	_, err = reader.Timeline(ctx, "", 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestSlidingWindow_Integration_HistoricalCount(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:  SlidingWindow,
+		Limit:      100,
+		Window:     time.Hour,
+		SubWindows: 4,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	counter, ok := limiter.(HistoricalCounter)
+	require.True(t, ok, "sliding window should implement HistoricalCounter")
+
+	sw := limiter.(*slidingWindowLimiter)
+	ctx := context.Background()
+	key := "user:history"
+
+	// Seed known counts in the 5 fifteen-minute sub-windows, oldest first
+	keys := sw.bucketKeys(key, sw.bucketStart(time.Now(), 4), 4)
+	for i, count := range []string{"3", "8", "0", "5", "1"} {
+		require.NoError(t, mr.Set(keys[i], count))
+	}
+
+	tests := []struct {
+		since time.Duration
+		want  int64
+	}{
+		{time.Nanosecond, 1},          // the current sub-window
+		{15 * time.Minute, 1 + 5},     // reaches into the previous one
+		{30 * time.Minute, 1 + 5 + 0}, // and the one before
+		{time.Hour, 1 + 5 + 0 + 8 + 3},
+	}
+	for _, tt := range tests {
+		count, err := counter.HistoricalCount(ctx, key, tt.since)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, count, "since %v", tt.since)
+	}
+
+	_, err = counter.HistoricalCount(ctx, key, 2*time.Hour)
+	assert.Error(t, err)
+	_, err = counter.HistoricalCount(ctx, key, 0)
+	assert.Error(t, err)
+	_, err = counter.HistoricalCount(ctx, "", time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestSlidingWindow_Integration_Inspect_UntouchedKey(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	state, err := limiter.(SlidingInspector).Inspect(context.Background(), "user:new")
+	require.NoError(t, err)
+	for _, bucket := range state.Buckets {
+		assert.Equal(t, int64(0), bucket.Count)
+	}
+	assert.Equal(t, 0.0, state.WeightedCount)
+	assert.Empty(t, mr.Keys(), "Inspect must not create keys")
+}
+
+func TestSlidingWindow_Integration_SubWindowAccuracy(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	coarse, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     100,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer coarse.Close()
+
+	fine, err := NewSlidingWindow(client, &Config{
+		Algorithm:  SlidingWindow,
+		Limit:      100,
+		Window:     time.Minute,
+		SubWindows: 6,
+	})
+	require.NoError(t, err)
+	defer fine.Close()
+
+	ctx := context.Background()
+	key := "user:burst"
+
+	// A burst of 100 requests at the very start of the previous window has
+	// fully left the true sliding window, so the exact count is 0.
+	burstStart := time.Now().Truncate(time.Minute).Add(-time.Minute).Unix()
+	require.NoError(t, mr.Set(coarse.(*slidingWindowLimiter).formatBucketKey(key, 1, burstStart), "100"))
+	require.NoError(t, mr.Set(fine.(*slidingWindowLimiter).formatBucketKey(key, 6, burstStart), "100"))
+
+	coarseState, err := coarse.(SlidingInspector).Inspect(ctx, key)
+	require.NoError(t, err)
+	require.Len(t, coarseState.Buckets, 2)
+
+	fineState, err := fine.(SlidingInspector).Inspect(ctx, key)
+	require.NoError(t, err)
+	require.Len(t, fineState.Buckets, 7)
+
+	// The two-window approximation spreads the burst over the whole previous
+	// window; 10s sub-windows discount it much faster.
+	assert.Greater(t, coarseState.WeightedCount, 0.0)
+	assert.Less(t, fineState.WeightedCount, coarseState.WeightedCount)
+}
+
+func TestSlidingWindow_Integration_AllowGranular(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	granular, ok := limiter.(GranularLimiter)
+	require.True(t, ok, "sliding window should implement GranularLimiter")
+
+	ctx := context.Background()
+
+	// Coarse and fine key classes share one limiter
+	for i := 0; i < 5; i++ {
+		result, err := granular.AllowGranular(ctx, "cheap:1", 1, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, err = granular.AllowGranular(ctx, "precise:1", 1, 12)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := granular.AllowGranular(ctx, "cheap:1", 1, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = granular.AllowGranular(ctx, "precise:1", 1, 12)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Fine-grained counters are namespaced by granularity and expire once
+	// they can no longer count towards the window (13 sub-windows of 5s)
+	var fineKeys int
+	for _, k := range mr.Keys() {
+		if strings.Contains(k, "precise:1:g12:") {
+			fineKeys++
+			assert.Equal(t, 65*time.Second, mr.TTL(k))
+		}
+	}
+	assert.GreaterOrEqual(t, fineKeys, 1)
+}
+
+func TestSlidingWindow_Integration_AllowGranular_Invalid(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	granular := limiter.(GranularLimiter)
+	ctx := context.Background()
+
+	for _, granularity := range []int{0, 7, MaxSubWindows + 1} {
+		_, err := granular.AllowGranular(ctx, "user:1", 1, granularity)
+		assert.ErrorIs(t, err, ErrInvalidGranularity, "granularity %d", granularity)
+	}
+	assert.Empty(t, mr.Keys())
+}
+
+func TestSlidingWindow_Integration_FirstSeen(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	var created []string
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:    SlidingWindow,
+		Limit:        10,
+		Window:       time.Hour,
+		OnKeyCreated: func(key string) { created = append(created, key) },
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "user:new")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	for i := 0; i < 3; i++ {
+		result, err = limiter.Allow(ctx, "user:new")
+		require.NoError(t, err)
+		assert.False(t, result.FirstSeen)
+	}
+
+	result, err = limiter.Allow(ctx, "user:other")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	assert.Equal(t, []string{"user:new", "user:other"}, created)
+}
+
+func TestSlidingWindow_Integration_AllowClass(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:   SlidingWindow,
+		Limit:       100,
+		Window:      time.Minute,
+		ClassLimits: map[string]int64{"read": 4, "write": 1},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	classes, ok := limiter.(ClassLimiter)
+	require.True(t, ok, "sliding window should implement ClassLimiter")
+
+	ctx := context.Background()
+	key := "user:123"
+
+	result, err := classes.AllowClass(ctx, key, "write", 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = classes.AllowClass(ctx, key, "write", 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = classes.AllowClass(ctx, key, "read", 4)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(4), result.Limit)
+}
+
+func TestSlidingWindow_Integration_HashTagKeys(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm:   SlidingWindow,
+		Limit:       2,
+		Window:      time.Minute,
+		HashTagKeys: true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	for _, key := range mr.Keys() {
+		assert.True(t, strings.HasPrefix(key, "ratelimit:{user:1}:"), key)
+	}
+}
+
+func TestSlidingWindow_Integration_Peek(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     4,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	peeker, ok := limiter.(Peeker)
+	require.True(t, ok, "sliding window should implement Peeker")
+
+	ctx := context.Background()
+	key := "user:peek"
+
+	_, err = limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+
+	result, err := peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	_, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+
+	result, err = peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+}
+
+func TestSlidingWindow_Integration_UsedMany(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.AllowN(ctx, "user:1", 2)
+	require.NoError(t, err)
+	_, err = limiter.AllowN(ctx, "user:2", 4)
+	require.NoError(t, err)
+
+	used, err := limiter.(UsageReader).UsedMany(ctx, []string{"user:1", "user:2", "user:untouched"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"user:1": 2, "user:2": 4, "user:untouched": 0}, used)
+
+	// UsedMany agrees with Peek
+	peeked, err := limiter.(Peeker).Peek(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Equal(t, peeked.Limit-peeked.Remaining, used["user:2"])
+}
+
+func TestSlidingWindow_Integration_Overage(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     5,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:over"
+
+	result, err := limiter.AllowN(ctx, key, 5)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Overage)
+
+	// Denied requests are still counted, taking the count over the limit
+	for want := int64(1); want <= 3; want++ {
+		result, err = limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, int64(0), result.Remaining)
+		assert.Equal(t, want, result.Overage)
+	}
+
+	result, err = limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Overage)
+
+	peeked, err := limiter.(Peeker).Peek(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), peeked.Overage)
+
+	// A key within its limit has no overage
+	peeked, err = limiter.(Peeker).Peek(ctx, "user:under")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), peeked.Overage)
+}
+
+func TestSlidingWindow_Integration_FullResetAt(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	tests := []struct {
+		name       string
+		subWindows int
+	}{
+		{"classic", 1},
+		{"sub-windows", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewSlidingWindow(client, &Config{
+				Algorithm:  SlidingWindow,
+				Limit:      10,
+				Window:     time.Minute,
+				SubWindows: tt.subWindows,
+				Prefix:     tt.name,
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			_, err = limiter.AllowN(ctx, "user:1", 4)
+			require.NoError(t, err)
+
+			// The sub-window counts fully for one window, then fades out
+			// over one more sub-window
+			bucket := time.Minute / time.Duration(tt.subWindows)
+			want := time.Now().Truncate(bucket).Add(time.Minute + bucket)
+
+			fullAt, err := limiter.(FullResetReporter).FullResetAt(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, want, fullAt)
+
+			fullAt, err = limiter.(FullResetReporter).FullResetAt(ctx, "user:untouched")
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now(), fullAt, 100*time.Millisecond, "an unused key is reset now")
+		})
+	}
+}
+
+// evalTTLHook records the TTL arguments, ARGV[2] and ARGV[3], of every
+// sliding window script call
+type evalTTLHook struct {
+	ttls *[]int64
+}
+
+func (h evalTTLHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h evalTTLHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		if len(args) > 3 && args[0] == "eval" {
+			numKeys, _ := args[2].(int)
+			for _, arg := range args[3+numKeys+1 : 3+numKeys+3] {
+				*h.ttls = append(*h.ttls, arg.(int64))
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h evalTTLHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestSlidingWindow_Integration_TinyWindowTTLsAcrossBoundaries(t *testing.T) {
+	client, mr := setupMiniredisSlidingWindow(t)
+	defer mr.Close()
+
+	var ttls []int64
+	client.AddHook(evalTTLHook{ttls: &ttls})
+
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     3,
+		Window:    time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	// Move Redis's clock back and forth across window boundaries
+	for _, step := range []time.Duration{0, -5 * time.Second, 500 * time.Millisecond, -time.Second, 2 * time.Second} {
+		mr.FastForward(step)
+		for range 4 {
+			_, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+		}
+	}
+
+	require.NotEmpty(t, ttls)
+	assert.Contains(t, ttls, int64(2000), "the previous window lives for two windows")
+	for _, ttl := range ttls {
+		assert.GreaterOrEqual(t, ttl, int64(1000), "PEXPIRE must never get a TTL below one second")
+	}
+	for _, key := range mr.Keys() {
+		assert.Greater(t, mr.TTL(key), time.Duration(0), "%s must expire", key)
+	}
+}
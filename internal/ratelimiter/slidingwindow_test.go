@@ -1,6 +1,7 @@
 package ratelimiter
 
 import (
+	"slices"
 	"testing"
 	"time"
 
@@ -167,7 +168,7 @@ func TestSlidingWindow_CalculateResetTime(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			sw.config.Window = tt.window
-			result := sw.calculateResetTime(tt.windowStart)
+			result := sw.calculateResetTime(tt.windowStart, 1)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -231,15 +232,180 @@ func TestSlidingWindow_CalculateWeightedCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := sw.calculateWeightedCount(tt.now, tt.windowStart, tt.prevCount, tt.currCount)
+			result := sw.calculateWeightedCount(tt.now, tt.windowStart, 1, []int64{tt.prevCount, tt.currCount})
 			assert.InDelta(t, tt.expected, result, 0.1)
 		})
 	}
 }
 
+func TestSlidingWindow_CalculateWeightedCount_SubWindows(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     100,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewSlidingWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	sw := limiter.(*slidingWindowLimiter)
+
+	// 4 sub-windows of 15s; only the oldest of the 5 is weighted
+	counts := []int64{40, 10, 10, 10, 5}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected float64
+	}{
+		{
+			name:     "at start of sub-window",
+			now:      time.Unix(1640000000, 0),
+			expected: 75.0, // 40 + 10 + 10 + 10 + 5
+		},
+		{
+			name:     "halfway through sub-window",
+			now:      time.Unix(1640000000, 0).Add(7500 * time.Millisecond),
+			expected: 55.0, // 40 * 0.5 + 35
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sw.calculateWeightedCount(tt.now, 1640000000, 4, counts)
+			assert.InDelta(t, tt.expected, result, 0.1)
+		})
+	}
+}
+
+func TestSlidingWindow_BucketKeys(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	sw := limiter.(*slidingWindowLimiter)
+
+	// Granularity 1 keeps the plain window key format
+	assert.Equal(t, []string{
+		"ratelimit:user:123:1639999980",
+		"ratelimit:user:123:1640000040",
+	}, sw.bucketKeys("user:123", 1640000040, 1))
+
+	assert.Equal(t, []string{
+		"ratelimit:user:123:g3:1639999980",
+		"ratelimit:user:123:g3:1640000000",
+		"ratelimit:user:123:g3:1640000020",
+		"ratelimit:user:123:g3:1640000040",
+	}, sw.bucketKeys("user:123", 1640000040, 3))
+}
+
+func TestSlidingWindow_TinyWindowsNearEpoch(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewSlidingWindow(client, &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	sw := limiter.(*slidingWindowLimiter)
+
+	// Buckets before the epoch get negative, still distinct starts
+	assert.Equal(t, []string{
+		"ratelimit:user:123:-1",
+		"ratelimit:user:123:0",
+	}, sw.bucketKeys("user:123", 0, 1))
+
+	currTTL, prevTTL := sw.keyTTLs(1)
+	assert.Equal(t, time.Second, currTTL)
+	assert.Equal(t, 2*time.Second, prevTTL)
+
+	// Sub-windows shorter than a second, which validation rejects, still
+	// get distinct keys and TTLs of at least a second
+	sw.config.Window = 3 * time.Second
+	keys := sw.bucketKeys("user:123", 0, 4)
+	assert.Len(t, keys, 5)
+	assert.Len(t, slices.Compact(slices.Sorted(slices.Values(keys))), 5, "sub-windows must not share keys: %v", keys)
+
+	currTTL, _ = sw.keyTTLs(4)
+	assert.GreaterOrEqual(t, currTTL, time.Second)
+
+	sw.config.Window = 500 * time.Millisecond
+	currTTL, prevTTL = sw.keyTTLs(1)
+	assert.Equal(t, time.Second, currTTL)
+	assert.Equal(t, time.Second, prevTTL)
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		expected    int64
+		expectError bool
+	}{
+		{name: "missing key", value: nil, expected: 0},
+		{name: "counter", value: "42", expected: 42},
+		{name: "non-numeric", value: "abc", expectError: true},
+		{name: "unexpected type", value: 42, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := parseCount(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, count)
+		})
+	}
+}
+
+func TestSlidingWindow_MaxBurst(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	config := &Config{
+		Algorithm: SlidingWindow,
+		Limit:     10,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewSlidingWindow(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	reporter, ok := limiter.(BurstReporter)
+	require.True(t, ok, "limiter should implement BurstReporter")
+	assert.Equal(t, int64(10), reporter.MaxBurst())
+}
+
 func TestSlidingWindow_InterfaceContract(t *testing.T) {
 	// Verify that slidingWindowLimiter implements RateLimiter interface
 	var _ RateLimiter = (*slidingWindowLimiter)(nil)
+	var _ SlidingInspector = (*slidingWindowLimiter)(nil)
+	var _ TimelineReader = (*slidingWindowLimiter)(nil)
+	var _ HistoricalCounter = (*slidingWindowLimiter)(nil)
+	var _ GranularLimiter = (*slidingWindowLimiter)(nil)
+	var _ ValueLimiter = (*slidingWindowLimiter)(nil)
+	var _ LimitSetter = (*slidingWindowLimiter)(nil)
+	var _ ClassLimiter = (*slidingWindowLimiter)(nil)
+	var _ Peeker = (*slidingWindowLimiter)(nil)
+	var _ DebugInfoProvider = (*slidingWindowLimiter)(nil)
+	var _ KeyCounter = (*slidingWindowLimiter)(nil)
+	var _ UsageReader = (*slidingWindowLimiter)(nil)
+	var _ DeniedTracker = (*slidingWindowLimiter)(nil)
+	var _ Flusher = (*slidingWindowLimiter)(nil)
+	var _ DescribingLimiter = (*slidingWindowLimiter)(nil)
+	var _ Reconciler = (*slidingWindowLimiter)(nil)
+	var _ FullResetReporter = (*slidingWindowLimiter)(nil)
 }
 
 func TestSlidingWindow_Close(t *testing.T) {
@@ -1,8 +1,11 @@
 package ratelimiter
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,41 +17,188 @@ const (
 	//
 	// KEYS[1]: Redis key for token bucket state
 	// ARGV[1]: Maximum capacity (limit)
-	// ARGV[2]: Tokens to consume (n)
+	// ARGV[2]: Tokens to consume (n, may be fractional)
 	// ARGV[3]: Refill rate (tokens per second as float)
 	// ARGV[4]: Current timestamp (seconds)
-	// ARGV[5]: TTL for the key (seconds)
+	// ARGV[5]: TTL for the key (milliseconds)
+	// ARGV[6]: Refresh the TTL only when below this fraction of ARGV[5] (0 = always)
+	// ARGV[7]: Tokens a new bucket starts with (0 = full capacity)
+	// ARGV[8]: Minimum interval between allowed requests in seconds (0 disables spacing)
+	// ARGV[9]: Tokens that must be left after consuming (reserve for critical requests, 0 = none)
+	// ARGV[10]: TTL in milliseconds for a bucket found full (0 = use ARGV[5])
 	//
-	// Returns: {allowed (0/1), tokens_remaining}
+	// Token counts are floats persisted with tostring(), which keeps ~14
+	// significant digits. A bucket refilled to exactly 5 tokens may read back
+	// as 4.99999999999, so comparisons and floors allow a small epsilon to keep
+	// "consume exactly what is remaining" from being denied by rounding.
+	//
+	// Returns: {allowed (0/1), tokens_remaining, created (0/1), wait_ms}
+	// wait_ms > 0 means the request arrived less than the minimum interval
+	// after the last allowed one; it is denied without consuming tokens and
+	// wait_ms is the rest of the interval.
 	tokenBucketScript = `
+local epsilon = 1e-9
 local capacity = tonumber(ARGV[1])
 local requested = tonumber(ARGV[2])
 local refill_rate = tonumber(ARGV[3])
 local now = tonumber(ARGV[4])
 local ttl = tonumber(ARGV[5])
+local refresh_below = tonumber(ARGV[6])
+local initial = tonumber(ARGV[7])
+if initial <= 0 then
+    initial = capacity
+end
+initial = math.min(capacity, initial)
+local min_interval = tonumber(ARGV[8])
+local reserve = tonumber(ARGV[9])
+local full_ttl = tonumber(ARGV[10])
 
 -- Get current state or initialize
-local state = redis.call('HMGET', KEYS[1], 'tokens', 'last_refill')
-local tokens = tonumber(state[1]) or capacity
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last_refill', 'last_allowed')
+local created = 0
+if not state[1] then
+    created = 1
+end
+local tokens = tonumber(state[1]) or initial
 local last_refill = tonumber(state[2]) or now
 
+-- If the clock went backward, add nothing and keep the stored refill time,
+-- so tokens are neither drained nor refilled twice for the same period
+if now < last_refill then
+    now = last_refill
+end
+
 -- Calculate tokens to add based on elapsed time
 local elapsed = now - last_refill
 local tokens_to_add = elapsed * refill_rate
 tokens = math.min(capacity, tokens + tokens_to_add)
 
--- Try to consume tokens
+-- A bucket found full holds nothing a new one wouldn't, so it only needs to
+-- outlive its refill
+if full_ttl > 0 and tokens + epsilon >= capacity then
+    ttl = full_ttl
+end
+
+-- Enforce spacing since the last allowed request
+if min_interval > 0 and state[3] then
+    local wait = math.min(min_interval, min_interval - (now - tonumber(state[3])))
+    if wait > 0 then
+        return {0, math.floor(tokens + epsilon), created, math.ceil(wait * 1000)}
+    end
+end
+
+-- Try to consume tokens, leaving the reserve untouched
 local allowed = 0
-if tokens >= requested then
-    tokens = tokens - requested
+if tokens + epsilon >= requested + reserve then
+    tokens = math.max(0, tokens - requested)
     allowed = 1
 end
 
 -- Save new state
 redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last_refill', tostring(now))
-redis.call('EXPIRE', KEYS[1], ttl)
+if allowed == 1 and min_interval > 0 then
+    redis.call('HSET', KEYS[1], 'last_allowed', tostring(now))
+end
+local pttl = redis.call('PTTL', KEYS[1])
+if refresh_below <= 0 or pttl < ttl * refresh_below or pttl > ttl then
+    redis.call('PEXPIRE', KEYS[1], ttl)
+end
+
+return {allowed, math.floor(tokens + epsilon), created, 0}
+`
+
+	// tokenBucketOverflowScript applies the token bucket algorithm to an ordered
+	// list of buckets and consumes from the first one with enough tokens.
+	//
+	// KEYS[1..n]: Redis keys for each bucket, in priority order
+	// ARGV[1..7]: Same as tokenBucketScript
+	// ARGV[8]: Tokens that must be left after consuming (reserve for critical requests, 0 = none)
+	//
+	// Returns: {allowed (0/1), bucket_index (1-based), tokens_remaining}
+	// When denied, bucket_index points at the bucket holding the most tokens.
+	tokenBucketOverflowScript = `
+local epsilon = 1e-9
+local capacity = tonumber(ARGV[1])
+local requested = tonumber(ARGV[2])
+local refill_rate = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local ttl = tonumber(ARGV[5])
+local refresh_below = tonumber(ARGV[6])
+local initial = tonumber(ARGV[7])
+if initial <= 0 then
+    initial = capacity
+end
+initial = math.min(capacity, initial)
+local reserve = tonumber(ARGV[8])
+
+local best_index = 1
+local best_tokens = -1
+
+for i, key in ipairs(KEYS) do
+    local state = redis.call('HMGET', key, 'tokens', 'last_refill')
+    local tokens = tonumber(state[1]) or initial
+    local last_refill = tonumber(state[2]) or now
+
+    -- A clock that went backward adds nothing (see tokenBucketScript)
+    local elapsed = math.max(0, now - last_refill)
+    tokens = math.min(capacity, tokens + elapsed * refill_rate)
+
+    if tokens + epsilon >= requested + reserve then
+        tokens = math.max(0, tokens - requested)
+        redis.call('HMSET', key, 'tokens', tostring(tokens), 'last_refill', tostring(math.max(now, last_refill)))
+        if refresh_below <= 0 or redis.call('PTTL', key) < ttl * refresh_below then
+            redis.call('PEXPIRE', key, ttl)
+        end
+        return {1, i, math.floor(tokens + epsilon)}
+    end
+
+    if tokens > best_tokens then
+        best_index = i
+        best_tokens = tokens
+    end
+end
+
+return {0, best_index, math.floor(best_tokens + epsilon)}
+`
 
-return {allowed, math.floor(tokens)}
+	// tokenBucketAdjustScript takes a signed number of tokens from a bucket,
+	// keeping it between zero and its capacity. The refill earned since the
+	// stored refill time is added first, as a consume would, so a later consume
+	// can't refill over the adjustment.
+	//
+	// KEYS[1]: Redis key for token bucket state
+	// ARGV[1]: Maximum capacity (limit)
+	// ARGV[2]: Tokens to take (negative to refund)
+	// ARGV[3]: Current timestamp (seconds)
+	// ARGV[4]: TTL for the key (milliseconds)
+	// ARGV[5]: Tokens a new bucket starts with (0 = full capacity)
+	// ARGV[6]: Refill rate (tokens per second as float)
+	//
+	// Returns: tokens_remaining
+	tokenBucketAdjustScript = `
+local epsilon = 1e-9
+local capacity = tonumber(ARGV[1])
+local now = tonumber(ARGV[3])
+local initial = tonumber(ARGV[5])
+if initial <= 0 then
+    initial = capacity
+end
+local refill_rate = tonumber(ARGV[6])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last_refill')
+local tokens = tonumber(state[1]) or math.min(capacity, initial)
+local last_refill = tonumber(state[2]) or now
+
+-- A clock that went backward adds nothing (see tokenBucketScript)
+if now < last_refill then
+    now = last_refill
+end
+tokens = math.min(capacity, tokens + (now - last_refill) * refill_rate)
+tokens = math.min(capacity, math.max(0, tokens - tonumber(ARGV[2])))
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last_refill', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return math.floor(tokens + epsilon)
 `
 )
 
@@ -57,6 +207,14 @@ return {allowed, math.floor(tokens)}
 type tokenBucketLimiter struct {
 	client *redis.Client
 	config *Config
+	limit  *dynamicLimit
+	stats  decisionStats
+
+	// estimate is nil unless Config.FailOpenEstimate is set
+	estimate *failOpenEstimate
+
+	// warmup is nil unless Config.ObserveFirst is set
+	warmup *warmupTracker
 }
 
 // NewTokenBucket creates a new Token Bucket rate limiter.
@@ -77,6 +235,14 @@ func NewTokenBucket(client *redis.Client, config *Config) (RateLimiter, error) {
 	return &tokenBucketLimiter{
 		client: client,
 		config: cfg,
+		limit:  newDynamicLimit(cfg.Limit),
+		stats: decisionStats{
+			deniedKeys: newDeniedTracker(cfg.TrackTopDenied),
+			exhausted:  newExhaustionNotifier(cfg),
+		},
+
+		estimate: newFailOpenEstimate(cfg),
+		warmup:   newWarmupTracker(cfg),
 	}, nil
 }
 
@@ -86,63 +252,513 @@ func (t *tokenBucketLimiter) Allow(ctx context.Context, key string) (*Result, er
 }
 
 // AllowN checks if N requests are allowed for the given key.
+// Reports the decision to Config.Observer when one is configured.
+func (t *tokenBucketLimiter) AllowN(ctx context.Context, key string, n int64) (result *Result, err error) {
+	defer t.config.recoverDecision(&result, &err)
+	return t.observeAllowN(ctx, key, n, t.config.keyLimit(key, t.limit.load()))
+}
+
+// AllowValue checks if a single request is allowed for the given key and
+// returns the Result by value, avoiding a heap allocation per call.
+func (t *tokenBucketLimiter) AllowValue(ctx context.Context, key string) (Result, error) {
+	return t.AllowValueN(ctx, key, 1)
+}
+
+// AllowValueN checks if N requests are allowed for the given key and returns
+// the Result by value, avoiding a heap allocation per call.
+func (t *tokenBucketLimiter) AllowValueN(ctx context.Context, key string, n int64) (result Result, err error) {
+	defer t.config.recoverDecisionValue(&result, &err)
+	if t.config.Observer != nil {
+		// Observers receive a *Result, so observed calls go through AllowN
+		return resultValue(t.AllowN(ctx, key, n))
+	}
+	return t.stats.forKey(key).recordValue(t.config.stampDecision(t.allowN(ctx, key, n, t.config.keyLimit(key, t.limit.load()))))
+}
+
+// observeAllowN makes the decision and reports it to Config.Observer when one is configured.
+func (t *tokenBucketLimiter) observeAllowN(ctx context.Context, key string, n, limit int64) (*Result, error) {
+	if t.config.Observer == nil {
+		return t.stats.forKey(key).record(resultPtr(t.config.stampDecision(t.allowN(ctx, key, n, limit))))
+	}
+
+	start := time.Now()
+	result, err := t.stats.forKey(key).record(resultPtr(t.config.stampDecision(t.allowN(ctx, key, n, limit))))
+	t.config.observeDecision(ctx, key, n, start, result, err)
+	return result, err
+}
+
+// recordDecision makes a decision other than AllowN's with decide, against
+// the key's limit, and counts and reports it as observeAllowN does.
+func (t *tokenBucketLimiter) recordDecision(ctx context.Context, key string, n int64, decide func(limit int64) (Result, error)) (*Result, error) {
+	start := time.Now()
+	result, err := t.stats.forKey(key).record(resultPtr(t.config.stampDecision(decide(t.config.keyLimit(key, t.limit.load())))))
+	if t.config.Observer != nil {
+		t.config.observeDecision(ctx, key, n, start, result, err)
+	}
+	return result, err
+}
+
+// allowN makes the rate limit decision for AllowN against a bucket with the
+// given capacity.
+func (t *tokenBucketLimiter) allowN(ctx context.Context, key string, n, limit int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, ErrInvalidN
+	}
+	return t.consume(ctx, key, float64(n), limit)
+}
+
+// AllowCostF checks if a request of fractional cost is allowed for the key.
+// Reports the decision to Config.Observer, with N rounded up, when one is configured.
+func (t *tokenBucketLimiter) AllowCostF(ctx context.Context, key string, cost float64) (result *Result, err error) {
+	defer t.config.recoverDecision(&result, &err)
+	if !(cost > 0) || math.IsInf(cost, 1) {
+		return nil, ErrInvalidCost
+	}
+
+	limit := t.config.keyLimit(key, t.limit.load())
+	if t.config.Observer == nil {
+		return t.stats.forKey(key).record(resultPtr(t.config.stampDecision(t.consume(ctx, key, cost, limit))))
+	}
+
+	start := time.Now()
+	result, err = t.stats.forKey(key).record(resultPtr(t.config.stampDecision(t.consume(ctx, key, cost, limit))))
+	t.config.observeDecision(ctx, key, int64(math.Ceil(cost)), start, result, err)
+	return result, err
+}
+
+// consume makes the rate limit decision for a request of the given cost
+// against a bucket with the given capacity.
 // Uses token bucket algorithm with continuous refilling.
-func (t *tokenBucketLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+func (t *tokenBucketLimiter) consume(ctx context.Context, key string, cost float64, limit int64) (Result, error) {
+	if result, unlimited := t.config.outsideSchedule(key, limit); unlimited {
+		return result, nil
+	}
+	if result, denied := t.config.permanentDenial(ctx, key, cost, limit); denied {
+		return result, nil
+	}
+
+	refillRate := t.refillRateFor(limit)
+	start := time.Now()
+	now := float64(start.UnixNano()) / 1e9 // Convert to seconds with fractional part
+	redisKey := t.stateKey(key, now)
+
+	reserve := t.config.reserveFor(ctx)
+	allowed, remaining, created, spacingWait, err := t.tryConsume(ctx, redisKey, cost, limit, refillRate, now)
+	if err != nil {
+		if t.config.FailOpen && !misconfigured(err) {
+			// Fail open: allow the request
+			return Result{
+				Allowed:       true,
+				Limit:         limit,
+				Remaining:     t.estimate.remaining(key, int64(math.Ceil(cost)), limit, time.Now()),
+				RetryAfter:    0,
+				ResetAt:       t.calculateResetTime(now),
+				CheckDuration: time.Since(start),
+			}, nil
+		}
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(err))
+	}
+	checkDuration := time.Since(start)
+	t.estimate.recovered()
+
+	result := Result{
+		Allowed:              allowed,
+		Limit:                limit,
+		Remaining:            remaining,
+		RetryAfter:           0,
+		ResetAt:              t.calculateResetTime(now),
+		FirstSeen:            created,
+		LimitChangedRecently: t.limit.changedWithin(t.config.LimitChangeWindow),
+		RequestsUntilDenied:  t.config.requestsUntilDenied(key, max(remaining-reserve, 0)),
+		CheckDuration:        checkDuration,
+	}
+
+	if created {
+		t.config.notifyKeyCreated(key)
+	}
+
+	if !allowed {
+		// Calculate time until enough tokens are available
+		result.Reason = ReasonLimitExceeded
+		tokensNeeded := cost + float64(reserve) - float64(remaining)
+		secondsToWait := tokensNeeded / refillRate
+		result.RetryAfter = time.Duration(secondsToWait * float64(time.Second))
+		if spacingWait > 0 {
+			// Denied by the minimum interval, not for lack of tokens
+			result.Reason = ReasonMinInterval
+			result.RetryAfter = max(spacingWait, result.RetryAfter)
+		}
+		if result.RetryAfter < 0 {
+			result.RetryAfter = 0
+		}
+		result.RetryAfter = t.config.roundRetryAfter(result.RetryAfter)
+	}
+	t.warmup.observe(ctx, t.client, t.config, key, int64(math.Ceil(cost)), &result)
+
+	return result, nil
+}
+
+// AllowWithOverflow checks the buckets for keys in order and consumes n tokens
+// from the first bucket that can serve the request.
+// Reports the decision to Config.Observer, under the first key, when one is configured.
+func (t *tokenBucketLimiter) AllowWithOverflow(ctx context.Context, keys []string, n int64) (result *Result, err error) {
+	defer t.config.recoverDecision(&result, &err)
+	if len(keys) == 0 {
+		return nil, ErrInvalidKey
+	}
+	if t.config.Observer == nil {
+		return t.stats.forKey(keys[0]).record(resultPtr(t.config.stampDecision(t.allowWithOverflow(ctx, keys, n))))
+	}
+
+	start := time.Now()
+	result, err = t.stats.forKey(keys[0]).record(resultPtr(t.config.stampDecision(t.allowWithOverflow(ctx, keys, n))))
+	t.config.observeDecision(ctx, keys[0], n, start, result, err)
+	return result, err
+}
+
+// allowWithOverflow makes the decision for AllowWithOverflow.
+func (t *tokenBucketLimiter) allowWithOverflow(ctx context.Context, keys []string, n int64) (Result, error) {
 	if n <= 0 {
-		return nil, ErrInvalidN
+		return Result{}, ErrInvalidN
+	}
+	if err := validateScriptKeys(len(keys)); err != nil {
+		return Result{}, err
+	}
+	limit := t.limit.load()
+	if result, unlimited := t.config.outsideSchedule(keys[0], limit); unlimited {
+		return result, nil
+	}
+	if result, denied := t.config.permanentDenial(ctx, keys[0], float64(n), limit); denied {
+		return result, nil
 	}
 
-	redisKey := t.config.FormatKey(key)
 	refillRate := t.calculateRefillRate()
-	now := float64(time.Now().UnixNano()) / 1e9 // Convert to seconds with fractional part
+	now := float64(time.Now().UnixNano()) / 1e9
 
-	allowed, remaining, err := t.tryConsume(ctx, redisKey, n, refillRate, now)
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		if key == "" {
+			return Result{}, ErrInvalidKey
+		}
+		redisKeys[i] = t.stateKey(key, now)
+	}
+
+	// Requests that aren't critical leave the reserve for those that are
+	reserve := t.config.reserveFor(ctx)
+	allowed, index, remaining, err := t.tryConsumeOverflow(ctx, redisKeys, n, refillRate, now)
 	if err != nil {
-		if t.config.FailOpen {
+		if t.config.FailOpen && !misconfigured(err) {
 			// Fail open: allow the request
-			return &Result{
+			return Result{
 				Allowed:    true,
-				Limit:      t.config.Limit,
+				Limit:      limit,
 				Remaining:  0,
 				RetryAfter: 0,
 				ResetAt:    t.calculateResetTime(now),
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", backendError(err))
 	}
 
-	result := &Result{
+	result := Result{
 		Allowed:    allowed,
-		Limit:      t.config.Limit,
+		Limit:      limit,
 		Remaining:  remaining,
 		RetryAfter: 0,
 		ResetAt:    t.calculateResetTime(now),
 	}
 
-	if !allowed {
-		// Calculate time until enough tokens are available
-		tokensNeeded := float64(n - remaining)
+	if allowed {
+		result.Source = keys[index]
+	} else {
+		result.Reason = ReasonLimitExceeded
+		result.DeniedBy = keys[len(keys)-1]
+
+		// The fullest bucket is the first one able to serve the request again
+		tokensNeeded := float64(n + reserve - remaining)
 		secondsToWait := tokensNeeded / refillRate
 		result.RetryAfter = time.Duration(secondsToWait * float64(time.Second))
 		if result.RetryAfter < 0 {
 			result.RetryAfter = 0
 		}
+		result.RetryAfter = t.config.roundRetryAfter(result.RetryAfter)
+	}
+
+	return result, nil
+}
+
+// AllowClass checks if N requests of the given class are allowed for the key,
+// using the class's limit from Config.ClassLimits and its own sub-bucket.
+func (t *tokenBucketLimiter) AllowClass(ctx context.Context, key string, class string, n int64) (result *Result, err error) {
+	defer t.config.recoverDecision(&result, &err)
+	limit, err := t.config.classLimit(class)
+	if err != nil {
+		return nil, err
+	}
+	return t.observeAllowN(ctx, classKey(key, class), n, limit)
+}
+
+// AllowHinted serves the request locally when the caller's hint shows the key
+// is comfortably under its limit, and falls back to AllowN otherwise.
+func (t *tokenBucketLimiter) AllowHinted(ctx context.Context, key string, n int64, localHint int64) (result *Result, err error) {
+	defer t.config.recoverDecision(&result, &err)
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+	limit := t.config.keyLimit(key, t.limit.load())
+	// Request spacing needs the last allowed time stored in Redis, and a
+	// reserve or ObserveFirst the stored tokens
+	if t.config.MinInterval > 0 || t.config.denylisted(key) || t.config.reserveFor(ctx) > 0 || t.warmup != nil ||
+		!hintIsSafe(limit, n, localHint) {
+		return t.AllowN(ctx, key, n)
+	}
+
+	resetAt := t.calculateResetTime(float64(time.Now().UnixNano()) / 1e9)
+	return t.recordDecision(ctx, key, n, func(limit int64) (Result, error) {
+		return *NewAllowedResult(limit, limit-localHint-n, resetAt), nil
+	})
+}
+
+// Peek returns the key's bucket status without consuming tokens. The refill
+// since the last request is computed locally; nothing is written to Redis.
+func (t *tokenBucketLimiter) Peek(ctx context.Context, key string) (result *Result, err error) {
+	defer t.config.recoverError(&err)
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	limit := t.config.keyLimit(key, t.limit.load())
+	refillRate := t.refillRateFor(limit)
+	now := float64(time.Now().UnixNano()) / 1e9
+
+	tokens, err := t.peekTokens(ctx, key, limit, refillRate, now)
+	if err != nil {
+		return nil, err
+	}
+
+	const epsilon = 1e-9 // Same tolerance as tokenBucketScript
+	result = &Result{
+		Allowed:   tokens+epsilon >= 1,
+		Limit:     limit,
+		Remaining: int64(math.Floor(tokens + epsilon)),
+		ResetAt:   t.calculateResetTime(now),
+	}
+	if !result.Allowed {
+		secondsToWait := (1 - tokens) / refillRate
+		result.RetryAfter = t.config.roundRetryAfter(time.Duration(secondsToWait * float64(time.Second)))
 	}
 
 	return result, nil
 }
 
+// FullResetAt returns when the key's bucket will be full again if no
+// further requests consume from it: now for a full bucket.
+func (t *tokenBucketLimiter) FullResetAt(ctx context.Context, key string) (resetAt time.Time, err error) {
+	defer t.config.recoverError(&err)
+	if key == "" {
+		return time.Time{}, ErrInvalidKey
+	}
+
+	limit := t.config.keyLimit(key, t.limit.load())
+	refillRate := t.refillRateFor(limit)
+	now := float64(time.Now().UnixNano()) / 1e9
+
+	tokens, err := t.peekTokens(ctx, key, limit, refillRate, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	secondsToFull := (float64(limit) - tokens) / refillRate
+	return time.Unix(0, int64((now+secondsToFull)*1e9)), nil
+}
+
+// peekTokens returns the tokens in the key's bucket as of now, refilled
+// locally without writing to Redis.
+func (t *tokenBucketLimiter) peekTokens(ctx context.Context, key string, limit int64, refillRate, now float64) (float64, error) {
+	values, err := t.client.HMGet(ctx, t.stateKey(key, now), "tokens", "last_refill").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to peek rate limit: %w", keyTypeError(err, TokenBucket))
+	}
+
+	// A bucket that doesn't exist yet would be created with its initial tokens
+	tokens := float64(limit)
+	if initial := t.config.initialTokens(limit); initial > 0 {
+		tokens = float64(min(limit, initial))
+	}
+	if values[0] != nil && values[1] != nil {
+		stored, err := parseFloat(values[0])
+		if err != nil {
+			return 0, err
+		}
+		lastRefill, err := parseFloat(values[1])
+		if err != nil {
+			return 0, err
+		}
+		// A clock behind the stored refill time adds nothing, as in tokenBucketScript
+		tokens = math.Min(float64(limit), stored+math.Max(0, now-lastRefill)*refillRate)
+	}
+	return tokens, nil
+}
+
+// UsedMany returns how many tokens each key's bucket is below capacity,
+// refilled as of now, reading the buckets with one pipelined HMGET per key.
+// Keys without a bucket report 0.
+func (t *tokenBucketLimiter) UsedMany(ctx context.Context, keys []string) (used map[string]int64, err error) {
+	defer t.config.recoverError(&err)
+	for _, key := range keys {
+		if key == "" {
+			return nil, ErrInvalidKey
+		}
+	}
+
+	limit := t.limit.load()
+	now := float64(time.Now().UnixNano()) / 1e9
+
+	pipe := t.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HMGet(ctx, t.stateKey(key, now), "tokens", "last_refill")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	const epsilon = 1e-9 // Same tolerance as tokenBucketScript
+	used = make(map[string]int64, len(keys))
+	for i, key := range keys {
+		values := cmds[i].Val()
+		if values[0] == nil || values[1] == nil {
+			used[key] = 0
+			continue
+		}
+		stored, err := parseFloat(values[0])
+		if err != nil {
+			return nil, err
+		}
+		lastRefill, err := parseFloat(values[1])
+		if err != nil {
+			return nil, err
+		}
+		keyLimit := t.config.keyLimit(key, limit)
+		tokens := math.Min(float64(keyLimit), stored+math.Max(0, now-lastRefill)*t.refillRateFor(keyLimit))
+		used[key] = keyLimit - int64(math.Floor(tokens+epsilon))
+	}
+	return used, nil
+}
+
 // Reset resets the rate limit counter for the given key.
+// With Config.WindowedState only the current window's bucket is cleared.
 func (t *tokenBucketLimiter) Reset(ctx context.Context, key string) error {
-	redisKey := t.config.FormatKey(key)
+	redisKeys := t.config.withWarmupKey([]string{t.stateKey(key, float64(time.Now().UnixNano())/1e9)}, key)
 
-	if err := t.client.Del(ctx, redisKey).Err(); err != nil {
+	if err := t.client.Del(ctx, redisKeys...).Err(); err != nil {
 		return fmt.Errorf("failed to reset rate limit: %w", err)
 	}
+	t.warmup.reset(key)
+
+	t.config.observeReset(ctx, key)
+	return nil
+}
+
+// MemoryUsage returns the Redis memory used by the key's bucket and its
+// Config.ObserveFirst count.
+func (t *tokenBucketLimiter) MemoryUsage(ctx context.Context, key string) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	redisKeys := t.config.withWarmupKey([]string{t.stateKey(key, float64(time.Now().UnixNano())/1e9)}, key)
+	return memoryUsage(ctx, t.client, redisKeys)
+}
+
+// MaxBurst returns the worst-case burst for a token bucket: its capacity (Limit).
+// A bucket that has been idle long enough refills to capacity, all of which
+// can be consumed at once.
+func (t *tokenBucketLimiter) MaxBurst() int64 {
+	return t.limit.load()
+}
+
+// SetLimit changes the limit applied to subsequent decisions.
+func (t *tokenBucketLimiter) SetLimit(limit int64) error {
+	return t.limit.set(limit)
+}
+
+// Describe returns the quota the limiter currently enforces.
+func (t *tokenBucketLimiter) Describe() QuotaInfo {
+	return t.limit.describe(t.config)
+}
+
+// AllowDescribe checks a single request and describes the quota it was
+// checked against.
+func (t *tokenBucketLimiter) AllowDescribe(ctx context.Context, key string) (*Result, QuotaInfo, error) {
+	return allowDescribe(ctx, t, key)
+}
+
+// AllowNDecision checks N requests and records the call as a Decision.
+func (t *tokenBucketLimiter) AllowNDecision(ctx context.Context, key string, n int64) (*Decision, error) {
+	return allowNDecision(ctx, t, key, n)
+}
+
+// StartKeyCounter periodically counts the keys under the limiter's prefix.
+func (t *tokenBucketLimiter) StartKeyCounter(ctx context.Context, interval time.Duration) (func(), <-chan int) {
+	return startKeyCounter(ctx, t.client, t.config.keyPattern(), interval)
+}
+
+// Reconcile takes the difference between the request's actual cost and
+// ProvisionalCost from the key's bucket; see Reconciler.
+func (t *tokenBucketLimiter) Reconcile(ctx context.Context, key string, actualCost int64) (err error) {
+	defer t.config.recoverError(&err)
+	delta, err := reconcileDelta(key, actualCost)
+	if err != nil {
+		return err
+	}
+	return t.adjust(ctx, key, delta)
+}
+
+// adjust takes delta tokens from the key's bucket (refunds them when
+// negative); see adjuster.
+func (t *tokenBucketLimiter) adjust(ctx context.Context, key string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	if err := t.config.checkCallBudget(ctx); err != nil {
+		return err
+	}
 
+	limit := t.config.keyLimit(key, t.limit.load())
+	now := float64(time.Now().UnixNano()) / 1e9
+	err := t.client.Eval(ctx, tokenBucketAdjustScript, []string{t.stateKey(key, now)},
+		limit, delta, now, t.stateTTL(now).Milliseconds(), t.config.initialTokens(limit), t.refillRateFor(limit)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to reconcile rate limit: %w", keyTypeError(err, TokenBucket))
+	}
 	return nil
 }
 
+// FlushAll deletes every key under the limiter's prefix. Meant for tests;
+// see Flusher.
+func (t *tokenBucketLimiter) FlushAll(ctx context.Context) error {
+	return flushKeys(ctx, t.client, t.config)
+}
+
+// DebugInfo returns a snapshot of the limiter's parameters and decision counts.
+func (t *tokenBucketLimiter) DebugInfo() map[string]any {
+	info := t.config.debugInfo(t.limit, &t.stats)
+	info["script_sha"] = scriptSHA(tokenBucketScript)
+	info["refill_rate"] = t.calculateRefillRate()
+	info["key_ttl"] = t.stateTTL(float64(time.Now().UnixNano()) / 1e9).String()
+	info["initial_tokens"] = t.config.InitialTokens
+	info["initial_fill"] = t.config.InitialFill
+	info["min_interval"] = t.config.MinInterval.String()
+	info["windowed_state"] = t.config.WindowedState
+	return info
+}
+
+// TopDenied returns the most denied keys of the last minute or two, most
+// denied first.
+func (t *tokenBucketLimiter) TopDenied() []KeyCount {
+	return t.stats.topDenied()
+}
+
 // Close closes the rate limiter and releases resources.
 func (t *tokenBucketLimiter) Close() error {
 	if t.client != nil {
@@ -153,41 +769,158 @@ func (t *tokenBucketLimiter) Close() error {
 
 // calculateRefillRate calculates tokens per second based on limit and window.
 func (t *tokenBucketLimiter) calculateRefillRate() float64 {
-	return float64(t.config.Limit) / t.config.Window.Seconds()
+	return t.refillRateFor(t.limit.load())
+}
+
+// refillRateFor calculates tokens per second for a bucket of the given capacity.
+func (t *tokenBucketLimiter) refillRateFor(capacity int64) float64 {
+	return float64(capacity) / t.config.Window.Seconds()
+}
+
+// initialTokens returns the tokens a new bucket of the given capacity starts
+// with, from InitialTokens or else InitialFill; 0 means full capacity.
+func (c *Config) initialTokens(capacity int64) int64 {
+	if c.InitialTokens > 0 {
+		return c.InitialTokens
+	}
+	if c.InitialFill > 0 && c.InitialFill < 1 {
+		return max(1, int64(float64(capacity)*c.InitialFill))
+	}
+	return 0
 }
 
 // calculateResetTime calculates when the bucket will be full again.
 // This is approximate since token bucket refills continuously.
 func (t *tokenBucketLimiter) calculateResetTime(now float64) time.Time {
 	// Estimate: time to fill entire bucket from empty
-	secondsToFull := float64(t.config.Limit) / t.calculateRefillRate()
+	secondsToFull := float64(t.limit.load()) / t.calculateRefillRate()
 	return time.Unix(int64(now), int64((now-float64(int64(now)))*1e9)).Add(time.Duration(secondsToFull * float64(time.Second)))
 }
 
-// tryConsume attempts to consume tokens from the bucket.
-func (t *tokenBucketLimiter) tryConsume(ctx context.Context, key string, n int64, refillRate, now float64) (bool, int64, error) {
-	capacity := t.config.Limit
-	ttl := int64(t.config.Window.Seconds() * 2) // Keep state for 2 windows
+// stateKey returns the Redis key holding the key's bucket. With
+// Config.WindowedState the key is suffixed with the start of the current
+// window, so every window gets its own hash.
+func (t *tokenBucketLimiter) stateKey(key string, now float64) string {
+	if !t.config.WindowedState {
+		return t.config.FormatKey(key)
+	}
+	return fmt.Sprintf("%s:%d", t.config.FormatKey(key), t.windowStart(now))
+}
+
+// windowStart returns the start of the window containing now, in Unix seconds.
+func (t *tokenBucketLimiter) windowStart(now float64) int64 {
+	window := int64(t.config.Window.Seconds())
+	seconds := int64(now)
+	return seconds - seconds%window
+}
 
-	result, err := t.client.Eval(ctx, tokenBucketScript, []string{key}, capacity, n, refillRate, now, ttl).Result()
+// stateTTL returns how long a bucket's state is kept. Continuous buckets are
+// kept for Config.StateRetention (two windows by default); windowed buckets
+// expire when their window ends, since the next window starts from a fresh
+// bucket.
+func (t *tokenBucketLimiter) stateTTL(now float64) time.Duration {
+	if !t.config.WindowedState {
+		return t.config.effectiveTTL(cmp.Or(t.config.StateRetention, 2*t.config.Window))
+	}
+	windowEnd := t.windowStart(now) + int64(t.config.Window.Seconds())
+	return t.config.effectiveTTL(time.Duration((float64(windowEnd) - now) * float64(time.Second)))
+}
+
+// tryConsume attempts to consume tokens from the bucket. It also reports
+// whether the bucket was created by this call, and how long until
+// Config.MinInterval has passed since the last allowed request (0 when
+// spacing does not deny the request).
+func (t *tokenBucketLimiter) tryConsume(ctx context.Context, key string, cost float64, capacity int64, refillRate, now float64) (bool, int64, bool, time.Duration, error) {
+	if err := t.config.checkCallBudget(ctx); err != nil {
+		return false, 0, false, 0, err
+	}
+
+	ttl := t.stateTTL(now).Milliseconds()
+	var fullTTL int64
+	if t.config.ExpireFullBuckets {
+		fullTTL = t.config.effectiveTTL(t.config.Window).Milliseconds()
+	}
+
+	result, err := t.client.Eval(ctx, tokenBucketScript, []string{key}, capacity, cost, refillRate, now, ttl, t.config.TTLRefreshFraction, t.config.initialTokens(capacity), t.config.MinInterval.Seconds(), t.config.reserveFor(ctx), fullTTL).Result()
 	if err != nil {
-		return false, 0, err
+		return false, 0, false, 0, keyTypeError(err, TokenBucket)
 	}
 
 	resultSlice, ok := result.([]interface{})
-	if !ok || len(resultSlice) != 2 {
-		return false, 0, fmt.Errorf("unexpected result type from Redis: %T", result)
+	if !ok || len(resultSlice) != 4 {
+		return false, 0, false, 0, fmt.Errorf("unexpected result type from Redis: %T", result)
 	}
 
 	allowedInt, ok := resultSlice[0].(int64)
 	if !ok {
-		return false, 0, fmt.Errorf("unexpected allowed type: %T", resultSlice[0])
+		return false, 0, false, 0, fmt.Errorf("unexpected allowed type: %T", resultSlice[0])
 	}
 
 	remaining, ok := resultSlice[1].(int64)
 	if !ok {
-		return false, 0, fmt.Errorf("unexpected remaining type: %T", resultSlice[1])
+		return false, 0, false, 0, fmt.Errorf("unexpected remaining type: %T", resultSlice[1])
+	}
+
+	created, ok := resultSlice[2].(int64)
+	if !ok {
+		return false, 0, false, 0, fmt.Errorf("unexpected created type: %T", resultSlice[2])
+	}
+
+	waitMillis, ok := resultSlice[3].(int64)
+	if !ok {
+		return false, 0, false, 0, fmt.Errorf("unexpected wait type: %T", resultSlice[3])
+	}
+
+	return allowedInt == 1, remaining, created == 1, time.Duration(waitMillis) * time.Millisecond, nil
+}
+
+// tryConsumeOverflow attempts to consume tokens from the first bucket with
+// enough capacity. The returned index is 0-based into keys.
+func (t *tokenBucketLimiter) tryConsumeOverflow(ctx context.Context, keys []string, n int64, refillRate, now float64) (bool, int, int64, error) {
+	if err := t.config.checkCallBudget(ctx); err != nil {
+		return false, 0, 0, err
+	}
+
+	capacity := t.limit.load()
+	ttl := t.stateTTL(now).Milliseconds()
+
+	result, err := t.client.Eval(ctx, tokenBucketOverflowScript, keys, capacity, n, refillRate, now, ttl, t.config.TTLRefreshFraction, t.config.initialTokens(capacity), t.config.reserveFor(ctx)).Result()
+	if err != nil {
+		return false, 0, 0, keyTypeError(crossSlotError(err), TokenBucket)
+	}
+
+	resultSlice, ok := result.([]interface{})
+	if !ok || len(resultSlice) != 3 {
+		return false, 0, 0, fmt.Errorf("unexpected result type from Redis: %T", result)
 	}
 
-	return allowedInt == 1, remaining, nil
+	allowedInt, ok := resultSlice[0].(int64)
+	if !ok {
+		return false, 0, 0, fmt.Errorf("unexpected allowed type: %T", resultSlice[0])
+	}
+
+	index, ok := resultSlice[1].(int64)
+	if !ok || index < 1 || int(index) > len(keys) {
+		return false, 0, 0, fmt.Errorf("unexpected bucket index: %v", resultSlice[1])
+	}
+
+	remaining, ok := resultSlice[2].(int64)
+	if !ok {
+		return false, 0, 0, fmt.Errorf("unexpected remaining type: %T", resultSlice[2])
+	}
+
+	return allowedInt == 1, int(index) - 1, remaining, nil
+}
+
+// parseFloat parses a float stored in a token bucket hash field.
+func parseFloat(value interface{}) (float64, error) {
+	v, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected token bucket field type: %T", value)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected token bucket field value %q: %w", v, err)
+	}
+	return f, nil
 }
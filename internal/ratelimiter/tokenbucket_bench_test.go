@@ -441,3 +441,29 @@ func BenchmarkTokenBucket_MixedLoad(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkTokenBucket_TTLRefresh reports how many EXPIREs a hot key issues
+// per request with and without TTL refresh coalescing
+func BenchmarkTokenBucket_TTLRefresh(b *testing.B) {
+	for _, fraction := range []float64{0, 0.5} {
+		b.Run(fmt.Sprintf("fraction=%v", fraction), func(b *testing.B) {
+			client, mr := setupBenchmarkRedisTokenBucket(b)
+			defer mr.Close()
+
+			limiter, err := NewTokenBucket(client, &Config{
+				Algorithm:          TokenBucket,
+				Limit:              1000000000,
+				Window:             10 * time.Second,
+				TTLRefreshFraction: fraction,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer limiter.Close()
+
+			b.ResetTimer()
+			refreshes := countTTLRefreshes(b, mr, limiter, "bench:user:hot", b.N)
+			b.ReportMetric(float64(refreshes)/float64(b.N), "expires/op")
+		})
+	}
+}
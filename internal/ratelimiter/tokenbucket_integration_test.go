@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -460,3 +461,809 @@ func TestTokenBucket_Integration_MaxCapacity(t *testing.T) {
 	// Should be at capacity (10), after consuming 1 = 9 remaining
 	assert.Equal(t, int64(9), result.Remaining)
 }
+
+func TestTokenBucket_Integration_AllowWithOverflow(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     3,
+		Window:    time.Hour, // Slow refill so buckets stay drained during the test
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	overflow, ok := limiter.(OverflowLimiter)
+	require.True(t, ok, "token bucket should implement OverflowLimiter")
+
+	ctx := context.Background()
+	keys := []string{"{org:1}:included", "{org:1}:payg"}
+
+	// Drain the primary bucket
+	for i := 0; i < 3; i++ {
+		result, err := overflow.AllowWithOverflow(ctx, keys, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, "{org:1}:included", result.Source)
+		assert.Empty(t, result.DeniedBy)
+	}
+
+	// Subsequent requests spill to the overflow bucket
+	for i := 0; i < 3; i++ {
+		result, err := overflow.AllowWithOverflow(ctx, keys, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, "{org:1}:payg", result.Source)
+		assert.Equal(t, int64(2-i), result.Remaining)
+	}
+
+	// Both buckets exhausted
+	result, err := overflow.AllowWithOverflow(ctx, keys, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Empty(t, result.Source)
+	assert.Equal(t, "{org:1}:payg", result.DeniedBy)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	// The primary bucket is still tracked under its own key
+	primary, err := limiter.Allow(ctx, "{org:1}:included")
+	require.NoError(t, err)
+	assert.False(t, primary.Allowed)
+}
+
+func TestTokenBucket_Integration_AllowWithOverflow_InvalidInput(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     3,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	overflow := limiter.(OverflowLimiter)
+	ctx := context.Background()
+
+	_, err = overflow.AllowWithOverflow(ctx, nil, 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = overflow.AllowWithOverflow(ctx, []string{"a", ""}, 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = overflow.AllowWithOverflow(ctx, []string{"a"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidN)
+}
+
+func TestTokenBucket_Integration_AllowWithOverflow_AppliesConfig(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	observer := &recordingObserver{}
+	config := &Config{
+		Algorithm:          TokenBucket,
+		Limit:              3,
+		Window:             time.Hour,
+		Denylist:           []string{"{org:2}:included"},
+		ReserveForCritical: 1,
+		Observer:           observer,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	overflow := limiter.(OverflowLimiter)
+	ctx := context.Background()
+	keys := []string{"{org:1}:included", "{org:1}:payg"}
+
+	// Each bucket keeps its last token for critical requests
+	for _, source := range []string{"{org:1}:included", "{org:1}:included", "{org:1}:payg", "{org:1}:payg"} {
+		result, err := overflow.AllowWithOverflow(ctx, keys, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, source, result.Source)
+	}
+
+	result, err := overflow.AllowWithOverflow(ctx, keys, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonLimitExceeded, result.Reason)
+
+	result, err = overflow.AllowWithOverflow(WithCritical(ctx), keys, 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, "{org:1}:included", result.Source)
+
+	// The denylist is checked against the first key
+	result, err = overflow.AllowWithOverflow(ctx, []string{"{org:2}:included", "{org:2}:payg"}, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonDenylisted, result.Reason)
+	assert.True(t, result.Permanent)
+
+	// Every decision is counted and observed
+	assert.Len(t, observer.all(), 7)
+	info := limiter.(DebugInfoProvider).DebugInfo()
+	assert.Equal(t, int64(5), info["decisions_allowed"])
+	assert.Equal(t, int64(2), info["decisions_denied"])
+}
+
+func TestTokenBucket_Integration_ExactRemainingWithFloatDrift(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	tb := limiter.(*tokenBucketLimiter)
+	ctx := context.Background()
+	key := tb.config.FormatKey("user:drift")
+
+	// Seed a bucket whose 5 tokens were persisted with float drift, and
+	// consume at the same instant so no refill can mask the drift
+	now := 1700000000.5
+	mr.HSet(key, "tokens", "4.9999999999999", "last_refill", "1700000000.5")
+
+	allowed, remaining, _, _, err := tb.tryConsume(ctx, key, 5, tb.limit.load(), tb.calculateRefillRate(), now)
+	require.NoError(t, err)
+	assert.True(t, allowed, "consuming exactly the remaining tokens should be allowed")
+	assert.Equal(t, int64(0), remaining)
+
+	// The bucket is now empty and must not go negative
+	allowed, remaining, _, _, err = tb.tryConsume(ctx, key, 1, tb.limit.load(), tb.calculateRefillRate(), now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, int64(0), remaining)
+}
+
+func TestTokenBucket_Integration_ClockRewind(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    10 * time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	tb := limiter.(*tokenBucketLimiter)
+	ctx := context.Background()
+	key := tb.config.FormatKey("user:rewind")
+
+	mr.HSet(key, "tokens", "5", "last_refill", "1700000100")
+
+	// The clock is 100s behind the stored refill time; a negative elapsed
+	// time must not drain the bucket
+	allowed, remaining, _, _, err := tb.tryConsume(ctx, key, 1, tb.limit.load(), tb.calculateRefillRate(), 1700000000)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(4), remaining, "only the consumed token should be removed")
+	assert.Equal(t, "1700000100", mr.HGet(key, "last_refill"), "the stored refill time should be kept")
+
+	// Once the clock catches up, refilling resumes from the stored time
+	allowed, remaining, _, _, err = tb.tryConsume(ctx, key, 1, tb.limit.load(), tb.calculateRefillRate(), 1700000101)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(4), remaining)
+}
+
+func TestTokenBucket_Integration_ExactRemaining(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Hour,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:exact"
+
+	result, err := limiter.AllowN(ctx, key, 3)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// n == remaining is allowed and leaves nothing behind
+	result, err = limiter.AllowN(ctx, key, result.Remaining)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+}
+
+// countTTLRefreshes makes calls requests one second apart (in Redis time) and
+// counts how many of them refreshed the key's TTL. miniredis only lowers a TTL
+// on FastForward, so a TTL that went up was refreshed by EXPIRE.
+func countTTLRefreshes(tb testing.TB, mr *miniredis.Miniredis, limiter RateLimiter, key string, calls int) int {
+	tb.Helper()
+
+	ctx := context.Background()
+	redisKey := limiter.(*tokenBucketLimiter).config.FormatKey(key)
+
+	refreshes := 0
+	for i := 0; i < calls; i++ {
+		before := mr.TTL(redisKey)
+		if _, err := limiter.Allow(ctx, key); err != nil {
+			tb.Fatal(err)
+		}
+		if mr.TTL(redisKey) > before {
+			refreshes++
+		}
+		mr.FastForward(time.Second)
+	}
+	return refreshes
+}
+
+func TestTokenBucket_Integration_TTLRefreshFraction(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	always, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     1000000,
+		Window:    10 * time.Second,
+		Prefix:    "always",
+	})
+	require.NoError(t, err)
+
+	coalesced, err := NewTokenBucket(client, &Config{
+		Algorithm:          TokenBucket,
+		Limit:              1000000,
+		Window:             10 * time.Second,
+		Prefix:             "coalesced",
+		TTLRefreshFraction: 0.5,
+	})
+	require.NoError(t, err)
+
+	alwaysRefreshes := countTTLRefreshes(t, mr, always, "user:hot", 100)
+	coalescedRefreshes := countTTLRefreshes(t, mr, coalesced, "user:hot", 100)
+
+	assert.Equal(t, 100, alwaysRefreshes)
+	assert.Less(t, coalescedRefreshes, alwaysRefreshes/5)
+}
+
+func TestTokenBucket_Integration_TTLRefreshFraction_NeverExpiresEarly(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:          TokenBucket,
+		Limit:              1000000,
+		Window:             10 * time.Second,
+		TTLRefreshFraction: 0.5,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:hot"
+	redisKey := limiter.(*tokenBucketLimiter).config.FormatKey(key)
+
+	// While traffic continues, the key always keeps at least one window of TTL
+	for i := 0; i < 50; i++ {
+		_, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, mr.TTL(redisKey), 10*time.Second, "call %d", i)
+		mr.FastForward(time.Second)
+	}
+
+	// Once traffic stops, the key still expires
+	mr.FastForward(20 * time.Second)
+	assert.False(t, mr.Exists(redisKey))
+}
+
+func TestTokenBucket_Integration_FirstSeen(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	var created []string
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:    TokenBucket,
+		Limit:        10,
+		Window:       time.Hour,
+		OnKeyCreated: func(key string) { created = append(created, key) },
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "user:new")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	for i := 0; i < 3; i++ {
+		result, err = limiter.Allow(ctx, "user:new")
+		require.NoError(t, err)
+		assert.False(t, result.FirstSeen)
+	}
+
+	result, err = limiter.Allow(ctx, "user:other")
+	require.NoError(t, err)
+	assert.True(t, result.FirstSeen)
+
+	assert.Equal(t, []string{"user:new", "user:other"}, created)
+}
+
+func TestTokenBucket_Integration_InitialTokens(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:     TokenBucket,
+		Limit:         100,
+		Window:        time.Hour,
+		InitialTokens: 3,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:warm"
+
+	// A fresh key starts at InitialTokens rather than capacity
+	result, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(2), result.Remaining)
+
+	result, err = limiter.AllowN(ctx, key, 2)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Once the key is gone, the next first touch starts at InitialTokens again
+	require.NoError(t, limiter.Reset(ctx, key))
+
+	result, err = limiter.AllowN(ctx, key, 4)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Remaining)
+}
+
+func TestTokenBucket_Integration_InitialTokens_DefaultFull(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     100,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	result, err := limiter.Allow(context.Background(), "user:cold")
+	require.NoError(t, err)
+	assert.Equal(t, int64(99), result.Remaining)
+}
+
+func TestTokenBucket_Integration_InitialFill(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	config := &Config{
+		Algorithm:   TokenBucket,
+		Limit:       100,
+		Window:      time.Hour,
+		InitialFill: 0.5,
+		LimitResolver: func(key string) int64 {
+			if key == "upload:1" {
+				return 10
+			}
+			return 0
+		},
+	}
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	// A fresh key starts half full
+	result, err := limiter.Allow(ctx, "user:cold")
+	require.NoError(t, err)
+	assert.Equal(t, int64(49), result.Remaining)
+
+	status, err := limiter.(Peeker).Peek(ctx, "user:untouched")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), status.Remaining, "Peek reports the initial fill too")
+
+	// The fill scales with each key's own limit
+	result, err = limiter.Allow(ctx, "upload:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Remaining)
+
+	// InitialTokens takes precedence over InitialFill
+	config.Prefix = "explicit"
+	config.InitialTokens = 3
+	explicit, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+
+	result, err = explicit.Allow(ctx, "user:cold")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Remaining)
+}
+
+func TestTokenBucket_Integration_AllowClass(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:   TokenBucket,
+		Limit:       100,
+		Window:      time.Hour,
+		ClassLimits: map[string]int64{"read": 10, "write": 3},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	classes, ok := limiter.(ClassLimiter)
+	require.True(t, ok, "token bucket should implement ClassLimiter")
+
+	ctx := context.Background()
+	key := "user:123"
+
+	result, err := classes.AllowClass(ctx, key, "write", 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Limit)
+
+	result, err = classes.AllowClass(ctx, key, "write", 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// The read bucket has its own capacity
+	result, err = classes.AllowClass(ctx, key, "read", 10)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(10), result.Limit)
+
+	_, err = classes.AllowClass(ctx, key, "admin", 1)
+	assert.ErrorIs(t, err, ErrUnknownClass)
+}
+
+func TestTokenBucket_Integration_RoundRetryAfter(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+
+	// 3 tokens per 10s refill one token every 3.33s
+	for _, round := range []bool{false, true} {
+		limiter, err := NewTokenBucket(client, &Config{
+			Algorithm:       TokenBucket,
+			Limit:           3,
+			Window:          10 * time.Second,
+			RoundRetryAfter: round,
+		})
+		require.NoError(t, err)
+
+		key := "user:round"
+		if round {
+			key = "user:round:rounded"
+		}
+		_, err = limiter.AllowN(ctx, key, 3)
+		require.NoError(t, err)
+
+		result, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		require.False(t, result.Allowed)
+
+		if round {
+			assert.Equal(t, 4*time.Second, result.RetryAfter)
+		} else {
+			assert.InDelta(t, float64(3333*time.Millisecond), float64(result.RetryAfter), float64(50*time.Millisecond))
+		}
+		assert.Equal(t, int64(4), result.RetryAfterSeconds())
+	}
+}
+
+func TestTokenBucket_Integration_Peek(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     5,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	peeker, ok := limiter.(Peeker)
+	require.True(t, ok, "token bucket should implement Peeker")
+
+	ctx := context.Background()
+	key := "user:peek"
+
+	result, err := peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Remaining)
+	assert.Empty(t, mr.Keys())
+
+	_, err = limiter.AllowN(ctx, key, 5)
+	require.NoError(t, err)
+
+	result, err = peeker.Peek(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+	// One token refills every 12 minutes
+	assert.InDelta(t, float64(12*time.Minute), float64(result.RetryAfter), float64(time.Second))
+}
+
+func TestTokenBucket_Integration_MinInterval(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:   TokenBucket,
+		Limit:       100,
+		Window:      time.Minute,
+		MinInterval: 200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:spaced"
+
+	result, err := limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// The bucket is nearly full, but the second request comes too soon
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(99), result.Remaining, "a spaced-out request should not consume tokens")
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, result.RetryAfter, 200*time.Millisecond)
+
+	time.Sleep(result.RetryAfter + 10*time.Millisecond)
+
+	result, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestTokenBucket_Integration_WindowedState(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:     TokenBucket,
+		Limit:         10,
+		Window:        time.Hour,
+		WindowedState: true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	windowStart := strconv.FormatInt(time.Now().Truncate(time.Hour).Unix(), 10)
+
+	_, err = limiter.AllowN(ctx, "user:1", 3)
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "user:2")
+	require.NoError(t, err)
+
+	// Every bucket of the current window shares its suffix and expires with it
+	keys := mr.Keys()
+	assert.ElementsMatch(t, []string{
+		"ratelimit:user:1:" + windowStart,
+		"ratelimit:user:2:" + windowStart,
+	}, keys)
+	for _, key := range keys {
+		assert.Equal(t, "hash", mr.Type(key))
+		assert.Greater(t, mr.TTL(key), time.Duration(0))
+		assert.LessOrEqual(t, mr.TTL(key), time.Hour)
+	}
+
+	peeker := limiter.(Peeker)
+	result, err := peeker.Peek(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Remaining)
+
+	// Reset clears the current window's hash for that key only
+	require.NoError(t, limiter.Reset(ctx, "user:1"))
+	assert.Equal(t, []string{"ratelimit:user:2:" + windowStart}, mr.Keys())
+
+	result, err = limiter.Allow(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), result.Remaining)
+}
+
+func TestTokenBucket_Integration_RequestsUntilDenied(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+
+	uniform, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+
+	result, err := uniform.Allow(ctx, "user:uniform")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), result.Remaining)
+	assert.Equal(t, result.Remaining, result.RequestsUntilDenied)
+
+	costly, err := NewTokenBucket(client, &Config{
+		Algorithm:   TokenBucket,
+		Limit:       10,
+		Window:      time.Hour,
+		RequestCost: func(key string) int64 { return 2 },
+	})
+	require.NoError(t, err)
+
+	// 8 tokens left cover 4 more requests of cost 2
+	result, err = costly.AllowN(ctx, "user:costly", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), result.Remaining)
+	assert.Equal(t, int64(4), result.RequestsUntilDenied)
+
+	// An odd remainder rounds down, since a partial request is denied
+	result, err = costly.Allow(ctx, "user:costly")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Remaining)
+	assert.Equal(t, int64(3), result.RequestsUntilDenied)
+}
+
+func TestTokenBucket_Integration_AllowCostF(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     2,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fractional, ok := limiter.(FractionalLimiter)
+	require.True(t, ok, "token bucket should implement FractionalLimiter")
+
+	ctx := context.Background()
+	key := "user:fractional"
+
+	// Half a unit leaves 1.5 tokens, reported as 1 whole remaining
+	result, err := fractional.AllowCostF(ctx, key, 0.5)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	tokens, err := strconv.ParseFloat(mr.HGet("ratelimit:"+key, "tokens"), 64)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, tokens, 0.01)
+
+	// Three more halves use up the bucket exactly
+	for range 3 {
+		result, err = fractional.AllowCostF(ctx, key, 0.5)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result, err = fractional.AllowCostF(ctx, key, 0.5)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+}
+
+func TestTokenBucket_Integration_UsedMany(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Hour,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.AllowN(ctx, "user:1", 3)
+	require.NoError(t, err)
+	_, err = limiter.AllowN(ctx, "user:2", 10)
+	require.NoError(t, err)
+
+	used, err := limiter.(UsageReader).UsedMany(ctx, []string{"user:1", "user:2", "user:untouched"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"user:1": 3, "user:2": 10, "user:untouched": 0}, used)
+	assert.False(t, mr.Exists(limiter.(*tokenBucketLimiter).config.FormatKey("user:untouched")), "reading should not create buckets")
+}
+
+func TestTokenBucket_Integration_FullResetAt(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	// Refills one token per second
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    10 * time.Second,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, err = limiter.AllowN(ctx, "user:1", 4)
+	require.NoError(t, err)
+
+	fullAt, err := limiter.(FullResetReporter).FullResetAt(ctx, "user:1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(4*time.Second), fullAt, 100*time.Millisecond)
+
+	fullAt, err = limiter.(FullResetReporter).FullResetAt(ctx, "user:untouched")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), fullAt, 100*time.Millisecond, "a full bucket is reset now")
+
+	_, err = limiter.(FullResetReporter).FullResetAt(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestTokenBucket_Integration_ExpireFullBuckets(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm:         TokenBucket,
+		Limit:             20,
+		Window:            2 * time.Second,
+		StateRetention:    time.Hour,
+		ExpireFullBuckets: true,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	key := "user:1"
+	redisKey := limiter.(*tokenBucketLimiter).config.FormatKey(key)
+
+	// A new bucket starts full, so it is only kept for the window
+	_, err = limiter.AllowN(ctx, key, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, mr.TTL(redisKey))
+
+	// A bucket that still owes tokens is kept for the whole retention
+	_, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, mr.TTL(redisKey))
+
+	// Once refilled to capacity (6 tokens at 10 per second), the TTL is reduced to the window again
+	time.Sleep(700 * time.Millisecond)
+	_, err = limiter.Allow(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, mr.TTL(redisKey))
+}
@@ -1,6 +1,8 @@
 package ratelimiter
 
 import (
+	"context"
+	"math"
 	"testing"
 	"time"
 
@@ -130,9 +132,58 @@ func TestTokenBucket_CalculateRefillRate(t *testing.T) {
 	}
 }
 
+func TestTokenBucket_MaxBurst(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	config := &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	}
+
+	limiter, err := NewTokenBucket(client, config)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	reporter, ok := limiter.(BurstReporter)
+	require.True(t, ok, "limiter should implement BurstReporter")
+	assert.Equal(t, int64(10), reporter.MaxBurst())
+}
+
+func TestTokenBucket_AllowCostF_InvalidCost(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     10,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	fractional := limiter.(FractionalLimiter)
+	for _, cost := range []float64{0, -0.5, math.NaN(), math.Inf(1)} {
+		result, err := fractional.AllowCostF(context.Background(), "test-key", cost)
+		assert.ErrorIs(t, err, ErrInvalidCost, "cost %v", cost)
+		assert.Nil(t, result)
+	}
+}
+
 func TestTokenBucket_InterfaceContract(t *testing.T) {
 	// Verify that tokenBucketLimiter implements RateLimiter interface
 	var _ RateLimiter = (*tokenBucketLimiter)(nil)
+	var _ OverflowLimiter = (*tokenBucketLimiter)(nil)
+	var _ ValueLimiter = (*tokenBucketLimiter)(nil)
+	var _ LimitSetter = (*tokenBucketLimiter)(nil)
+	var _ ClassLimiter = (*tokenBucketLimiter)(nil)
+	var _ Peeker = (*tokenBucketLimiter)(nil)
+	var _ DebugInfoProvider = (*tokenBucketLimiter)(nil)
+	var _ KeyCounter = (*tokenBucketLimiter)(nil)
+	var _ FractionalLimiter = (*tokenBucketLimiter)(nil)
+	var _ UsageReader = (*tokenBucketLimiter)(nil)
+	var _ DeniedTracker = (*tokenBucketLimiter)(nil)
+	var _ Flusher = (*tokenBucketLimiter)(nil)
+	var _ DescribingLimiter = (*tokenBucketLimiter)(nil)
+	var _ Reconciler = (*tokenBucketLimiter)(nil)
+	var _ FullResetReporter = (*tokenBucketLimiter)(nil)
 }
 
 func TestTokenBucket_Close(t *testing.T) {
@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TopDeniedWindow is how long a denial counts towards TopDenied.
+const TopDeniedWindow = time.Minute
+
+// KeyCount is a key with how many of its requests were denied.
+type KeyCount struct {
+	Key   string
+	Count int64
+}
+
+// deniedTracker counts denials per key in process with bounded memory, for
+// Config.TrackTopDenied.
+//
+// Each TopDeniedWindow gets its own table of at most capacity keys, kept
+// with the Space-Saving algorithm: when the table is full, a new key replaces
+// the key with the fewest denials and inherits its count plus one. Counts may
+// therefore be overestimated, but any key denied more than 1/capacity of the
+// window's denials is always present. TopDenied merges the current and
+// previous windows, so it covers the last one to two windows.
+type deniedTracker struct {
+	capacity int
+	now      func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	current  map[string]int64
+	previous map[string]int64
+}
+
+// newDeniedTracker returns a tracker for up to capacity keys per window, or
+// nil when capacity is 0 (tracking disabled).
+func newDeniedTracker(capacity int) *deniedTracker {
+	if capacity <= 0 {
+		return nil
+	}
+	return &deniedTracker{
+		capacity: capacity,
+		now:      time.Now,
+		current:  make(map[string]int64, capacity),
+	}
+}
+
+// record counts a denial of key.
+func (d *deniedTracker) record(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rotate()
+	if _, ok := d.current[key]; !ok && len(d.current) >= d.capacity {
+		// Evict the key with the fewest denials; the new key inherits its
+		// count, the Space-Saving bound on how often it may have been denied
+		evicted, fewest := "", int64(0)
+		for k, count := range d.current {
+			if evicted == "" || count < fewest {
+				evicted, fewest = k, count
+			}
+		}
+		delete(d.current, evicted)
+		d.current[key] = fewest
+	}
+	d.current[key]++
+}
+
+// top returns the tracked keys of the current and previous windows, most
+// denied first.
+func (d *deniedTracker) top() []KeyCount {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rotate()
+	counts := make(map[string]int64, len(d.current)+len(d.previous))
+	for _, table := range []map[string]int64{d.previous, d.current} {
+		for key, count := range table {
+			counts[key] += count
+		}
+	}
+
+	top := make([]KeyCount, 0, len(counts))
+	for key, count := range counts {
+		top = append(top, KeyCount{Key: key, Count: count})
+	}
+	slices.SortFunc(top, func(a, b KeyCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+	if len(top) > d.capacity {
+		top = top[:d.capacity]
+	}
+	return top
+}
+
+// rotate starts a new window once the current one is over. Must be called
+// with mu held.
+func (d *deniedTracker) rotate() {
+	now := d.now()
+	elapsed := now.Sub(d.start)
+	if elapsed < TopDeniedWindow {
+		return
+	}
+
+	d.previous = d.current
+	if elapsed >= 2*TopDeniedWindow {
+		// The current window ended over a window ago
+		d.previous = nil
+	}
+	d.current = make(map[string]int64, d.capacity)
+	d.start = now.Truncate(TopDeniedWindow)
+}
+
+// topDenied returns the most denied keys, or nil when tracking is disabled.
+func (s *decisionStats) topDenied() []KeyCount {
+	if s.deniedKeys == nil {
+		return nil
+	}
+	return s.deniedKeys.top()
+}
@@ -0,0 +1,109 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeniedTracker_HeavyHittersSurviveManyKeys(t *testing.T) {
+	tracker := newDeniedTracker(10)
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	tracker.now = clock.Now
+
+	// Three abusers, each with over a tenth of the denials, hidden among 2000
+	// keys denied once each
+	for i := 0; i < 2000; i++ {
+		tracker.record(fmt.Sprintf("user:%d", i))
+		if i%5 == 0 {
+			tracker.record("abuser:1")
+			tracker.record("abuser:2")
+		}
+		if i%4 == 0 {
+			tracker.record("abuser:3")
+		}
+	}
+
+	top := tracker.top()
+	require.Len(t, top, 10, "memory is bounded by the capacity")
+	keys := make([]string, 0, 3)
+	for _, entry := range top[:3] {
+		keys = append(keys, entry.Key)
+	}
+	assert.ElementsMatch(t, []string{"abuser:1", "abuser:2", "abuser:3"}, keys)
+	assert.GreaterOrEqual(t, top[0].Count, int64(500), "counts may only be overestimated")
+	assert.GreaterOrEqual(t, top[0].Count, top[9].Count)
+}
+
+func TestDeniedTracker_ForgetsOldWindows(t *testing.T) {
+	tracker := newDeniedTracker(5)
+	clock := &fakeClock{now: time.Unix(1700000000, 0).Truncate(TopDeniedWindow)}
+	tracker.now = clock.Now
+
+	tracker.record("user:1")
+	tracker.record("user:1")
+
+	clock.Advance(TopDeniedWindow)
+	tracker.record("user:1")
+	tracker.record("user:2")
+	assert.Equal(t, []KeyCount{{Key: "user:1", Count: 3}, {Key: "user:2", Count: 1}}, tracker.top())
+
+	clock.Advance(TopDeniedWindow)
+	assert.Equal(t, []KeyCount{{Key: "user:1", Count: 1}, {Key: "user:2", Count: 1}}, tracker.top())
+
+	clock.Advance(2 * TopDeniedWindow)
+	assert.Empty(t, tracker.top())
+}
+
+func TestTopDenied_RecordsLimiterDenials(t *testing.T) {
+	client, mr := setupMiniredis(t)
+	defer mr.Close()
+
+	limiter, err := NewFixedWindow(client, &Config{
+		Algorithm:      FixedWindow,
+		Limit:          1,
+		Window:         time.Minute,
+		TrackTopDenied: 3,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for key, requests := range map[string]int{"user:1": 6, "user:2": 4, "user:3": 2, "user:4": 1} {
+		for i := 0; i < requests; i++ {
+			_, err := limiter.Allow(ctx, key)
+			require.NoError(t, err)
+		}
+	}
+
+	// Allowed requests are not counted, and user:4 was never denied
+	assert.Equal(t, []KeyCount{
+		{Key: "user:1", Count: 5},
+		{Key: "user:2", Count: 3},
+		{Key: "user:3", Count: 1},
+	}, limiter.(DeniedTracker).TopDenied())
+}
+
+func TestTopDenied_DisabledByDefault(t *testing.T) {
+	client, mr := setupMiniredisTokenBucket(t)
+	defer mr.Close()
+
+	limiter, err := NewTokenBucket(client, &Config{
+		Algorithm: TokenBucket,
+		Limit:     1,
+		Window:    time.Minute,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+	}
+	assert.Nil(t, limiter.(DeniedTracker).TopDenied())
+}
@@ -0,0 +1,16 @@
+package ratelimiter
+
+import (
+	"cmp"
+	"time"
+)
+
+// effectiveTTL returns the TTL to set on a Redis key whose state must last
+// ttl: at least Config.MinKeyTTL (DefaultMinKeyTTL when unset), rounded up to
+// whole milliseconds. Every script sets TTLs with PEXPIRE, so a short window,
+// or what is left of one, never turns into a TTL of 0 that would delete the
+// key as soon as it is written.
+func (c *Config) effectiveTTL(ttl time.Duration) time.Duration {
+	ttl = max(ttl, cmp.Or(c.MinKeyTTL, DefaultMinKeyTTL))
+	return (ttl + time.Millisecond - 1).Truncate(time.Millisecond)
+}
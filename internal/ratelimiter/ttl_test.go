@@ -0,0 +1,39 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_EffectiveTTL(t *testing.T) {
+	tests := []struct {
+		name      string
+		minKeyTTL time.Duration
+		ttl       time.Duration
+		want      time.Duration
+	}{
+		{"window above the minimum", 0, time.Minute, time.Minute},
+		{"zero", 0, 0, DefaultMinKeyTTL},
+		{"negative", 0, -time.Hour, DefaultMinKeyTTL},
+		{"sub-second", 0, 300 * time.Millisecond, DefaultMinKeyTTL},
+		{"rounded up to milliseconds", 0, 1500*time.Millisecond + time.Microsecond, 1501 * time.Millisecond},
+		{"custom minimum", 5 * time.Second, 2 * time.Second, 5 * time.Second},
+		{"sub-millisecond minimum", time.Microsecond, 0, time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{MinKeyTTL: tt.minKeyTTL}
+			assert.Equal(t, tt.want, config.effectiveTTL(tt.ttl))
+		})
+	}
+
+	// Never zero or negative, which would make PEXPIRE delete the key
+	for _, minKeyTTL := range []time.Duration{0, time.Nanosecond, time.Second} {
+		config := &Config{MinKeyTTL: minKeyTTL}
+		for ttl := -time.Second; ttl <= 3*time.Second; ttl += 7 * time.Millisecond {
+			assert.GreaterOrEqual(t, config.effectiveTTL(ttl).Milliseconds(), int64(1), "MinKeyTTL %v, ttl %v", minKeyTTL, ttl)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// minWaitInterval is the shortest pause between attempts in WaitN, used when
+// a denial carries no RetryAfter so that WaitN never spins against Redis.
+const minWaitInterval = 10 * time.Millisecond
+
+// WaitError is returned by Wait and WaitN when the context ends before the
+// request is allowed.
+type WaitError struct {
+	// Remaining is how much longer the caller would have had to wait, as of
+	// the moment the wait was abandoned. Callers can use it to decide whether
+	// to retry elsewhere instead.
+	Remaining time.Duration
+
+	// Err is the context error that ended the wait
+	Err error
+}
+
+// Error returns a description including the remaining wait.
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("rate limit wait abandoned with %v remaining: %v", e.Remaining, e.Err)
+}
+
+// Unwrap returns the context error, so errors.Is(err, context.Canceled) works.
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// Wait blocks until a single request is allowed for the key.
+// See WaitN.
+func Wait(ctx context.Context, limiter RateLimiter, key string) (*Result, error) {
+	return WaitN(ctx, limiter, key, 1)
+}
+
+// WaitN blocks until N requests are allowed for the key, sleeping for each
+// denial's RetryAfter between attempts.
+//
+// If ctx is cancelled mid-wait, WaitN returns a *WaitError carrying the
+// remaining wait. If ctx has a deadline that the next attempt would miss,
+// WaitN returns the *WaitError immediately instead of sleeping until it.
+// A permanent denial (see Result.Permanent) is returned at once with
+// ErrPermanentDenial, since no amount of waiting would help.
+// Errors from the limiter itself are returned unchanged.
+func WaitN(ctx context.Context, limiter RateLimiter, key string, n int64) (*Result, error) {
+	for {
+		result, err := limiter.AllowN(ctx, key, n)
+		if err != nil {
+			return nil, err
+		}
+		if result.Allowed {
+			return result, nil
+		}
+		if result.Permanent {
+			return result, fmt.Errorf("%w: %s", ErrPermanentDenial, result.Reason)
+		}
+
+		wait := max(result.RetryAfter, minWaitInterval)
+		retryAt := time.Now().Add(wait)
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(retryAt) {
+			return nil, &WaitError{Remaining: wait, Err: context.DeadlineExceeded}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &WaitError{Remaining: max(time.Until(retryAt), 0), Err: ctx.Err()}
+		}
+	}
+}
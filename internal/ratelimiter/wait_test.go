@@ -0,0 +1,91 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// denyingLimiter denies its first denials calls with the given RetryAfter
+type denyingLimiter struct {
+	denials    int
+	retryAfter time.Duration
+	calls      int
+}
+
+func (d *denyingLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return d.AllowN(ctx, key, 1)
+}
+
+func (d *denyingLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	d.calls++
+	if d.calls <= d.denials {
+		return NewDeniedResult(10, d.retryAfter, time.Now().Add(d.retryAfter)), nil
+	}
+	return NewAllowedResult(10, 9, time.Now().Add(time.Minute)), nil
+}
+
+func (d *denyingLimiter) Reset(ctx context.Context, key string) error { return nil }
+
+func (d *denyingLimiter) Close() error { return nil }
+
+func TestWaitN_WaitsForRetryAfter(t *testing.T) {
+	limiter := &denyingLimiter{denials: 2, retryAfter: 20 * time.Millisecond}
+
+	start := time.Now()
+	result, err := WaitN(context.Background(), limiter, "user:1", 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 3, limiter.calls)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestWaitN_CancelledMidWait(t *testing.T) {
+	limiter := &denyingLimiter{denials: 1, retryAfter: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	result, err := Wait(ctx, limiter, "user:1")
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var waitErr *WaitError
+	require.True(t, errors.As(err, &waitErr))
+	// Cancelled ~100ms into a 1s wait
+	assert.Greater(t, waitErr.Remaining, 700*time.Millisecond)
+	assert.LessOrEqual(t, waitErr.Remaining, 900*time.Millisecond)
+	assert.Equal(t, 1, limiter.calls)
+}
+
+func TestWaitN_DeadlineTooSoon(t *testing.T) {
+	limiter := &denyingLimiter{denials: 1, retryAfter: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The retry would miss the deadline, so WaitN gives up without sleeping
+	start := time.Now()
+	_, err := WaitN(ctx, limiter, "user:1", 1)
+	assert.Less(t, time.Since(start), 40*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var waitErr *WaitError
+	require.True(t, errors.As(err, &waitErr))
+	assert.Equal(t, time.Minute, waitErr.Remaining)
+}
+
+func TestWaitN_LimiterError(t *testing.T) {
+	backendErr := errors.New("redis down")
+	limiter := &scriptedLimiter{err: backendErr}
+
+	_, err := WaitN(context.Background(), limiter, "user:1", 1)
+	assert.ErrorIs(t, err, backendErr)
+
+	var waitErr *WaitError
+	assert.False(t, errors.As(err, &waitErr))
+}
@@ -0,0 +1,140 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// warmupScript counts a key's requests towards Config.ObserveFirst, and stops
+// counting once the warmup is over. Every call renews the count's TTL.
+//
+// KEYS[1]: The Redis key holding the key's request count
+// ARGV[1]: The requests to count (n)
+// ARGV[2]: Config.ObserveFirst
+// ARGV[3]: The TTL in milliseconds
+//
+// Returns: the count before this call
+const warmupScript = `
+local count = tonumber(redis.call('GET', KEYS[1]) or 0)
+if count < tonumber(ARGV[2]) then
+    redis.call('INCRBY', KEYS[1], ARGV[1])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return count
+`
+
+// warmupTracker applies Config.ObserveFirst. Keys known to be past their
+// warmup are remembered in process for the current Window-long period, so
+// only their first decision in each period pays for the extra Redis call;
+// memory is bounded by the keys seen within one window. The count in Redis
+// lives for two windows from that call, so it expires only for keys idle
+// for over a window.
+type warmupTracker struct {
+	observeFirst int64
+	window       time.Duration
+	now          func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	done  map[string]struct{}
+}
+
+// newWarmupTracker returns the tracker for config, or nil unless
+// ObserveFirst is set.
+func newWarmupTracker(config *Config) *warmupTracker {
+	if config.ObserveFirst <= 0 {
+		return nil
+	}
+	return &warmupTracker{
+		observeFirst: config.ObserveFirst,
+		window:       config.Window,
+		now:          time.Now,
+	}
+}
+
+// warmupKey returns the Redis key counting the key's requests towards
+// Config.ObserveFirst.
+func (c *Config) warmupKey(key string) string {
+	return c.FormatKey(key) + ":warmup"
+}
+
+// withWarmupKey appends the key's warmup count to the Redis keys holding its
+// state, when Config.ObserveFirst is set.
+func (c *Config) withWarmupKey(redisKeys []string, key string) []string {
+	if c.ObserveFirst <= 0 {
+		return redisKeys
+	}
+	return append(redisKeys, c.warmupKey(key))
+}
+
+// observe lets a decision denied for being over the limit through while key
+// is within its first Config.ObserveFirst requests, marking it WouldDeny.
+// Every request checked against Redis counts towards the warmup, allowed or
+// not. If the count can't be read the decision is left as made. A nil
+// tracker does nothing.
+func (w *warmupTracker) observe(ctx context.Context, client *redis.Client, config *Config, key string, n int64, result *Result) {
+	if w == nil || w.isDone(key) {
+		return
+	}
+	ttl := config.effectiveTTL(2 * w.window)
+	before, err := client.Eval(ctx, warmupScript, []string{config.warmupKey(key)}, n, w.observeFirst, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return
+	}
+	if before+n >= w.observeFirst {
+		// This call counted the last of the warmup, if it wasn't over already
+		w.markDone(key)
+	}
+	if before >= w.observeFirst {
+		return
+	}
+	if !result.Allowed && (result.Reason == ReasonLimitExceeded || result.Reason == ReasonMinInterval) {
+		// Reason still says why the request would have been denied
+		result.Allowed = true
+		result.WouldDeny = true
+		result.RetryAfter = 0
+	}
+}
+
+// isDone reports whether the key is known to be past its warmup in the
+// current period.
+func (w *warmupTracker) isDone(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate()
+	_, done := w.done[key]
+	return done
+}
+
+// markDone remembers that the key is past its warmup for the current period.
+func (w *warmupTracker) markDone(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate()
+	w.done[key] = struct{}{}
+}
+
+// reset forgets the key, for Reset, which deletes its count. A nil tracker
+// does nothing.
+func (w *warmupTracker) reset(key string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.done, key)
+}
+
+// rotate drops the remembered keys at each period boundary. Callers hold mu.
+func (w *warmupTracker) rotate() {
+	if start := w.now().Truncate(w.window); w.done == nil || !start.Equal(w.start) {
+		w.start = start
+		w.done = make(map[string]struct{})
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveFirst(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		limiter, err := tt.create(client, &Config{
+			Algorithm:    tt.algorithm,
+			Limit:        3,
+			Window:       time.Hour,
+			ObserveFirst: 5,
+		})
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		for i := range 7 {
+			result, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+
+			switch {
+			case i < 3:
+				// Within the limit
+				assert.True(t, result.Allowed, "request %d", i+1)
+				assert.False(t, result.WouldDeny, "request %d", i+1)
+			case i < 5:
+				// Over the limit, but still within the warmup
+				assert.True(t, result.Allowed, "request %d", i+1)
+				assert.True(t, result.WouldDeny, "request %d", i+1)
+				assert.Equal(t, ReasonLimitExceeded, result.Reason)
+				assert.Zero(t, result.RetryAfter)
+			default:
+				// Enforced once the warmup is over
+				assert.False(t, result.Allowed, "request %d", i+1)
+				assert.False(t, result.WouldDeny, "request %d", i+1)
+			}
+		}
+
+		// Warmups are per key
+		result, err := limiter.Allow(ctx, "user:2")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.False(t, result.WouldDeny)
+	})
+}
+
+func TestObserveFirst_WarmupCount(t *testing.T) {
+	forEachAlgorithm(t, func(t *testing.T, tt algorithmCase) {
+		client, mr := setupMiniredis(t)
+		defer mr.Close()
+
+		config := &Config{
+			Algorithm:    tt.algorithm,
+			Limit:        1,
+			Window:       time.Hour,
+			ObserveFirst: 2,
+		}
+		limiter, err := tt.create(client, config)
+		require.NoError(t, err)
+		defer limiter.Close()
+
+		ctx := context.Background()
+		warmupKey := config.WithDefaults().warmupKey("user:1")
+		for range 2 {
+			_, err := limiter.Allow(ctx, "user:1")
+			require.NoError(t, err)
+		}
+
+		// The count expires two windows after the key's last request
+		assert.Equal(t, 2*time.Hour, mr.TTL(warmupKey))
+
+		// Once the warmup is over, decisions skip the count for the rest
+		// of the window: they cost what they would without ObserveFirst
+		config.ObserveFirst = 0
+		enforcing, err := tt.create(client, config)
+		require.NoError(t, err)
+		defer enforcing.Close()
+		for range 2 {
+			_, err := enforcing.Allow(ctx, "user:2")
+			require.NoError(t, err)
+		}
+		commands := func(key string, limiter RateLimiter) int {
+			before := mr.CommandCount()
+			result, err := limiter.Allow(ctx, key)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			return mr.CommandCount() - before
+		}
+		assert.Equal(t, commands("user:2", enforcing), commands("user:1", limiter))
+
+		// The count is part of the key's state
+		usage, err := limiter.(MemoryReporter).MemoryUsage(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Positive(t, usage)
+
+		require.NoError(t, limiter.Reset(ctx, "user:1"))
+		assert.False(t, mr.Exists(warmupKey))
+
+		// Reset starts the warmup over
+		result, err := limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		result, err = limiter.Allow(ctx, "user:1")
+		require.NoError(t, err)
+		assert.True(t, result.WouldDeny)
+	})
+}